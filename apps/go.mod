@@ -9,3 +9,5 @@ require (
 	golang.org/x/image v0.0.0-20190703141733-d6a02ce849c9
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
 )
+
+replace gioui.org/ui => ../ui
@@ -5,38 +5,21 @@ package main
 import (
 	"image"
 	"testing"
-	"time"
 
 	"gioui.org/ui"
-	"gioui.org/ui/input"
 	"gioui.org/ui/layout"
+	"gioui.org/ui/uitest"
 )
 
-type queue struct{}
-
-type config struct{}
-
 func BenchmarkUI(b *testing.B) {
 	fetch := func(_ string) {}
 	u := newUI(fetch)
 	ops := new(ui.Ops)
-	q := new(queue)
-	c := new(config)
+	q := new(uitest.Queue)
+	c := new(uitest.Config)
 	cs := layout.RigidConstraints(image.Point{800, 600})
 	for i := 0; i < b.N; i++ {
 		ops.Reset()
 		u.Layout(c, q, ops, cs)
 	}
 }
-
-func (queue) Next(k input.Key) (input.Event, bool) {
-	return nil, false
-}
-
-func (config) Now() time.Time {
-	return time.Now()
-}
-
-func (config) Px(v ui.Value) int {
-	return int(v.V + .5)
-}
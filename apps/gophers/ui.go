@@ -54,6 +54,7 @@ type UI struct {
 	// Profiling.
 	profiling   bool
 	profile     system.ProfileEvent
+	timings     widget.FrameTimings
 	lastMallocs uint64
 }
 
@@ -190,12 +191,20 @@ func (u *UI) layoutTimings(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Co
 	cs = al.Begin(ops, cs)
 	in := layout.Inset{Top: ui.Dp(16)}
 	cs = in.Begin(c, ops, cs)
-	txt := fmt.Sprintf("m: %d %s", mallocs, u.profile.Timings)
+	txt := fmt.Sprintf("m: %d %s", mallocs, u.profile)
 	dims := text.Label{Material: theme.text, Face: u.face(fonts.mono, 10), Text: txt}.Layout(ops, cs)
 	dims = in.End(dims)
 	return al.End(dims)
 }
 
+func (u *UI) layoutGraph(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Constraints) layout.Dimens {
+	if !u.profiling {
+		return layout.Dimens{}
+	}
+	u.timings.Alignment = layout.SW
+	return u.timings.Layout(c, q, ops, cs)
+}
+
 func (u *UI) Layout(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Constraints) layout.Dimens {
 	u.faces.Reset(c)
 	for i := range u.userClicks {
@@ -213,6 +222,7 @@ func (u *UI) Layout(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Constrain
 		dims = u.selectedUser.Layout(c, q, ops, cs)
 	}
 	u.layoutTimings(c, q, ops, cs)
+	u.layoutGraph(c, q, ops, cs)
 	return dims
 }
 
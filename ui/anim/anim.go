@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package anim implements animations driven by the animation
+time of a ui.Config.
+
+An Animation describes a value changing over time, such as a
+Tween from one value to another along an easing Curve, or a
+physical Spring. A Player plays an animation:
+
+	var p anim.Player
+
+	// Start an animation, for example when a button is clicked.
+	p.Start(cfg.Now(), anim.Tween{From: 0, To: 1, Duration: 200*time.Millisecond, Curve: anim.EaseOut})
+
+	// During layout, compute the current value. Value schedules
+	// the redraws needed for animating.
+	v := p.Value(cfg, ops)
+
+Players request redraws only while their animation is running
+and stop when it ends, so idle windows don't redraw.
+*/
+package anim
+
+import (
+	"time"
+
+	"gioui.org/ui"
+)
+
+// Animation is a value that changes over time.
+type Animation interface {
+	// At returns the value at time t after the start of
+	// the animation.
+	At(t time.Duration) float32
+	// End returns the duration of the animation. The
+	// value at and after End is the final value.
+	End() time.Duration
+}
+
+// Tween animates a value from From to To along a Curve.
+type Tween struct {
+	From, To float32
+	Duration time.Duration
+	// Curve eases the animation. A nil Curve means
+	// Linear.
+	Curve Curve
+}
+
+// Sequence plays animations one after the other.
+type Sequence []Animation
+
+// Player plays an Animation. The zero value is an
+// idle Player with the value 0.
+type Player struct {
+	anim    Animation
+	start   time.Time
+	running bool
+	done    bool
+	value   float32
+}
+
+func (tw Tween) At(t time.Duration) float32 {
+	p := float32(1)
+	if t < tw.Duration {
+		p = float32(t) / float32(tw.Duration)
+		if p < 0 {
+			p = 0
+		}
+	}
+	if tw.Curve != nil {
+		p = tw.Curve(p)
+	}
+	return tw.From + (tw.To-tw.From)*p
+}
+
+func (tw Tween) End() time.Duration {
+	return tw.Duration
+}
+
+func (s Sequence) At(t time.Duration) float32 {
+	if len(s) == 0 {
+		return 0
+	}
+	for _, a := range s[:len(s)-1] {
+		end := a.End()
+		if t < end {
+			return a.At(t)
+		}
+		t -= end
+	}
+	return s[len(s)-1].At(t)
+}
+
+func (s Sequence) End() time.Duration {
+	var d time.Duration
+	for _, a := range s {
+		d += a.End()
+	}
+	return d
+}
+
+// Start playing a at time start, replacing any running
+// animation. The animation starts after a delay if start
+// is later than the current animation time.
+func (p *Player) Start(start time.Time, a Animation) {
+	p.anim = a
+	p.start = start
+	p.running = true
+	p.done = false
+}
+
+// Stop the animation at its most recent value. A
+// stopped animation is not Done.
+func (p *Player) Stop() {
+	p.anim = nil
+	p.running = false
+}
+
+// Value returns the value of the animation at the time
+// cfg.Now(). If the animation is still running, Value adds
+// an InvalidateOp to ops for the next frame.
+func (p *Player) Value(cfg ui.Config, ops *ui.Ops) float32 {
+	if !p.running {
+		return p.value
+	}
+	now := cfg.Now()
+	if now.Before(p.start) {
+		// Sleep until the animation starts.
+		ui.InvalidateOp{At: p.start}.Add(ops)
+		p.value = p.anim.At(0)
+		return p.value
+	}
+	t := now.Sub(p.start)
+	p.value = p.anim.At(t)
+	if t >= p.anim.End() {
+		p.anim = nil
+		p.running = false
+		p.done = true
+	} else {
+		ui.InvalidateOp{}.Add(ops)
+	}
+	return p.value
+}
+
+// Active reports whether an animation is running.
+func (p *Player) Active() bool {
+	return p.running
+}
+
+// Done reports whether the most recently started
+// animation ran to completion.
+func (p *Player) Done() bool {
+	return p.done
+}
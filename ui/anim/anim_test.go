@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package anim
+
+import (
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/uitest"
+)
+
+func TestCubicBezier(t *testing.T) {
+	for _, c := range []Curve{Ease, EaseIn, EaseOut, EaseInOut} {
+		if v := c(0); v != 0 {
+			t.Errorf("c(0) = %v, expected 0", v)
+		}
+		if v := c(1); v != 1 {
+			t.Errorf("c(1) = %v, expected 1", v)
+		}
+		prev := float32(0)
+		for i := 1; i <= 100; i++ {
+			v := c(float32(i) / 100)
+			if v < prev {
+				t.Fatalf("curve decreases at %d: %v < %v", i, v, prev)
+			}
+			prev = v
+		}
+	}
+	// The linear Bézier is the identity.
+	lin := CubicBezier(1./3, 1./3, 2./3, 2./3)
+	for _, x := range []float32{.1, .25, .5, .9} {
+		if v := lin(x); abs(v-x) > 1e-4 {
+			t.Errorf("lin(%v) = %v", x, v)
+		}
+	}
+	if v := EaseInOut(.5); abs(v-.5) > 1e-4 {
+		t.Errorf("EaseInOut(.5) = %v, expected .5", v)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	s := Sequence{
+		Tween{From: 0, To: 10, Duration: time.Second},
+		Tween{From: 10, To: 0, Duration: time.Second},
+	}
+	if e := s.End(); e != 2*time.Second {
+		t.Errorf("End() = %v, expected 2s", e)
+	}
+	tests := []struct {
+		t time.Duration
+		v float32
+	}{
+		{0, 0}, {500 * time.Millisecond, 5}, {time.Second, 10},
+		{1500 * time.Millisecond, 5}, {3 * time.Second, 0},
+	}
+	for _, test := range tests {
+		if v := s.At(test.t); v != test.v {
+			t.Errorf("At(%v) = %v, expected %v", test.t, v, test.v)
+		}
+	}
+}
+
+func TestSpring(t *testing.T) {
+	for _, damping := range []float32{.3, 1, 2} {
+		s := Spring{From: 0, To: 100, Velocity: 50, Period: 500 * time.Millisecond, Damping: damping}
+		end := s.End()
+		if end <= 0 || end > 10*time.Second {
+			t.Fatalf("damping %v: End() = %v", damping, end)
+		}
+		if v := s.At(end); v != 100 {
+			t.Errorf("damping %v: At(End) = %v, expected 100", damping, v)
+		}
+		var max float32
+		for d := time.Duration(0); d < end; d += time.Millisecond {
+			if v := s.At(d); v > max {
+				max = v
+			}
+		}
+		if overshoot := max > 100.5; overshoot != (damping < 1) {
+			t.Errorf("damping %v: maximum %v", damping, max)
+		}
+		// The spring is at rest near the end.
+		if v := s.At(end - time.Millisecond); abs(v-100) > .5 {
+			t.Errorf("damping %v: At(End-1ms) = %v", damping, v)
+		}
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	cfg := &uitest.Config{Time: time.Unix(1000, 0)}
+	var p Player
+	ops := new(ui.Ops)
+	p.Start(cfg.Now().Add(time.Second), Tween{From: 1, To: 2, Duration: time.Second})
+	if v := p.Value(cfg, ops); v != 1 || !p.Active() {
+		t.Errorf("got %v before start, expected 1 and active", v)
+	}
+	cfg.Advance(1500 * time.Millisecond)
+	if v := p.Value(cfg, ops); v != 1.5 {
+		t.Errorf("got %v, expected 1.5", v)
+	}
+	cfg.Advance(time.Second)
+	if v := p.Value(cfg, ops); v != 2 || p.Active() || !p.Done() {
+		t.Errorf("got %v, expected completed animation at 2", v)
+	}
+	// Stopped animations keep their value.
+	p.Start(cfg.Now(), Tween{From: 2, To: 4, Duration: time.Second})
+	cfg.Advance(500 * time.Millisecond)
+	p.Value(cfg, ops)
+	p.Stop()
+	cfg.Advance(time.Second)
+	if v := p.Value(cfg, ops); v != 3 || p.Active() || p.Done() {
+		t.Errorf("got %v, expected stopped animation at 3", v)
+	}
+	// Idle players don't request redraws.
+	ops.Reset()
+	p.Value(cfg, ops)
+	if n := len(ops.Data()); n != 0 {
+		t.Errorf("idle player added %d bytes of operations", n)
+	}
+}
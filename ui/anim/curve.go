@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package anim
+
+// Curve maps the progress of an animation in the range
+// [0, 1] to eased progress. Curves start at 0 and end at
+// 1, but may overshoot in between.
+type Curve func(t float32) float32
+
+var (
+	// Linear progresses at a constant rate.
+	Linear Curve = func(t float32) float32 { return t }
+	// Ease, EaseIn, EaseOut and EaseInOut are the
+	// standard CSS easing curves.
+	Ease      = CubicBezier(.25, .1, .25, 1)
+	EaseIn    = CubicBezier(.42, 0, 1, 1)
+	EaseOut   = CubicBezier(0, 0, .58, 1)
+	EaseInOut = CubicBezier(.42, 0, .58, 1)
+)
+
+// CubicBezier returns the curve of the cubic Bézier with the
+// end points (0, 0) and (1, 1) and the control points (x1, y1)
+// and (x2, y2), like the CSS cubic-bezier function. The x
+// coordinates are clamped to [0, 1].
+func CubicBezier(x1, y1, x2, y2 float32) Curve {
+	x1, x2 = clamp1(x1), clamp1(x2)
+	// Polynomial coefficients of the curve coordinates.
+	cx := 3 * x1
+	bx := 3*(x2-x1) - cx
+	ax := 1 - cx - bx
+	cy := 3 * y1
+	by := 3*(y2-y1) - cy
+	ay := 1 - cy - by
+	x := func(s float32) float32 { return ((ax*s+bx)*s + cx) * s }
+	y := func(s float32) float32 { return ((ay*s+by)*s + cy) * s }
+	dx := func(s float32) float32 { return (3*ax*s+2*bx)*s + cx }
+	return func(t float32) float32 {
+		if t <= 0 || t >= 1 {
+			return clamp1(t)
+		}
+		// Solve x(s) = t, first with Newton's method.
+		const eps = 1e-6
+		s := t
+		for i := 0; i < 8; i++ {
+			e := x(s) - t
+			if abs(e) < eps {
+				return y(s)
+			}
+			d := dx(s)
+			if abs(d) < eps {
+				break
+			}
+			s -= e / d
+		}
+		// Fall back to bisection, which always converges
+		// because x is monotonic.
+		lo, hi := float32(0), float32(1)
+		s = t
+		for i := 0; i < 32 && hi-lo > eps; i++ {
+			if x(s) < t {
+				lo = s
+			} else {
+				hi = s
+			}
+			s = (lo + hi) / 2
+		}
+		return y(s)
+	}
+}
+
+func clamp1(v float32) float32 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	default:
+		return v
+	}
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
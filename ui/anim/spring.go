@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package anim
+
+import (
+	"math"
+	"time"
+)
+
+// Spring animates a value from From to To like a mass on a
+// damped spring. Springs are useful for animations that are
+// interrupted, because a new Spring can start from the
+// value and velocity of the interrupted one.
+type Spring struct {
+	From, To float32
+	// Velocity is the initial velocity in units
+	// per second.
+	Velocity float32
+	// Period is the period of the undamped spring. Shorter
+	// periods mean stiffer springs.
+	Period time.Duration
+	// Damping is the damping ratio. A ratio of 1 is critically
+	// damped and reaches To as fast as possible without
+	// overshooting. Lower ratios overshoot and oscillate,
+	// higher ratios approach To slower. Zero means 1.
+	Damping float32
+}
+
+// settleFraction is the fraction of the initial displacement
+// within which a spring is considered at rest.
+const settleFraction = 1e-3
+
+func (s Spring) At(t time.Duration) float32 {
+	if t >= s.End() {
+		return s.To
+	}
+	x, _ := s.displacement(t.Seconds())
+	return s.To + float32(x)
+}
+
+// VelocityAt returns the velocity of the spring at time t
+// after its start.
+func (s Spring) VelocityAt(t time.Duration) float32 {
+	if s.Period <= 0 {
+		return 0
+	}
+	_, v := s.displacement(t.Seconds())
+	return float32(v)
+}
+
+// End returns the time the spring comes to rest.
+func (s Spring) End() time.Duration {
+	if s.Period <= 0 {
+		return 0
+	}
+	w := s.omega()
+	x0, v0 := float64(s.From-s.To), float64(s.Velocity)
+	eps := math.Max(math.Abs(x0), math.Abs(v0)/w) * settleFraction
+	if eps == 0 {
+		return 0
+	}
+	// Find a time where the displacement envelope is below eps by
+	// doubling, and refine it by bisection.
+	lo, hi := 0.0, s.Period.Seconds()
+	for s.envelope(hi) >= eps {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 32; i++ {
+		mid := (lo + hi) / 2
+		if s.envelope(mid) >= eps {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return time.Duration(hi * float64(time.Second))
+}
+
+func (s Spring) omega() float64 {
+	return 2 * math.Pi / s.Period.Seconds()
+}
+
+func (s Spring) damping() float64 {
+	if s.Damping <= 0 {
+		return 1
+	}
+	return float64(s.Damping)
+}
+
+// displacement returns the displacement from To and the
+// velocity at time t in seconds.
+func (s Spring) displacement(t float64) (float64, float64) {
+	w, z := s.omega(), s.damping()
+	x0, v0 := float64(s.From-s.To), float64(s.Velocity)
+	switch {
+	case z < 1:
+		// Underdamped:
+		//
+		// x(t) = e^(-zwt)*(x0*cos(wd*t) + (v0 + zw*x0)/wd*sin(wd*t))
+		wd := w * math.Sqrt(1-z*z)
+		a, b := x0, (v0+z*w*x0)/wd
+		e := math.Exp(-z * w * t)
+		sin, cos := math.Sincos(wd * t)
+		x := e * (a*cos + b*sin)
+		v := -z*w*x + e*wd*(b*cos-a*sin)
+		return x, v
+	case z == 1:
+		// Critically damped:
+		//
+		// x(t) = e^(-wt)*(x0 + (v0 + w*x0)*t)
+		b := v0 + w*x0
+		e := math.Exp(-w * t)
+		x := e * (x0 + b*t)
+		v := e * (b - w*(x0+b*t))
+		return x, v
+	default:
+		// Overdamped:
+		//
+		// x(t) = c1*e^(r1*t) + c2*e^(r2*t)
+		r1, r2, c1, c2 := s.overdamped()
+		e1, e2 := math.Exp(r1*t), math.Exp(r2*t)
+		return c1*e1 + c2*e2, c1*r1*e1 + c2*r2*e2
+	}
+}
+
+// envelope returns a non-increasing bound for the absolute
+// displacement at time t. The bound holds once the spring
+// is close to rest.
+func (s Spring) envelope(t float64) float64 {
+	w, z := s.omega(), s.damping()
+	x0, v0 := float64(s.From-s.To), float64(s.Velocity)
+	switch {
+	case z < 1:
+		wd := w * math.Sqrt(1-z*z)
+		return math.Hypot(x0, (v0+z*w*x0)/wd) * math.Exp(-z*w*t)
+	case z == 1:
+		// The bound decreases only after its peak at t = 1/w.
+		t = math.Max(t, 1/w)
+		return (math.Abs(x0) + math.Abs(v0+w*x0)*t) * math.Exp(-w*t)
+	default:
+		r1, r2, c1, c2 := s.overdamped()
+		return math.Abs(c1)*math.Exp(r1*t) + math.Abs(c2)*math.Exp(r2*t)
+	}
+}
+
+// overdamped returns the exponents and coefficients
+// of an overdamped spring.
+func (s Spring) overdamped() (r1, r2, c1, c2 float64) {
+	w, z := s.omega(), s.damping()
+	x0, v0 := float64(s.From-s.To), float64(s.Velocity)
+	d := math.Sqrt(z*z - 1)
+	r1, r2 = -w*(z-d), -w*(z+d)
+	c2 = (v0 - r1*x0) / (r2 - r1)
+	c1 = x0 - c2
+	return
+}
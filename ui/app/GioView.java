@@ -85,7 +85,12 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 	}
 
 	@Override public boolean onKeyDown(int keyCode, KeyEvent event) {
-		onKeyEvent(nhandle, keyCode, event.getUnicodeChar(), event.getEventTime());
+		onKeyEvent(nhandle, keyCode, event.getUnicodeChar(), event.getAction(), event.getRepeatCount(), event.getMetaState(), event.getEventTime());
+		return false;
+	}
+
+	@Override public boolean onKeyUp(int keyCode, KeyEvent event) {
+		onKeyEvent(nhandle, keyCode, event.getUnicodeChar(), event.getAction(), event.getRepeatCount(), event.getMetaState(), event.getEventTime());
 		return false;
 	}
 
@@ -104,6 +109,8 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 						event.ACTION_MOVE,
 						event.getPointerId(i),
 						event.getToolType(i),
+						event.getButtonState(),
+						event.getMetaState(),
 						event.getHistoricalX(i, j),
 						event.getHistoricalY(i, j),
 						time);
@@ -121,6 +128,8 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 					act,
 					event.getPointerId(i),
 					event.getToolType(i),
+					event.getButtonState(),
+					event.getMetaState(),
 					event.getX(i),
 					event.getY(i),
 					event.getEventTime());
@@ -214,8 +223,8 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 	static private native void onConfigurationChanged(long handle);
 	static private native void onWindowInsets(long handle, int top, int right, int bottom, int left);
 	static private native void onLowMemory();
-	static private native void onTouchEvent(long handle, int action, int pointerID, int tool, float x, float y, long time);
-	static private native void onKeyEvent(long handle, int code, int character, long time);
+	static private native void onTouchEvent(long handle, int action, int pointerID, int tool, int buttons, int meta, float x, float y, long time);
+	static private native void onKeyEvent(long handle, int code, int character, int action, int repeat, int meta, long time);
 	static private native void onFrameCallback(long handle, long nanos);
 	static private native boolean onBack(long handle);
 	static private native void onFocusChange(long handle, boolean focus);
@@ -61,5 +61,27 @@ A Window's Queue method returns an input.Queue implementation that distributes
 incoming events to the input handlers declared in the latest call to Update.
 See the gioui.org/ui/input package for more information about input handlers.
 
+Headless windows
+
+A Window created with the WithHeadless option is driven by a Headless
+driver instead of the operating system. Headless windows need neither
+a display nor OpenGL and are useful for testing complete programs:
+
+	h := app.NewHeadless(image.Point{X: 800, Y: 600})
+	w := app.NewWindow(app.WithHeadless(h))
+	go runEventLoop(w)
+	// Inject input.
+	h.Event(pointer.Event{Type: pointer.Press, ...})
+	// Request an update and inspect the result.
+	h.Frame()
+	img := h.Screenshot()
+
+The headless driver is built together with the platform drivers, so
+tests of headless windows still link against the platform libraries.
+On Linux, that means the development packages for Wayland, xkbcommon,
+EGL and OpenGL ES, for example libwayland-dev, libxkbcommon-dev,
+libegl1-mesa-dev and libgles2-mesa-dev on Debian. No display or GPU
+is needed to run the tests.
+
 */
 package app
@@ -7,6 +7,7 @@ package app
 import (
 	"errors"
 	"fmt"
+	"image"
 	"runtime"
 	"strings"
 
@@ -31,6 +32,11 @@ type eglContext struct {
 	ctx      _EGLContext
 	visualID int
 	srgb     bool
+	// bufferAge is set if EGL_EXT_buffer_age is supported.
+	bufferAge bool
+	// swapWithDamage is set if swapping with damage is
+	// supported.
+	swapWithDamage bool
 }
 
 var (
@@ -43,6 +49,7 @@ var (
 const (
 	_EGL_ALPHA_SIZE             = 0x3021
 	_EGL_BLUE_SIZE              = 0x3022
+	_EGL_BUFFER_AGE_EXT         = 0x313d
 	_EGL_CONFIG_CAVEAT          = 0x3027
 	_EGL_CONTEXT_CLIENT_VERSION = 0x3098
 	_EGL_DEPTH_SIZE             = 0x3025
@@ -97,6 +104,53 @@ func (c *context) Present() error {
 	return nil
 }
 
+func (c *context) BufferAge() int {
+	if c.eglWin == nil {
+		panic("context is not active")
+	}
+	if c.srgbFBO != nil {
+		// The sRGB framebuffer is preserved between frames.
+		return 1
+	}
+	if !c.eglCtx.bufferAge {
+		return 0
+	}
+	age, ok := eglQuerySurface(c.eglCtx.disp, c.eglSurf, _EGL_BUFFER_AGE_EXT)
+	if !ok {
+		return 0
+	}
+	return int(age)
+}
+
+func (c *context) PresentDamage(damage []image.Rectangle) error {
+	if c.eglWin == nil {
+		panic("context is not active")
+	}
+	if len(damage) == 0 {
+		c.eglWin.skipFrame()
+		return nil
+	}
+	if !c.eglCtx.swapWithDamage {
+		return c.Present()
+	}
+	if c.srgbFBO != nil {
+		c.srgbFBO.Blit()
+	}
+	// Convert the rectangles to the lower left
+	// origin of EGL.
+	rects := make([]_EGLint, 0, len(damage)*4)
+	for _, r := range damage {
+		rects = append(rects, _EGLint(r.Min.X), _EGLint(c.height-r.Max.Y), _EGLint(r.Dx()), _EGLint(r.Dy()))
+	}
+	if !eglSwapBuffersWithDamage(c.eglCtx.disp, c.eglSurf, rects) {
+		return fmt.Errorf("eglSwapBuffersWithDamage failed (%x)", eglGetError())
+	}
+	if c.srgbFBO != nil {
+		c.srgbFBO.AfterPresent()
+	}
+	return nil
+}
+
 func newContext(w *window) (*context, error) {
 	eglCtx, err := createContext(_EGLNativeDisplayType(w.display()))
 	if err != nil {
@@ -236,16 +290,26 @@ func createContext(disp _EGLNativeDisplayType) (*eglContext, error) {
 	if eglCtx == nilEGLContext {
 		return nil, fmt.Errorf("eglCreateContext failed: 0x%x", eglGetError())
 	}
+	var swapWithDamage bool
+	for _, ext := range []string{"EGL_KHR_swap_buffers_with_damage", "EGL_EXT_swap_buffers_with_damage"} {
+		if hasExtension(exts, ext) {
+			// The function name ends with the vendor of the extension.
+			swapWithDamage = eglLoadSwapBuffersWithDamage("eglSwapBuffersWithDamage" + ext[4:7])
+			break
+		}
+	}
 	visID, ret := eglGetConfigAttrib(eglDisp, eglCfg, _EGL_NATIVE_VISUAL_ID)
 	if !ret {
 		return nil, errors.New("newContext: eglGetConfigAttrib for _EGL_NATIVE_VISUAL_ID failed")
 	}
 	return &eglContext{
-		disp:     eglDisp,
-		config:   _EGLConfig(eglCfg),
-		ctx:      _EGLContext(eglCtx),
-		visualID: int(visID),
-		srgb:     srgb,
+		disp:           eglDisp,
+		config:         _EGLConfig(eglCfg),
+		ctx:            _EGLContext(eglCtx),
+		visualID:       int(visID),
+		srgb:           srgb,
+		bufferAge:      hasExtension(exts, "EGL_EXT_buffer_age"),
+		swapWithDamage: swapWithDamage,
 	}, nil
 }
 
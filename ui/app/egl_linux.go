@@ -5,13 +5,20 @@ package app
 /*
 #cgo LDFLAGS: -lEGL
 
+#include <stdlib.h>
 #include <EGL/egl.h>
 #include <EGL/eglext.h>
 #include <GLES2/gl2.h>
 #include <GLES3/gl3.h>
+
+static EGLBoolean gio_eglSwapBuffersWithDamage(PFNEGLSWAPBUFFERSWITHDAMAGEKHRPROC f, EGLDisplay disp, EGLSurface surf, EGLint *rects, EGLint n) {
+	return f(disp, surf, rects, n);
+}
 */
 import "C"
 
+import "unsafe"
+
 type (
 	_EGLint     = C.EGLint
 	_EGLDisplay = C.EGLDisplay
@@ -70,6 +77,27 @@ func eglSwapBuffers(disp _EGLDisplay, surf _EGLSurface) bool {
 	return C.eglSwapBuffers(disp, surf) == C.EGL_TRUE
 }
 
+// swapBuffersWithDamage is eglSwapBuffersWithDamageKHR or
+// eglSwapBuffersWithDamageEXT.
+var swapBuffersWithDamage C.PFNEGLSWAPBUFFERSWITHDAMAGEKHRPROC
+
+func eglLoadSwapBuffersWithDamage(name string) bool {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	swapBuffersWithDamage = C.PFNEGLSWAPBUFFERSWITHDAMAGEKHRPROC(unsafe.Pointer(C.eglGetProcAddress(cname)))
+	return swapBuffersWithDamage != nil
+}
+
+func eglSwapBuffersWithDamage(disp _EGLDisplay, surf _EGLSurface, rects []_EGLint) bool {
+	return C.gio_eglSwapBuffersWithDamage(swapBuffersWithDamage, disp, surf, &rects[0], _EGLint(len(rects)/4)) == C.EGL_TRUE
+}
+
+func eglQuerySurface(disp _EGLDisplay, surf _EGLSurface, attr _EGLint) (_EGLint, bool) {
+	var val _EGLint
+	ret := C.eglQuerySurface(disp, surf, attr, &val)
+	return val, ret == C.EGL_TRUE
+}
+
 func eglSwapInterval(disp _EGLDisplay, interval _EGLint) bool {
 	return C.eglSwapInterval(disp, interval) == C.EGL_TRUE
 }
@@ -24,7 +24,8 @@ type (
 )
 
 type eglWindow struct {
-	w *C.struct_wl_egl_window
+	w    *C.struct_wl_egl_window
+	surf *C.struct_wl_surface
 }
 
 func newEGLWindow(w _EGLNativeWindowType, width, height int) (*eglWindow, error) {
@@ -33,7 +34,7 @@ func newEGLWindow(w _EGLNativeWindowType, width, height int) (*eglWindow, error)
 	if win == nil {
 		return nil, errors.New("wl_egl_create_window failed")
 	}
-	return &eglWindow{win}, nil
+	return &eglWindow{w: win, surf: surf}, nil
 }
 
 func (w *eglWindow) window() _EGLNativeWindowType {
@@ -48,6 +49,13 @@ func (w *eglWindow) destroy() {
 	C.wl_egl_window_destroy(w.w)
 }
 
+// skipFrame commits the surface without a new buffer, to
+// keep frame callbacks coming when a frame is skipped.
+func (w *eglWindow) skipFrame() {
+	C.wl_surface_commit(w.surf)
+	C.wl_display_flush(conn.disp)
+}
+
 func eglGetDisplay(disp _EGLNativeDisplayType) _EGLDisplay {
 	return C.eglGetDisplay(disp)
 }
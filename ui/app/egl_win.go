@@ -18,3 +18,4 @@ func (w *eglWindow) window() _EGLNativeWindowType {
 
 func (w *eglWindow) resize(width, height int) {}
 func (w *eglWindow) destroy()                 {}
+func (w *eglWindow) skipFrame()               {}
@@ -33,6 +33,7 @@ var (
 	_eglGetError            = libEGL.NewProc("eglGetError")
 	_eglInitialize          = libEGL.NewProc("eglInitialize")
 	_eglMakeCurrent         = libEGL.NewProc("eglMakeCurrent")
+	_eglQuerySurface        = libEGL.NewProc("eglQuerySurface")
 	_eglReleaseThread       = libEGL.NewProc("eglReleaseThread")
 	_eglSwapInterval        = libEGL.NewProc("eglSwapInterval")
 	_eglSwapBuffers         = libEGL.NewProc("eglSwapBuffers")
@@ -122,6 +123,30 @@ func eglSwapInterval(disp _EGLDisplay, interval _EGLint) bool {
 	return r != 0
 }
 
+// swapBuffersWithDamage is eglSwapBuffersWithDamageKHR or
+// eglSwapBuffersWithDamageEXT, if exported by libEGL.dll.
+var swapBuffersWithDamage *syscall.LazyProc
+
+func eglLoadSwapBuffersWithDamage(name string) bool {
+	proc := libEGL.NewProc(name)
+	if proc.Find() != nil {
+		return false
+	}
+	swapBuffersWithDamage = proc
+	return true
+}
+
+func eglSwapBuffersWithDamage(disp _EGLDisplay, surf _EGLSurface, rects []_EGLint) bool {
+	r, _, _ := swapBuffersWithDamage.Call(uintptr(disp), uintptr(surf), uintptr(unsafe.Pointer(&rects[0])), uintptr(len(rects)/4))
+	return r != 0
+}
+
+func eglQuerySurface(disp _EGLDisplay, surf _EGLSurface, attr _EGLint) (_EGLint, bool) {
+	var val uintptr
+	r, _, _ := _eglQuerySurface.Call(uintptr(disp), uintptr(surf), uintptr(attr), uintptr(unsafe.Pointer(&val)))
+	return _EGLint(val), r != 0
+}
+
 func eglSwapBuffers(disp _EGLDisplay, surf _EGLSurface) bool {
 	r, _, _ := _eglSwapBuffers.Call(uintptr(disp), uintptr(surf))
 	return r != 0
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package app
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/input"
+	"gioui.org/ui/internal/raster"
+)
+
+// Headless is a window driver that needs neither a display nor
+// OpenGL. It is useful for running the event loop of a Window in
+// automated tests.
+//
+// Frames are rendered with a software renderer and are available
+// through Screenshot.
+//
+// Use WithHeadless to create a Window with a Headless driver. A
+// program must keep receiving events from the Window, exactly like
+// it would for a platform window.
+type Headless struct {
+	mu        sync.Mutex
+	w         *Window
+	size      image.Point
+	cfg       Config
+	animating bool
+	textInput bool
+	renderer  raster.Renderer
+	frame     *image.RGBA
+}
+
+// NewHeadless creates a headless driver for windows of the given
+// size in pixels. The initial scale is 1 pixel per dp and sp.
+func NewHeadless(size image.Point) *Headless {
+	return &Headless{
+		size: size,
+		cfg: Config{
+			pxPerDp: 1,
+			pxPerSp: 1,
+		},
+	}
+}
+
+func (h *Headless) attach(w *Window) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.w != nil {
+		panic("Headless driver already in use")
+	}
+	h.w = w
+}
+
+// SetSize sets the window size for subsequent frames.
+func (h *Headless) SetSize(size image.Point) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.size = size
+}
+
+// SetScale sets the number of pixels per dp and per sp
+// for subsequent frames.
+func (h *Headless) SetScale(pxPerDp, pxPerSp float32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg.pxPerDp = pxPerDp
+	h.cfg.pxPerSp = pxPerSp
+}
+
+// SetTime sets the time reported by Config.Now for subsequent
+// frames. If t is the zero time, the current time is used.
+func (h *Headless) SetTime(t time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg.now = t
+}
+
+// Frame sends an UpdateEvent to the window and waits for it to
+// be processed. Frame reports whether the program updated the
+// window in response.
+func (h *Headless) Frame() bool {
+	h.mu.Lock()
+	w, size, cfg := h.w, h.size, h.cfg
+	prev := h.frame
+	h.mu.Unlock()
+	if cfg.now.IsZero() {
+		cfg.now = time.Now()
+	}
+	w.event(UpdateEvent{Config: cfg, Size: size})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.frame != prev
+}
+
+// Event injects an input event, such as a pointer.Event,
+// key.Event or key.EditEvent, into the window.
+func (h *Headless) Event(e input.Event) {
+	h.mu.Lock()
+	w := h.w
+	h.mu.Unlock()
+	w.event(e)
+}
+
+// Close destroys the window. The window sends a
+// DestroyEvent and closes its event channel.
+func (h *Headless) Close() {
+	h.mu.Lock()
+	w := h.w
+	h.mu.Unlock()
+	w.event(DestroyEvent{})
+}
+
+// Screenshot returns the most recently rendered frame,
+// or nil if no frame has been rendered.
+func (h *Headless) Screenshot() *image.RGBA {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.frame
+}
+
+// Animating reports whether the window requested continuous
+// updates, for example because of an InvalidateOp.
+func (h *Headless) Animating() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.animating
+}
+
+// TextInputVisible reports whether the window requested
+// that a virtual keyboard is shown.
+func (h *Headless) TextInputVisible() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.textInput
+}
+
+func (h *Headless) draw(size image.Point, frame *ui.Ops) {
+	if frame == nil {
+		return
+	}
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	h.renderer.Draw(img, frame)
+	h.mu.Lock()
+	h.frame = img
+	h.mu.Unlock()
+}
+
+func (h *Headless) setAnimating(anim bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.animating = anim
+}
+
+func (h *Headless) showTextInput(show bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.textInput = show
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package app
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/paint"
+	"gioui.org/ui/pointer"
+)
+
+func TestHeadless(t *testing.T) {
+	size := image.Point{X: 20, Y: 10}
+	h := NewHeadless(size)
+	w := NewWindow(WithHeadless(h))
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	red := color.RGBA{R: 0xff, A: 0xff}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// The left half of the window turns red when
+		// pressed.
+		pressed := new(bool)
+		ops := new(ui.Ops)
+		for e := range w.Events() {
+			e, ok := e.(UpdateEvent)
+			if !ok {
+				continue
+			}
+			q := w.Queue()
+			for ev, ok := q.Next(pressed); ok; ev, ok = q.Next(pressed) {
+				if ev, ok := ev.(pointer.Event); ok && ev.Type == pointer.Press {
+					*pressed = true
+				}
+			}
+			ops.Reset()
+			c := blue
+			if *pressed {
+				c = red
+			}
+			half := image.Rect(0, 0, e.Size.X/2, e.Size.Y)
+			paint.ColorOp{Color: c}.Add(ops)
+			paint.PaintOp{Rect: toRectF(half)}.Add(ops)
+			pointer.RectAreaOp{Rect: half}.Add(ops)
+			pointer.InputOp{Key: pressed}.Add(ops)
+			w.Update(ops)
+		}
+	}()
+	if img := h.Screenshot(); img != nil {
+		t.Fatal("screenshot before the first frame")
+	}
+	if !h.Frame() {
+		t.Fatal("no frame drawn")
+	}
+	assertPixels(t, h.Screenshot(), size, blue)
+
+	// A press outside the handler area is ignored.
+	h.Event(pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Point{X: 15, Y: 5}})
+	h.Event(pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Position: f32.Point{X: 15, Y: 5}})
+	h.Frame()
+	assertPixels(t, h.Screenshot(), size, blue)
+
+	h.Event(pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Point{X: 5, Y: 5}})
+	h.Frame()
+	assertPixels(t, h.Screenshot(), size, red)
+
+	// Frames follow the window size.
+	size = image.Point{X: 30, Y: 5}
+	h.SetSize(size)
+	h.Frame()
+	assertPixels(t, h.Screenshot(), size, red)
+
+	h.Close()
+	<-done
+}
+
+// assertPixels checks that img has the given size, and that the
+// left half is c and the right half is the white background.
+func assertPixels(t *testing.T, img *image.RGBA, size image.Point, c color.RGBA) {
+	t.Helper()
+	if img == nil {
+		t.Fatal("no screenshot")
+	}
+	if got := img.Bounds().Size(); got != size {
+		t.Fatalf("got screenshot size %v, expected %v", got, size)
+	}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			exp := c
+			if x >= size.X/2 {
+				exp = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			if got := img.RGBAAt(x, y); got != exp {
+				t.Fatalf("got pixel %v at (%d,%d), expected %v", got, x, y, exp)
+			}
+		}
+	}
+}
+
+func toRectF(r image.Rectangle) f32.Rectangle {
+	return f32.Rectangle{
+		Min: f32.Point{X: float32(r.Min.X), Y: float32(r.Min.Y)},
+		Max: f32.Point{X: float32(r.Max.X), Y: float32(r.Max.Y)},
+	}
+}
@@ -37,6 +37,8 @@ static void (*_glEndQuery)(GLenum target);
 static void (*_glGenQueries)(GLsizei n, GLuint *ids);
 static void (*_glGetQueryObjectuiv)(GLuint id, GLenum pname, GLuint *params);
 
+static GLuint (*_glGetDebugMessageLog)(GLuint count, GLsizei bufSize, GLenum *sources, GLenum *types, GLuint *ids, GLenum *severities, GLsizei *lengths, GLchar *messageLog);
+
 // The pointer-free version of glVertexAttribPointer, to avoid the Cgo pointer checks.
 __attribute__ ((visibility ("hidden"))) void gio_glVertexAttribPointer(GLuint index, GLint size, GLenum type, GLboolean normalized, GLsizei stride, uintptr_t offset) {
 	glVertexAttribPointer(index, size, type, normalized, stride, (const GLvoid *)offset);
@@ -74,6 +76,13 @@ __attribute__ ((visibility ("hidden"))) void gio_glGetQueryObjectuiv(GLuint id,
 	_glGetQueryObjectuiv(id, pname, params);
 }
 
+__attribute__ ((visibility ("hidden"))) GLuint gio_glGetDebugMessageLog(GLuint count, GLsizei bufSize, GLenum *sources, GLenum *types, GLuint *ids, GLenum *severities, GLsizei *lengths, GLchar *messageLog) {
+	if (_glGetDebugMessageLog == NULL) {
+		return 0;
+	}
+	return _glGetDebugMessageLog(count, bufSize, sources, types, ids, severities, lengths, messageLog);
+}
+
 __attribute__((constructor)) static void gio_loadGLFunctions() {
 #ifdef __APPLE__
 	#if TARGET_OS_IPHONE
@@ -108,6 +117,9 @@ __attribute__((constructor)) static void gio_loadGLFunctions() {
 	_glGetQueryObjectuiv = dlsym(RTLD_DEFAULT, "glGetQueryObjectuiv");
 	if (_glGetQueryObjectuiv == NULL)
 		_glGetQueryObjectuiv = dlsym(RTLD_DEFAULT, "glGetQueryObjectuivEXT");
+	_glGetDebugMessageLog = dlsym(RTLD_DEFAULT, "glGetDebugMessageLog");
+	if (_glGetDebugMessageLog == NULL)
+		_glGetDebugMessageLog = dlsym(RTLD_DEFAULT, "glGetDebugMessageLogKHR");
 #endif
 }
 */
@@ -185,6 +197,10 @@ func (f *Functions) ClearDepthf(d float32) {
 	C.glClearDepthf(C.GLfloat(d))
 }
 
+func (f *Functions) CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	C.glCopyTexSubImage2D(C.GLenum(target), C.GLint(level), C.GLint(xoffset), C.GLint(yoffset), C.GLint(x), C.GLint(y), C.GLsizei(width), C.GLsizei(height))
+}
+
 func (f *Functions) CompileShader(s Shader) {
 	C.glCompileShader(C.GLuint(s.V))
 }
@@ -342,6 +358,40 @@ func (f *Functions) GetProgramInfoLog(p Program) string {
 	return string(buf)
 }
 
+// DebugMessages returns the messages logged by the KHR_debug
+// extension since the previous call, or nil if the extension
+// is not supported.
+func (f *Functions) DebugMessages() []DebugMessage {
+	const count = 16
+	var (
+		sources, types, severities [count]C.GLenum
+		ids                        [count]C.GLuint
+		lengths                    [count]C.GLsizei
+		buf                        [4096]C.GLchar
+	)
+	var msgs []DebugMessage
+	for {
+		n := int(C.gio_glGetDebugMessageLog(count, C.GLsizei(len(buf)), &sources[0], &types[0], &ids[0], &severities[0], &lengths[0], &buf[0]))
+		if n == 0 {
+			return msgs
+		}
+		off := 0
+		for i := 0; i < n; i++ {
+			// The lengths include the terminating zero.
+			l := int(lengths[i])
+			msg := C.GoStringN((*C.char)(unsafe.Pointer(&buf[off])), C.int(l-1))
+			off += l
+			msgs = append(msgs, DebugMessage{
+				Source:   Enum(sources[i]),
+				Type:     Enum(types[i]),
+				Severity: Enum(severities[i]),
+				ID:       uint(ids[i]),
+				Message:  msg,
+			})
+		}
+	}
+}
+
 func (f *Functions) GetQueryObjectuiv(query Query, pname Enum) uint {
 	C.gio_glGetQueryObjectuiv(C.GLuint(query.V), C.GLenum(pname), &f.uints[0])
 	return uint(f.uints[0])
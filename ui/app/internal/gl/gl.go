@@ -2,6 +2,8 @@
 
 package gl
 
+import "image"
+
 type (
 	Attrib uint
 	Enum   uint
@@ -16,6 +18,20 @@ type Context interface {
 	Unlock()
 }
 
+// PartialPresenter is implemented by Contexts that can
+// reuse the contents of previous frames.
+type PartialPresenter interface {
+	// BufferAge returns the number of frames since the
+	// contents of the current back buffer were presented,
+	// or 0 if the contents are undefined.
+	BufferAge() int
+	// PresentDamage is like Present, except that only the
+	// damaged rectangles, in window coordinates, changed
+	// since the previous frame. An empty damage means that
+	// nothing changed and that the frame is not presented.
+	PresentDamage(damage []image.Rectangle) error
+}
+
 const (
 	ARRAY_BUFFER                          = 0x8892
 	BLEND                                 = 0xbe2
@@ -61,6 +77,7 @@ const (
 	RGB                                   = 0x1907
 	RGBA                                  = 0x1908
 	RGBA8                                 = 0x8058
+	SCISSOR_TEST                          = 0xc11
 	SHORT                                 = 0x1402
 	SRGB                                  = 0x8c40
 	SRGB_ALPHA_EXT                        = 0x8c42
@@ -86,8 +103,20 @@ const (
 	// EXT_disjoint_timer_query
 	TIME_ELAPSED_EXT = 0x88BF
 	GPU_DISJOINT_EXT = 0x8FBB
+
+	// KHR_debug
+	DEBUG_OUTPUT             = 0x92e0
+	DEBUG_OUTPUT_SYNCHRONOUS = 0x8242
 )
 
+// DebugMessage is a message logged by the KHR_debug
+// extension.
+type DebugMessage struct {
+	Source, Type, Severity Enum
+	ID                     uint
+	Message                string
+}
+
 // Enforce Functions interface.
 var _ interface {
 	ActiveTexture(texture Enum)
@@ -106,6 +135,8 @@ var _ interface {
 	ClearColor(red, green, blue, alpha float32)
 	ClearDepthf(d float32)
 	CompileShader(s Shader)
+	DebugMessages() []DebugMessage
+	CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int)
 	CreateBuffer() Buffer
 	CreateFramebuffer() Framebuffer
 	CreateProgram() Program
@@ -90,6 +90,9 @@ func (f *Functions) ClearColor(red, green, blue, alpha float32) {
 func (f *Functions) ClearDepthf(d float32) {
 	f.Ctx.Call("clearDepth", d)
 }
+func (f *Functions) CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	f.Ctx.Call("copyTexSubImage2D", int(target), level, xoffset, yoffset, x, y, width, height)
+}
 func (f *Functions) CompileShader(s Shader) {
 	f.Ctx.Call("compileShader", js.Value(s))
 }
@@ -114,6 +117,10 @@ func (f *Functions) CreateShader(ty Enum) Shader {
 func (f *Functions) CreateTexture() Texture {
 	return Texture(f.Ctx.Call("createTexture"))
 }
+func (f *Functions) DebugMessages() []DebugMessage {
+	// WebGL doesn't support KHR_debug.
+	return nil
+}
 func (f *Functions) DeleteBuffer(v Buffer) {
 	f.Ctx.Call("deleteBuffer", js.Value(v))
 }
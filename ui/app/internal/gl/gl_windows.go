@@ -28,7 +28,9 @@ var (
 	_glClearColor                         = LibGLESv2.NewProc("glClearColor")
 	_glClearDepthf                        = LibGLESv2.NewProc("glClearDepthf")
 	_glDeleteQueries                      = LibGLESv2.NewProc("glDeleteQueries")
+	_glGetDebugMessageLogKHR              = LibGLESv2.NewProc("glGetDebugMessageLogKHR")
 	_glCompileShader                      = LibGLESv2.NewProc("glCompileShader")
+	_glCopyTexSubImage2D                  = LibGLESv2.NewProc("glCopyTexSubImage2D")
 	_glGenBuffers                         = LibGLESv2.NewProc("glGenBuffers")
 	_glGenFramebuffers                    = LibGLESv2.NewProc("glGenFramebuffers")
 	_glCreateProgram                      = LibGLESv2.NewProc("glCreateProgram")
@@ -144,6 +146,9 @@ func (c *Functions) ClearDepthf(d float32) {
 func (c *Functions) CompileShader(s Shader) {
 	syscall.Syscall(_glCompileShader.Addr(), 1, uintptr(s.V), 0, 0)
 }
+func (c *Functions) CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	syscall.Syscall9(_glCopyTexSubImage2D.Addr(), 8, uintptr(target), uintptr(level), uintptr(xoffset), uintptr(yoffset), uintptr(x), uintptr(y), uintptr(width), uintptr(height), 0)
+}
 func (c *Functions) CreateBuffer() Buffer {
 	var buf uintptr
 	syscall.Syscall(_glGenBuffers.Addr(), 2, 1, uintptr(unsafe.Pointer(&buf)), 0)
@@ -267,6 +272,44 @@ func (c *Functions) GetProgramInfoLog(p Program) string {
 	syscall.Syscall6(_glGetProgramInfoLog.Addr(), 4, uintptr(p.V), uintptr(len(buf)), 0, uintptr(unsafe.Pointer(&buf[0])), 0, 0)
 	return string(buf)
 }
+
+// DebugMessages returns the messages logged by the KHR_debug
+// extension since the previous call, or nil if the extension
+// is not supported.
+func (c *Functions) DebugMessages() []DebugMessage {
+	if _glGetDebugMessageLogKHR.Find() != nil {
+		return nil
+	}
+	const count = 16
+	var (
+		sources, types, severities [count]uint32
+		ids                        [count]uint32
+		lengths                    [count]int32
+		buf                        [4096]byte
+	)
+	var msgs []DebugMessage
+	for {
+		n, _, _ := syscall.Syscall9(_glGetDebugMessageLogKHR.Addr(), 8, count, uintptr(len(buf)),
+			uintptr(unsafe.Pointer(&sources[0])), uintptr(unsafe.Pointer(&types[0])), uintptr(unsafe.Pointer(&ids[0])),
+			uintptr(unsafe.Pointer(&severities[0])), uintptr(unsafe.Pointer(&lengths[0])), uintptr(unsafe.Pointer(&buf[0])), 0)
+		if n == 0 {
+			return msgs
+		}
+		off := 0
+		for i := 0; i < int(n); i++ {
+			// The lengths include the terminating zero.
+			l := int(lengths[i])
+			msgs = append(msgs, DebugMessage{
+				Source:   Enum(sources[i]),
+				Type:     Enum(types[i]),
+				Severity: Enum(severities[i]),
+				ID:       uint(ids[i]),
+				Message:  string(buf[off : off+l-1]),
+			})
+			off += l
+		}
+	}
+}
 func (c *Functions) GetQueryObjectuiv(query Query, pname Enum) uint {
 	syscall.Syscall(_glGetQueryObjectuiv.Addr(), 3, uintptr(query.V), uintptr(pname), uintptr(unsafe.Pointer(&c.int32s[0])))
 	return uint(c.int32s[0])
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"fmt"
+
+	"gioui.org/ui/app/internal/gl"
+)
+
+// backend is the graphics API used by the renderer. Its
+// methods follow OpenGL ES 2 and 3, with objects and
+// constants from package gl.
+type backend interface {
+	// Buffers.
+	CreateBuffer() gl.Buffer
+	DeleteBuffer(v gl.Buffer)
+	BindBuffer(target gl.Enum, b gl.Buffer)
+	BufferData(target gl.Enum, src []byte, usage gl.Enum)
+
+	// Textures.
+	CreateTexture() gl.Texture
+	DeleteTexture(v gl.Texture)
+	ActiveTexture(texture gl.Enum)
+	BindTexture(target gl.Enum, t gl.Texture)
+	TexParameteri(target, pname gl.Enum, param int)
+	TexImage2D(target gl.Enum, level int, internalFormat int, width, height int, format, ty gl.Enum, data []byte)
+	TexSubImage2D(target gl.Enum, level int, x, y, width, height int, format, ty gl.Enum, data []byte)
+	CopyTexSubImage2D(target gl.Enum, level, xoffset, yoffset, x, y, width, height int)
+	PixelStorei(pname gl.Enum, param int32)
+
+	// Framebuffers.
+	CreateFramebuffer() gl.Framebuffer
+	DeleteFramebuffer(v gl.Framebuffer)
+	BindFramebuffer(target gl.Enum, fb gl.Framebuffer)
+	FramebufferTexture2D(target, attachment, texTarget gl.Enum, t gl.Texture, level int)
+	CheckFramebufferStatus(target gl.Enum) gl.Enum
+	InvalidateFramebuffer(target, attachment gl.Enum)
+
+	// Programs.
+	CreateProgram(vsSrc, fsSrc string, attribs []string) (gl.Program, error)
+	DeleteProgram(p gl.Program)
+	UseProgram(p gl.Program)
+	GetUniformLocation(p gl.Program, name string) gl.Uniform
+	Uniform1f(dst gl.Uniform, v float32)
+	Uniform1i(dst gl.Uniform, v int)
+	Uniform2f(dst gl.Uniform, v0, v1 float32)
+	Uniform3f(dst gl.Uniform, v0, v1, v2 float32)
+	Uniform4f(dst gl.Uniform, v0, v1, v2, v3 float32)
+	VertexAttribPointer(dst gl.Attrib, size int, ty gl.Enum, normalized bool, stride, offset int)
+	EnableVertexAttribArray(a gl.Attrib)
+	DisableVertexAttribArray(a gl.Attrib)
+
+	// Drawing.
+	Viewport(x, y, width, height int)
+	Scissor(x, y, width, height int32)
+	Enable(cap gl.Enum)
+	Disable(cap gl.Enum)
+	BlendFunc(sfactor, dfactor gl.Enum)
+	DepthFunc(f gl.Enum)
+	DepthMask(mask bool)
+	ClearColor(red, green, blue, alpha float32)
+	ClearDepthf(depth float32)
+	Clear(mask gl.Enum)
+	DrawArrays(mode gl.Enum, first, count int)
+	DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int)
+
+	// Timers.
+	CreateQuery() gl.Query
+	DeleteQuery(query gl.Query)
+	BeginQuery(target gl.Enum, query gl.Query)
+	EndQuery(target gl.Enum)
+	GetQueryObjectuiv(query gl.Query, pname gl.Enum) uint
+
+	// State.
+	GetString(pname gl.Enum) string
+	GetInteger(pname gl.Enum) int
+	GetBinding(pname gl.Enum) gl.Object
+	GetError() gl.Enum
+}
+
+// glBackend implements backend with OpenGL functions.
+type glBackend struct {
+	*gl.Functions
+}
+
+var _ backend = glBackend{}
+
+func (b glBackend) CreateProgram(vsSrc, fsSrc string, attribs []string) (gl.Program, error) {
+	return gl.CreateProgram(b.Functions, vsSrc, fsSrc, attribs)
+}
+
+// getUniformLocation is like GetUniformLocation but panics
+// if the uniform doesn't exist.
+func getUniformLocation(b backend, prog gl.Program, name string) gl.Uniform {
+	loc := b.GetUniformLocation(prog, name)
+	if !loc.Valid() {
+		panic(fmt.Errorf("uniform %s not found", name))
+	}
+	return loc
+}
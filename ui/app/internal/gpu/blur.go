@@ -0,0 +1,284 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"gioui.org/ui/app/internal/gl"
+	"gioui.org/ui/f32"
+)
+
+// effectOp is a shadow or a blurred backdrop. Effects are
+// rendered offscreen with separable Gaussian blur passes
+// and blended like textures.
+type effectOp struct {
+	// src is the area of the blur source in window
+	// coordinates, including the margin of the blur.
+	src   image.Rectangle
+	sigma float32
+	// shadow is set for shadows. Otherwise, the effect
+	// blurs the framebuffer contents in src.
+	shadow bool
+	// The shadow shape in window coordinates and its
+	// linear color.
+	rect   f32.Rectangle
+	radius float32
+	color  [4]float32
+	// fbo is the index of the first of the two
+	// blur targets of the effect.
+	fbo int
+	// used is cleared for culled effects.
+	used bool
+}
+
+type blurrer struct {
+	ctx            *context
+	prog           gl.Program
+	uUVScale       gl.Uniform
+	uTapStep       gl.Uniform
+	uUVMin, uUVMax gl.Uniform
+	uWeights       [maxBlurTaps]gl.Uniform
+	shadow         gl.Program
+	uRect, uRadius gl.Uniform
+	uColor         gl.Uniform
+}
+
+// maxBlurTaps is the number of taps on each side of the center,
+// including the center, of the blur program. Wider blurs skip
+// pixels between taps.
+const maxBlurTaps = 24
+
+func newBlurrer(ctx *context) *blurrer {
+	prog, err := ctx.CreateProgram(blurVSrc, blurFSrc, blitAttribs)
+	if err != nil {
+		panic(err)
+	}
+	shadow, err := ctx.CreateProgram(blurVSrc, shadowFSrc, blitAttribs)
+	if err != nil {
+		ctx.DeleteProgram(prog)
+		panic(err)
+	}
+	b := &blurrer{
+		ctx:      ctx,
+		prog:     prog,
+		uUVScale: getUniformLocation(ctx, prog, "uvScale"),
+		uTapStep: getUniformLocation(ctx, prog, "tapStep"),
+		uUVMin:   getUniformLocation(ctx, prog, "uvMin"),
+		uUVMax:   getUniformLocation(ctx, prog, "uvMax"),
+		shadow:   shadow,
+		uRect:    getUniformLocation(ctx, shadow, "rect"),
+		uRadius:  getUniformLocation(ctx, shadow, "radius"),
+		uColor:   getUniformLocation(ctx, shadow, "color"),
+	}
+	for i := range b.uWeights {
+		b.uWeights[i] = getUniformLocation(ctx, prog, fmt.Sprintf("weights[%d]", i))
+	}
+	ctx.UseProgram(prog)
+	ctx.Uniform1i(getUniformLocation(ctx, prog, "tex"), 0)
+	return b
+}
+
+func (b *blurrer) release() {
+	b.ctx.DeleteProgram(b.prog)
+	b.ctx.DeleteProgram(b.shadow)
+}
+
+// prepareEffects allocates the offscreen targets of effects.
+func (r *renderer) prepareEffects(effects []*effectOp) {
+	if len(effects) == 0 {
+		return
+	}
+	sizes := make([]image.Point, 0, len(effects)*2)
+	for _, e := range effects {
+		e.fbo = len(sizes)
+		sz := e.src.Size()
+		sizes = append(sizes, sz, sz)
+	}
+	r.effects.resize(r.ctx, r.ctx.caps.srgbaTriple, sizes)
+}
+
+// drawEffect renders e and binds the result for blending into the
+// framebuffer fbo that covers target in window coordinates. It
+// returns the transformation from quad coordinates of clip to the
+// texture coordinates of the result.
+func (r *renderer) drawEffect(e *effectOp, clip, target image.Rectangle, fbo gl.Framebuffer) f32.Affine2D {
+	src := e.src
+	if !e.shadow {
+		// Only the target contents can be blurred.
+		src = src.Intersect(target)
+	}
+	dst, tmp := r.effects.fbos[e.fbo], r.effects.fbos[e.fbo+1]
+	sz := src.Size()
+	uvScale := f32.Point{
+		X: float32(sz.X) / float32(dst.size.X),
+		Y: float32(sz.Y) / float32(dst.size.Y),
+	}
+	// The passes draw offscreen.
+	scissored := r.scissored
+	r.scissor(false)
+	r.ctx.Disable(gl.BLEND)
+	r.ctx.Disable(gl.DEPTH_TEST)
+	r.ctx.Viewport(0, 0, sz.X, sz.Y)
+	if e.shadow {
+		bindFramebuffer(r.ctx, dst.fbo)
+		r.ctx.UseProgram(r.blurrer.shadow)
+		// Convert the shape to the upside down
+		// coordinates of the target.
+		c := e.rect.Min.Add(e.rect.Max).Mul(.5)
+		r.ctx.Uniform4f(r.blurrer.uRect, c.X-float32(src.Min.X), float32(src.Max.Y)-c.Y, e.rect.Dx()/2, e.rect.Dy()/2)
+		r.ctx.Uniform1f(r.blurrer.uRadius, e.radius)
+		r.ctx.Uniform4f(r.blurrer.uColor, e.color[0], e.color[1], e.color[2], e.color[3])
+		r.ctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+	} else {
+		// Copy the backdrop from the current framebuffer, which
+		// is upside down in OpenGL coordinates.
+		r.ctx.BindTexture(gl.TEXTURE_2D, dst.tex)
+		r.ctx.CopyTexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, src.Min.X-target.Min.X, target.Max.Y-src.Max.Y, sz.X, sz.Y)
+	}
+	weights, stride := blurWeights(e.sigma)
+	r.ctx.UseProgram(r.blurrer.prog)
+	for i, w := range weights {
+		r.ctx.Uniform1f(r.blurrer.uWeights[i], w)
+	}
+	fsz := f32.Point{X: float32(dst.size.X), Y: float32(dst.size.Y)}
+	r.ctx.Uniform2f(r.blurrer.uUVScale, uvScale.X, uvScale.Y)
+	// Clamp samples to the centers of the edge pixels.
+	r.ctx.Uniform2f(r.blurrer.uUVMin, .5/fsz.X, .5/fsz.Y)
+	r.ctx.Uniform2f(r.blurrer.uUVMax, (float32(sz.X)-.5)/fsz.X, (float32(sz.Y)-.5)/fsz.Y)
+	// Blur horizontally into tmp and then vertically back into dst.
+	passes := [...]struct {
+		from, to stencilFBO
+		step     f32.Point
+	}{
+		{dst, tmp, f32.Point{X: float32(stride) / fsz.X}},
+		{tmp, dst, f32.Point{Y: float32(stride) / fsz.Y}},
+	}
+	for _, p := range passes {
+		bindFramebuffer(r.ctx, p.to.fbo)
+		r.ctx.BindTexture(gl.TEXTURE_2D, p.from.tex)
+		r.ctx.Uniform2f(r.blurrer.uTapStep, p.step.X, p.step.Y)
+		r.ctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+	}
+	r.ctx.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	tsz := target.Size()
+	r.ctx.Viewport(0, 0, tsz.X, tsz.Y)
+	r.ctx.Enable(gl.DEPTH_TEST)
+	r.ctx.Enable(gl.BLEND)
+	r.scissor(scissored)
+	r.ctx.BindTexture(gl.TEXTURE_2D, dst.tex)
+	sx := float32(clip.Dx()) / fsz.X
+	sy := float32(clip.Dy()) / fsz.Y
+	return f32.NewAffine2D(
+		sx, 0, float32(clip.Min.X-src.Min.X)/fsz.X,
+		0, -sy, float32(src.Max.Y-clip.Min.Y)/fsz.Y,
+	)
+}
+
+// blurWeights returns the weights of the blur program for a
+// Gaussian blur with standard deviation sigma, along with the
+// number of pixels between taps.
+func blurWeights(sigma float32) ([maxBlurTaps]float32, int) {
+	var w [maxBlurTaps]float32
+	stride := 1
+	if n := blurExtent(sigma); n >= maxBlurTaps {
+		stride = (n + maxBlurTaps - 2) / (maxBlurTaps - 1)
+	}
+	sigma /= float32(stride)
+	n := blurExtent(sigma)
+	if n == 0 {
+		w[0] = 1
+		return w, stride
+	}
+	var sum float32
+	for i := 0; i <= n && i < len(w); i++ {
+		x := float64(i) / float64(sigma)
+		w[i] = float32(math.Exp(-x * x / 2))
+		sum += w[i]
+		if i > 0 {
+			sum += w[i]
+		}
+	}
+	for i := range w {
+		w[i] /= sum
+	}
+	return w, stride
+}
+
+// blurExtent returns the number of pixels affected by a
+// Gaussian blur in each direction.
+func blurExtent(sigma float32) int {
+	return int(math.Ceil(float64(3 * sigma)))
+}
+
+// transformScale returns the average scale factor of t.
+func transformScale(t f32.Affine2D) float32 {
+	sx, hx, _, hy, sy, _ := t.Elems()
+	return float32(math.Sqrt(math.Abs(float64(sx*sy - hx*hy))))
+}
+
+const blurVSrc = `
+#version 100
+
+precision highp float;
+
+attribute vec2 pos;
+attribute vec2 uv;
+
+uniform vec2 uvScale;
+
+varying vec2 vUV;
+
+void main() {
+	gl_Position = vec4(pos, 0, 1);
+	vUV = (pos*0.5 + 0.5)*uvScale;
+}
+`
+
+var blurFSrc = fmt.Sprintf(`
+#version 100
+
+precision mediump float;
+
+#define MAX_TAPS %d
+
+// Use high precision to be pixel accurate for
+// large targets.
+varying highp vec2 vUV;
+uniform sampler2D tex;
+uniform highp vec2 tapStep;
+uniform highp vec2 uvMin;
+uniform highp vec2 uvMax;
+uniform float weights[MAX_TAPS];
+
+void main() {
+	vec4 c = texture2D(tex, vUV)*weights[0];
+	for (int i = 1; i < MAX_TAPS; i++) {
+		highp vec2 o = tapStep*float(i);
+		vec4 a = texture2D(tex, clamp(vUV - o, uvMin, uvMax));
+		vec4 b = texture2D(tex, clamp(vUV + o, uvMin, uvMax));
+		c += (a + b)*weights[i];
+	}
+	gl_FragColor = c;
+}
+`, maxBlurTaps)
+
+const shadowFSrc = `
+#version 100
+
+precision mediump float;
+
+// The center and half size of the shape.
+uniform highp vec4 rect;
+uniform highp float radius;
+uniform vec4 color;
+
+void main() {
+	// Signed distance to a rounded rectangle.
+	highp vec2 q = abs(gl_FragCoord.xy - rect.xy) - rect.zw + radius;
+	highp float d = length(max(q, 0.0)) + min(max(q.x, q.y), 0.0) - radius;
+	gl_FragColor = color*clamp(0.5 - d, 0.0, 1.0);
+}
+`
@@ -8,102 +8,228 @@ import (
 	"gioui.org/ui/internal/ops"
 )
 
+// CacheBudget limits the memory of the GPU resources kept
+// across frames. Resources in use are never evicted, so a
+// single frame may exceed the budget.
+type CacheBudget struct {
+	// TextureBytes is the budget for image textures. Zero
+	// means the default of 64 MiB.
+	TextureBytes int
+	// VertexBytes is the budget for path vertices. Zero
+	// means the default of 16 MiB.
+	VertexBytes int
+}
+
+// CacheStats contain the counters of a resource cache.
+type CacheStats struct {
+	// Hits counts the resources reused from
+	// earlier frames.
+	Hits int
+	// Misses counts the resources created.
+	Misses int
+	// Evictions counts the resources released to
+	// stay within the budget.
+	Evictions int
+	// Bytes is the memory used by the cached
+	// resources.
+	Bytes int
+}
+
 type resourceCache struct {
-	res    map[interface{}]resource
-	newRes map[interface{}]resource
+	res map[interface{}]*cacheEntry
+	lru lru
 }
 
 // opCache is like a resourceCache using the concrete Key
 // key type to avoid allocations.
 type opCache struct {
-	res    map[ops.Key]resource
-	newRes map[ops.Key]resource
+	res map[ops.Key]*cacheEntry
+	lru lru
 }
 
-func newResourceCache() *resourceCache {
-	return &resourceCache{
-		res:    make(map[interface{}]resource),
-		newRes: make(map[interface{}]resource),
+// lru tracks the memory and use of cache entries and
+// evicts the least recently used entries over budget.
+type lru struct {
+	budget int
+	// frame counts the completed frames.
+	frame int
+	// head is the sentinel of the list of entries,
+	// most recently used first.
+	head  cacheEntry
+	stats CacheStats
+}
+
+type cacheEntry struct {
+	res  resource
+	size int
+	// frame is the frame the entry was last used.
+	frame      int
+	prev, next *cacheEntry
+	// key or opKey is the key of the entry.
+	key   interface{}
+	opKey ops.Key
+}
+
+const (
+	defaultTextureBudget = 64 << 20
+	defaultVertexBudget  = 16 << 20
+)
+
+func newResourceCache(budget int) *resourceCache {
+	c := &resourceCache{
+		res: make(map[interface{}]*cacheEntry),
 	}
+	c.lru.init(budget)
+	return c
 }
 
 func (r *resourceCache) get(key interface{}) (resource, bool) {
-	v, exists := r.res[key]
-	if exists {
-		r.newRes[key] = v
+	e, exists := r.res[key]
+	if !exists {
+		r.lru.stats.Misses++
+		return nil, false
 	}
-	return v, exists
+	r.lru.use(e)
+	return e.res, true
 }
 
-func (r *resourceCache) put(key interface{}, val resource) {
-	if _, exists := r.newRes[key]; exists {
+// put adds a resource that uses size bytes of memory.
+func (r *resourceCache) put(key interface{}, val resource, size int) {
+	if _, exists := r.res[key]; exists {
 		panic(fmt.Errorf("key exists, %p", key))
 	}
-	r.res[key] = val
-	r.newRes[key] = val
+	e := &cacheEntry{res: val, size: size, key: key}
+	r.res[key] = e
+	r.lru.add(e)
 }
 
+// frame evicts resources over budget and completes
+// the frame.
 func (r *resourceCache) frame(ctx *context) {
-	for k, v := range r.res {
-		if _, exists := r.newRes[k]; !exists {
-			delete(r.res, k)
-			v.release(ctx)
-		}
-	}
-	for k, v := range r.newRes {
-		delete(r.newRes, k)
-		r.res[k] = v
-	}
+	r.lru.evict(ctx, func(e *cacheEntry) {
+		delete(r.res, e.key)
+	})
 }
 
 func (r *resourceCache) release(ctx *context) {
-	for _, v := range r.newRes {
-		v.release(ctx)
+	for _, e := range r.res {
+		e.res.release(ctx)
 	}
-	r.newRes = nil
 	r.res = nil
 }
 
-func newOpCache() *opCache {
-	return &opCache{
-		res:    make(map[ops.Key]resource),
-		newRes: make(map[ops.Key]resource),
+func newOpCache(budget int) *opCache {
+	c := &opCache{
+		res: make(map[ops.Key]*cacheEntry),
 	}
+	c.lru.init(budget)
+	return c
 }
 
 func (r *opCache) get(key ops.Key) (resource, bool) {
-	v, exists := r.res[key]
-	if exists {
-		r.newRes[key] = v
+	e, exists := r.res[key]
+	if !exists {
+		r.lru.stats.Misses++
+		return nil, false
 	}
-	return v, exists
+	r.lru.use(e)
+	return e.res, true
 }
 
-func (r *opCache) put(key ops.Key, val resource) {
-	if _, exists := r.newRes[key]; exists {
+// put adds a resource that uses size bytes of memory.
+func (r *opCache) put(key ops.Key, val resource, size int) {
+	if _, exists := r.res[key]; exists {
 		panic(fmt.Errorf("key exists, %#v", key))
 	}
-	r.res[key] = val
-	r.newRes[key] = val
+	e := &cacheEntry{res: val, size: size, opKey: key}
+	r.res[key] = e
+	r.lru.add(e)
 }
 
-func (r *opCache) frame(ctx *context) {
-	for k, v := range r.res {
-		if _, exists := r.newRes[k]; !exists {
-			delete(r.res, k)
-			v.release(ctx)
+// dropStale releases the resources not used in the current
+// frame whose keys are stale. The keys of ops include the
+// version of their op list, so the resources of an op list
+// that is reset every frame are unreachable after the frame.
+func (r *opCache) dropStale(ctx *context) {
+	for e := r.lru.head.prev; e != &r.lru.head; {
+		prev := e.prev
+		if e.frame != r.lru.frame && e.opKey.Stale() {
+			delete(r.res, e.opKey)
+			r.lru.remove(ctx, e)
 		}
+		e = prev
 	}
-	for k, v := range r.newRes {
-		delete(r.newRes, k)
-		r.res[k] = v
-	}
+}
+
+// frame evicts resources over budget and completes
+// the frame.
+func (r *opCache) frame(ctx *context) {
+	r.lru.evict(ctx, func(e *cacheEntry) {
+		delete(r.res, e.opKey)
+	})
 }
 
 func (r *opCache) release(ctx *context) {
-	for _, v := range r.newRes {
-		v.release(ctx)
+	for _, e := range r.res {
+		e.res.release(ctx)
 	}
-	r.newRes = nil
 	r.res = nil
 }
+
+func (l *lru) init(budget int) {
+	l.budget = budget
+	l.head.next = &l.head
+	l.head.prev = &l.head
+}
+
+// use marks e as used in the current frame.
+func (l *lru) use(e *cacheEntry) {
+	if e.frame != l.frame {
+		// Count reuse once per frame.
+		l.stats.Hits++
+	}
+	e.frame = l.frame
+	l.unlink(e)
+	l.link(e)
+}
+
+// add a new entry used in the current frame.
+func (l *lru) add(e *cacheEntry) {
+	e.frame = l.frame
+	l.stats.Bytes += e.size
+	l.link(e)
+}
+
+// evict releases the least recently used entries until the
+// memory is within budget or the remaining entries are in use.
+// The remove function is called for every evicted entry.
+func (l *lru) evict(ctx *context, remove func(e *cacheEntry)) {
+	for e := l.head.prev; e != &l.head && l.stats.Bytes > l.budget && e.frame != l.frame; {
+		prev := e.prev
+		remove(e)
+		l.remove(ctx, e)
+		l.stats.Evictions++
+		e = prev
+	}
+	l.frame++
+}
+
+// remove unlinks and releases e.
+func (l *lru) remove(ctx *context, e *cacheEntry) {
+	l.unlink(e)
+	e.res.release(ctx)
+	l.stats.Bytes -= e.size
+}
+
+func (l *lru) link(e *cacheEntry) {
+	e.prev = &l.head
+	e.next = l.head.next
+	e.next.prev = e
+	l.head.next = e
+}
+
+func (l *lru) unlink(e *cacheEntry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image/color"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/paint"
+)
+
+type fakeResource struct {
+	released bool
+}
+
+func (r *fakeResource) release(ctx *context) {
+	if r.released {
+		panic("resource released twice")
+	}
+	r.released = true
+}
+
+func TestCacheEvictionOrder(t *testing.T) {
+	c := newResourceCache(30)
+	a, b, d, e := new(fakeResource), new(fakeResource), new(fakeResource), new(fakeResource)
+	c.put("a", a, 10)
+	c.put("b", b, 10)
+	c.put("d", d, 10)
+	c.frame(nil)
+	// Use a and b, making d the least recently used.
+	c.get("b")
+	c.get("a")
+	c.put("e", e, 10)
+	c.frame(nil)
+	if !d.released || a.released || b.released || e.released {
+		t.Errorf("got released a=%v b=%v d=%v e=%v, expected only d", a.released, b.released, d.released, e.released)
+	}
+	if _, ok := c.get("d"); ok {
+		t.Error("evicted resource still cached")
+	}
+	if got := c.lru.stats.Bytes; got != 30 {
+		t.Errorf("got %d bytes, expected 30", got)
+	}
+	// b is now the least recently used.
+	c.put("f", new(fakeResource), 10)
+	c.get("a")
+	c.get("e")
+	c.frame(nil)
+	if !b.released {
+		t.Error("least recently used resource not evicted")
+	}
+}
+
+func TestCacheBudgetInUse(t *testing.T) {
+	c := newResourceCache(10)
+	a, b := new(fakeResource), new(fakeResource)
+	c.put("a", a, 10)
+	c.put("b", b, 10)
+	// Resources in use are kept over budget.
+	c.frame(nil)
+	if a.released || b.released {
+		t.Fatal("resource in use evicted")
+	}
+	if got := c.lru.stats.Bytes; got != 20 {
+		t.Errorf("got %d bytes, expected 20", got)
+	}
+	c.frame(nil)
+	if !a.released || b.released {
+		t.Errorf("got released a=%v b=%v, expected only a", a.released, b.released)
+	}
+	if got := c.lru.stats.Bytes; got != 10 {
+		t.Errorf("got %d bytes, expected 10", got)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := newResourceCache(15)
+	c.get("a")
+	c.put("a", new(fakeResource), 10)
+	// Hits in the frame a resource is added don't count.
+	c.get("a")
+	c.frame(nil)
+	c.get("a")
+	c.get("a")
+	c.get("b")
+	c.put("b", new(fakeResource), 10)
+	c.frame(nil)
+	c.frame(nil)
+	exp := CacheStats{Hits: 1, Misses: 2, Evictions: 1, Bytes: 10}
+	if got := c.lru.stats; got != exp {
+		t.Errorf("got stats %+v, expected %+v", got, exp)
+	}
+}
+
+func TestCacheBudgetDefaults(t *testing.T) {
+	g, err := newRecordingGPU(new(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Release()
+	if got := g.cache.lru.budget; got != defaultTextureBudget {
+		t.Errorf("got texture budget %d, expected %d", got, defaultTextureBudget)
+	}
+	if got := g.pathCache.lru.budget; got != defaultVertexBudget {
+		t.Errorf("got vertex budget %d, expected %d", got, defaultVertexBudget)
+	}
+}
+
+func TestOpCacheDropStale(t *testing.T) {
+	frameOps, retained := new(ui.Ops), new(ui.Ops)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(frameOps)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(retained)
+	c := newOpCache(1 << 20)
+	frameRes, retainedRes := new(fakeResource), new(fakeResource)
+	c.put(opKey(frameOps), frameRes, 10)
+	c.put(opKey(retained), retainedRes, 10)
+	c.frame(nil)
+
+	// The frame ops are reset, the retained ops are not.
+	frameOps.Reset()
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(frameOps)
+	if _, ok := c.get(opKey(frameOps)); ok {
+		t.Fatal("key of reset ops found")
+	}
+	c.dropStale(nil)
+	if !frameRes.released || retainedRes.released {
+		t.Errorf("got released frame=%v retained=%v, expected only frame", frameRes.released, retainedRes.released)
+	}
+	if _, ok := c.res[opKey(retained)]; !ok {
+		t.Error("retained resource dropped")
+	}
+	if got := c.lru.stats.Bytes; got != 10 {
+		t.Errorf("got %d bytes, expected 10", got)
+	}
+	if got := c.lru.stats.Evictions; got != 0 {
+		t.Errorf("got %d evictions, expected 0", got)
+	}
+}
+
+// opKey returns the key of the first op in o.
+func opKey(o *ui.Ops) ops.Key {
+	var r ops.Reader
+	r.Reset(o)
+	e, ok := r.Decode()
+	if !ok {
+		panic("no ops")
+	}
+	return e.Key
+}
@@ -10,8 +10,9 @@ import (
 )
 
 type context struct {
-	caps caps
-	*gl.Functions
+	caps     caps
+	counters frameCounters
+	backend
 }
 
 type caps struct {
@@ -32,9 +33,9 @@ type textureTriple struct {
 	typ            gl.Enum
 }
 
-func newContext(glctx gl.Context) (*context, error) {
+func newContext(b backend) (*context, error) {
 	ctx := &context{
-		Functions: glctx.Functions(),
+		backend: b,
 	}
 	exts := strings.Split(ctx.GetString(gl.EXTENSIONS), " ")
 	glVer := ctx.GetString(gl.VERSION)
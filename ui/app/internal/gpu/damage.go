@@ -0,0 +1,326 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
+)
+
+// damageTracker computes the damaged regions of a frame by
+// comparing its operations with the operations of the
+// previous frame.
+type damageTracker struct {
+	// valid is cleared when the previous frame is lost.
+	valid      bool
+	viewport   image.Point
+	clearColor [3]float32
+	keys       []damageKey
+	prevKeys   []damageKey
+	// prevIndices maps keys to their indices in prevKeys.
+	prevIndices map[damageKey][]int
+	matched     []bool
+	damage      []image.Rectangle
+}
+
+// damageKey identifies the drawing of an imageOp.
+type damageKey struct {
+	clip image.Rectangle
+	// mat is the material without its layer
+	// and effect.
+	mat material
+	// effect is the effect without its target.
+	effect effectOp
+	// path is the hash of the clip paths.
+	path uint64
+	mask *image.Alpha
+	// version is the version of the texture
+	// contents.
+	version int
+}
+
+// damageHistory tracks the damage of the most
+// recently presented frames.
+type damageHistory struct {
+	frames [][]image.Rectangle
+}
+
+const (
+	// maxDamageRects is the maximum number of damaged
+	// rectangles of a frame, before they are merged.
+	maxDamageRects = 8
+	// maxDamageHistory is the maximum buffer age that
+	// doesn't result in a full redraw.
+	maxDamageHistory = 4
+)
+
+// invalidate forces a full redraw of the next frame.
+func (t *damageTracker) invalidate() {
+	t.valid = false
+}
+
+// frame returns the damaged rectangles of the operations in d
+// compared to the previous frame. An empty result means
+// that nothing changed.
+func (t *damageTracker) frame(d *drawOps) []image.Rectangle {
+	t.keys = t.keys[:0]
+	// Add operations in drawing order, which is the
+	// z order.
+	zops, iops := d.zimageOps, d.imageOps
+	for len(zops) > 0 || len(iops) > 0 {
+		if len(iops) == 0 || len(zops) > 0 && zops[0].z < iops[0].z {
+			t.add(zops[0])
+			zops = zops[1:]
+		} else {
+			t.add(iops[0])
+			iops = iops[1:]
+		}
+	}
+	t.damage = t.damage[:0]
+	if !t.valid || t.viewport != d.viewport || t.clearColor != d.clearColor {
+		t.damage = append(t.damage, image.Rectangle{Max: d.viewport})
+	} else {
+		t.diff()
+	}
+	t.valid = true
+	t.viewport = d.viewport
+	t.clearColor = d.clearColor
+	t.keys, t.prevKeys = t.prevKeys, t.keys
+	if len(t.damage) > maxDamageRects {
+		t.damage = append(t.damage[:0], boundRects(t.damage))
+	}
+	return t.damage
+}
+
+// add the keys of img and the operations of its layer, if any.
+func (t *damageTracker) add(img imageOp) {
+	k := damageKey{
+		clip: img.clip,
+		mat:  img.material,
+	}
+	if tex := k.mat.texture; tex != nil {
+		k.version = tex.version
+	}
+	if l := k.mat.layer; l != nil {
+		for _, img := range l.imageOps {
+			t.add(img)
+		}
+		k.mat.layer = nil
+	}
+	if e := k.mat.effect; e != nil {
+		k.effect = *e
+		k.effect.fbo = 0
+		k.mat.effect = nil
+	}
+	if img.path != nil {
+		k.path = img.path.hash
+	}
+	k.mask = img.mask
+	t.keys = append(t.keys, k)
+}
+
+// diff damages the areas of the operations that were added,
+// removed or moved in the drawing order since the previous frame.
+func (t *damageTracker) diff() {
+	if t.prevIndices == nil {
+		t.prevIndices = make(map[damageKey][]int)
+	}
+	for k := range t.prevIndices {
+		delete(t.prevIndices, k)
+	}
+	for i, k := range t.prevKeys {
+		t.prevIndices[k] = append(t.prevIndices[k], i)
+	}
+	t.matched = t.matched[:0]
+	for range t.prevKeys {
+		t.matched = append(t.matched, false)
+	}
+	last := -1
+	for _, k := range t.keys {
+		idx := t.prevIndices[k]
+		if len(idx) == 0 {
+			t.addDamage(k.clip)
+			continue
+		}
+		prev := idx[0]
+		t.prevIndices[k] = idx[1:]
+		t.matched[prev] = true
+		if prev < last {
+			// The operation moved below an operation that was
+			// above it in the previous frame.
+			t.addDamage(k.clip)
+		} else {
+			last = prev
+		}
+	}
+	for i, m := range t.matched {
+		if !m {
+			t.addDamage(t.prevKeys[i].clip)
+		}
+	}
+}
+
+// addDamage adds r to the damage, merging it with the first
+// rectangle it overlaps.
+func (t *damageTracker) addDamage(r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+	for i, d := range t.damage {
+		if d.Overlaps(r) {
+			t.damage[i] = d.Union(r)
+			return
+		}
+	}
+	t.damage = append(t.damage, r)
+}
+
+// region returns the bounds of the area that must be redrawn for a
+// frame with the given damage and back buffer age.
+func (h *damageHistory) region(damage []image.Rectangle, age int, viewport image.Point) image.Rectangle {
+	if age == 0 || age-1 > len(h.frames) {
+		return image.Rectangle{Max: viewport}
+	}
+	reg := boundRects(damage)
+	for _, f := range h.frames[:age-1] {
+		reg = reg.Union(boundRects(f))
+	}
+	return reg
+}
+
+// add the damage of a presented frame.
+func (h *damageHistory) add(damage []image.Rectangle) {
+	var f []image.Rectangle
+	if len(h.frames) == maxDamageHistory {
+		// Reuse the oldest frame.
+		f = h.frames[len(h.frames)-1][:0]
+		h.frames = h.frames[:len(h.frames)-1]
+	}
+	f = append(f, damage...)
+	h.frames = append(h.frames, nil)
+	copy(h.frames[1:], h.frames)
+	h.frames[0] = f
+}
+
+// expandDamage expands reg and damage with the source areas of the
+// backdrop blurs they overlap, because a blur changes if its source
+// changes and depends on the contents of its source area.
+func expandDamage(reg image.Rectangle, damage []image.Rectangle, effects []*effectOp, viewport image.Point) (image.Rectangle, []image.Rectangle) {
+	for changed := true; changed; {
+		changed = false
+		for _, e := range effects {
+			src := e.src.Intersect(image.Rectangle{Max: viewport})
+			if e.shadow || !src.Overlaps(reg) || src.In(reg) {
+				continue
+			}
+			reg = reg.Union(src)
+			damage = append(damage, src)
+			changed = true
+		}
+	}
+	return reg, damage
+}
+
+// cull removes the operations outside reg, along with the
+// layers and effects that are no longer used.
+func (d *drawOps) cull(reg image.Rectangle) {
+	d.zimageOps = cullOps(d.zimageOps, reg)
+	d.imageOps = cullOps(d.imageOps, reg)
+	for _, l := range d.layers {
+		l.used = false
+	}
+	for _, e := range d.effects {
+		e.used = false
+	}
+	var mark func(ops []imageOp)
+	mark = func(ops []imageOp) {
+		for _, img := range ops {
+			if l := img.material.layer; l != nil {
+				l.used = true
+				mark(l.imageOps)
+			}
+			if e := img.material.effect; e != nil {
+				e.used = true
+			}
+		}
+	}
+	mark(d.imageOps)
+	layers := d.layers[:0]
+	for _, l := range d.layers {
+		if l.used {
+			layers = append(layers, l)
+		}
+	}
+	d.layers = layers
+	effects := d.effects[:0]
+	for _, e := range d.effects {
+		if e.used {
+			effects = append(effects, e)
+		}
+	}
+	d.effects = effects
+}
+
+func cullOps(ops []imageOp, reg image.Rectangle) []imageOp {
+	res := ops[:0]
+	for _, img := range ops {
+		if img.clip.Overlaps(reg) {
+			res = append(res, img)
+		}
+	}
+	return res
+}
+
+// pathHash returns the hash of the path with the given key and
+// vertices. Hashes are reused for keys of the previous frame.
+func (d *drawOps) pathHash(key ops.Key, aux []byte) uint64 {
+	h, exists := d.prevPathHashes[key]
+	if !exists {
+		h = hashBytes(fnvOffset, aux)
+	}
+	d.pathHashes[key] = h
+	return h
+}
+
+// clipHash returns the hash of a clip path with the hash pathHash,
+// offset by off and filled by the even-odd rule if evenOdd is set,
+// and intersected with the clip with the hash parent.
+func clipHash(parent, pathHash uint64, off f32.Point, evenOdd bool) uint64 {
+	h := hashUint64(parent, pathHash)
+	h = hashUint64(h, uint64(math.Float32bits(off.X))<<32|uint64(math.Float32bits(off.Y)))
+	return hashUint64(h, uint64(boolInt(evenOdd)))
+}
+
+func boundRects(rects []image.Rectangle) image.Rectangle {
+	var b image.Rectangle
+	for _, r := range rects {
+		b = b.Union(r)
+	}
+	return b
+}
+
+// FNV-1a parameters.
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+func hashBytes(h uint64, b []byte) uint64 {
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnvPrime
+	}
+	return h
+}
+
+func hashUint64(h, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= fnvPrime
+		v >>= 8
+	}
+	return h
+}
@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestDamageTrackerFrame(t *testing.T) {
+	a := colorOp(image.Rect(0, 0, 10, 10), 1)
+	b := colorOp(image.Rect(20, 0, 30, 10), 2)
+	c := colorOp(image.Rect(40, 0, 50, 10), 3)
+	tex := &texture{src: image.NewRGBA(image.Rect(0, 0, 1, 1)), version: 1}
+	texOp := imageOp{clip: image.Rect(60, 0, 70, 10), material: material{material: materialTexture, texture: tex}}
+	full := []image.Rectangle{{Max: image.Point{X: 100, Y: 100}}}
+	tests := []struct {
+		name       string
+		prev, next []imageOp
+		change     func(d *drawOps)
+		damage     []image.Rectangle
+	}{
+		{name: "unchanged", prev: []imageOp{a, b}, next: []imageOp{a, b}},
+		{name: "added", prev: []imageOp{a}, next: []imageOp{a, b, c}, damage: []image.Rectangle{b.clip, c.clip}},
+		{name: "removed", prev: []imageOp{a, b, c}, next: []imageOp{b}, damage: []image.Rectangle{a.clip, c.clip}},
+		{name: "changed", prev: []imageOp{a, b}, next: []imageOp{colorOp(a.clip, 4), b}, damage: []image.Rectangle{a.clip}},
+		{name: "reordered", prev: []imageOp{a, b, c}, next: []imageOp{c, a, b}, damage: []image.Rectangle{a.clip, b.clip}},
+		{name: "duplicate", prev: []imageOp{a, a}, next: []imageOp{a}, damage: []image.Rectangle{a.clip}},
+		{
+			name: "texture version",
+			prev: []imageOp{a, texOp}, next: []imageOp{a, texOp},
+			change: func(d *drawOps) { tex.version++ },
+			damage: []image.Rectangle{texOp.clip},
+		},
+		{
+			name: "viewport",
+			prev: []imageOp{a}, next: []imageOp{a},
+			change: func(d *drawOps) { d.viewport.X = 50 },
+			damage: []image.Rectangle{{Max: image.Point{X: 50, Y: 100}}},
+		},
+		{
+			name: "clear color",
+			prev: []imageOp{a}, next: []imageOp{a},
+			change: func(d *drawOps) { d.clearColor[0] = 1 },
+			damage: full,
+		},
+		{
+			name: "merged",
+			prev: manyOps(0, maxDamageRects+1), next: manyOps(1, maxDamageRects+1),
+			damage: []image.Rectangle{image.Rect(0, 0, 10, 10*(maxDamageRects+1))},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tr damageTracker
+			d := &drawOps{viewport: image.Point{X: 100, Y: 100}, imageOps: test.prev}
+			if got := tr.frame(d); !reflect.DeepEqual(got, full) {
+				t.Errorf("got first frame damage %v, expected %v", got, full)
+			}
+			d.imageOps = test.next
+			if test.change != nil {
+				test.change(d)
+			}
+			got := tr.frame(d)
+			if len(got) != 0 || len(test.damage) != 0 {
+				if !reflect.DeepEqual(got, test.damage) {
+					t.Errorf("got damage %v, expected %v", got, test.damage)
+				}
+			}
+		})
+	}
+}
+
+func TestDamageTrackerZOrder(t *testing.T) {
+	a := colorOp(image.Rect(0, 0, 10, 10), 1)
+	b := colorOp(image.Rect(20, 0, 30, 10), 2)
+	a.z, b.z = 1, 2
+	var tr damageTracker
+	d := &drawOps{viewport: image.Point{X: 100, Y: 100}, zimageOps: []imageOp{a}, imageOps: []imageOp{b}}
+	tr.frame(d)
+	// Moving an opaque op to the blended ops at the same
+	// z order is not a change.
+	d.zimageOps, d.imageOps = nil, []imageOp{a, b}
+	if got := tr.frame(d); len(got) != 0 {
+		t.Errorf("got damage %v, expected none", got)
+	}
+	// The layer ops are compared too.
+	l := &layerOp{imageOps: []imageOp{colorOp(image.Rect(5, 5, 8, 8), 3)}}
+	b.material.layer = l
+	d.imageOps = []imageOp{a, b}
+	if got := tr.frame(d); !reflect.DeepEqual(got, []image.Rectangle{image.Rect(5, 5, 8, 8)}) {
+		t.Errorf("got damage %v, expected the layer op", got)
+	}
+}
+
+func TestDamageHistoryRegion(t *testing.T) {
+	viewport := image.Point{X: 100, Y: 100}
+	full := image.Rectangle{Max: viewport}
+	var h damageHistory
+	h.add([]image.Rectangle{image.Rect(0, 0, 10, 10)})
+	h.add([]image.Rectangle{image.Rect(20, 0, 30, 10), image.Rect(20, 20, 30, 30)})
+	h.add(nil)
+	damage := []image.Rectangle{image.Rect(50, 50, 60, 60)}
+	tests := []struct {
+		age int
+		exp image.Rectangle
+	}{
+		// Unknown buffer contents.
+		{0, full},
+		{1, image.Rect(50, 50, 60, 60)},
+		{2, image.Rect(50, 50, 60, 60)},
+		{3, image.Rect(20, 0, 60, 60)},
+		{4, image.Rect(0, 0, 60, 60)},
+		// Older than the history.
+		{5, full},
+		{10, full},
+	}
+	for _, test := range tests {
+		if got := h.region(damage, test.age, viewport); got != test.exp {
+			t.Errorf("age %d: got region %v, expected %v", test.age, got, test.exp)
+		}
+	}
+	// The history is limited to maxDamageHistory frames.
+	for i := 0; i < 2*maxDamageHistory; i++ {
+		h.add(damage)
+	}
+	if n := len(h.frames); n != maxDamageHistory {
+		t.Errorf("got %d frames of history, expected %d", n, maxDamageHistory)
+	}
+	if got := h.region(damage, maxDamageHistory+2, viewport); got != full {
+		t.Errorf("got region %v beyond the history, expected %v", got, full)
+	}
+}
+
+func TestExpandDamage(t *testing.T) {
+	viewport := image.Point{X: 100, Y: 100}
+	tests := []struct {
+		name    string
+		reg     image.Rectangle
+		effects []*effectOp
+		expReg  image.Rectangle
+		added   []image.Rectangle
+	}{
+		{
+			name:    "outside",
+			reg:     image.Rect(0, 0, 10, 10),
+			effects: []*effectOp{{src: image.Rect(50, 50, 70, 70)}},
+			expReg:  image.Rect(0, 0, 10, 10),
+		},
+		{
+			name:    "overlapping",
+			reg:     image.Rect(45, 45, 55, 55),
+			effects: []*effectOp{{src: image.Rect(50, 50, 70, 70)}},
+			expReg:  image.Rect(45, 45, 70, 70),
+			added:   []image.Rectangle{image.Rect(50, 50, 70, 70)},
+		},
+		{
+			name:    "contained",
+			reg:     image.Rect(0, 0, 80, 80),
+			effects: []*effectOp{{src: image.Rect(50, 50, 70, 70)}},
+			expReg:  image.Rect(0, 0, 80, 80),
+		},
+		{
+			name:    "shadow",
+			reg:     image.Rect(45, 45, 55, 55),
+			effects: []*effectOp{{src: image.Rect(50, 50, 70, 70), shadow: true}},
+			expReg:  image.Rect(45, 45, 55, 55),
+		},
+		{
+			// The first blur only overlaps the region after
+			// it is expanded by the second.
+			name: "chained",
+			reg:  image.Rect(0, 0, 10, 10),
+			effects: []*effectOp{
+				{src: image.Rect(15, 0, 25, 10)},
+				{src: image.Rect(5, 0, 20, 10)},
+			},
+			expReg: image.Rect(0, 0, 25, 10),
+			added:  []image.Rectangle{image.Rect(5, 0, 20, 10), image.Rect(15, 0, 25, 10)},
+		},
+		{
+			name:    "clipped",
+			reg:     image.Rect(85, 85, 95, 95),
+			effects: []*effectOp{{src: image.Rect(80, 80, 120, 120)}},
+			expReg:  image.Rect(80, 80, 100, 100),
+			added:   []image.Rectangle{image.Rect(80, 80, 100, 100)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			damage := []image.Rectangle{test.reg}
+			reg, damage := expandDamage(test.reg, damage, test.effects, viewport)
+			if reg != test.expReg {
+				t.Errorf("got region %v, expected %v", reg, test.expReg)
+			}
+			exp := append([]image.Rectangle{test.reg}, test.added...)
+			if !reflect.DeepEqual(damage, exp) {
+				t.Errorf("got damage %v, expected %v", damage, exp)
+			}
+		})
+	}
+}
+
+func TestCull(t *testing.T) {
+	in := image.Rect(0, 0, 10, 10)
+	out := image.Rect(50, 50, 60, 60)
+	// A layer inside the region with a nested layer
+	// and a blur, and a layer outside it.
+	nested := &layerOp{imageOps: []imageOp{colorOp(out, 1)}}
+	blur := &effectOp{src: out}
+	inLayer := &layerOp{imageOps: []imageOp{
+		{clip: in, material: material{layer: nested}},
+		{clip: in, material: material{effect: blur}},
+	}}
+	outLayer := &layerOp{imageOps: []imageOp{colorOp(in, 2)}}
+	shadow := &effectOp{shadow: true}
+	d := &drawOps{
+		zimageOps: []imageOp{colorOp(in, 3), colorOp(out, 4)},
+		imageOps: []imageOp{
+			{clip: in, material: material{layer: inLayer}},
+			{clip: out, material: material{layer: outLayer}},
+			{clip: out, material: material{effect: shadow}},
+		},
+		layers:  []*layerOp{nested, inLayer, outLayer},
+		effects: []*effectOp{blur, shadow},
+	}
+	d.cull(image.Rect(5, 5, 20, 20))
+	if len(d.zimageOps) != 1 || d.zimageOps[0].clip != in {
+		t.Errorf("got z ops %v, expected the op inside the region", d.zimageOps)
+	}
+	if len(d.imageOps) != 1 || d.imageOps[0].material.layer != inLayer {
+		t.Errorf("got ops %v, expected the layer inside the region", d.imageOps)
+	}
+	if !reflect.DeepEqual(d.layers, []*layerOp{nested, inLayer}) {
+		t.Errorf("got layers %v, expected the layers used inside the region", d.layers)
+	}
+	if !reflect.DeepEqual(d.effects, []*effectOp{blur}) {
+		t.Errorf("got effects %v, expected the blur used inside the region", d.effects)
+	}
+}
+
+// colorOp returns an op that fills clip with a color
+// identified by c.
+func colorOp(clip image.Rectangle, c float32) imageOp {
+	return imageOp{
+		clip:     clip,
+		material: material{material: materialColor, color: [4]float32{c, 0, 0, 1}},
+	}
+}
+
+// manyOps returns n stacked ops with the color c.
+func manyOps(c float32, n int) []imageOp {
+	var ops []imageOp
+	for i := 0; i < n; i++ {
+		ops = append(ops, colorOp(image.Rect(0, i*10, 10, i*10+10), c))
+	}
+	return ops
+}
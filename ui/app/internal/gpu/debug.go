@@ -0,0 +1,423 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+
+	"gioui.org/ui/app/internal/gl"
+)
+
+// debugBackend wraps a backend and checks for errors after
+// every call. Errors are logged along with the Go stack of the
+// call, and so are the messages from the KHR_debug extension,
+// if supported. The calls of a frame are traced and can be
+// logged on request.
+type debugBackend struct {
+	b backend
+	// messages is the source of KHR_debug messages,
+	// if any.
+	messages debugMessenger
+	// trace is the calls since the start of the frame.
+	trace []string
+	// dump is set if the trace is logged at the end
+	// of the frame.
+	dump bool
+}
+
+type debugMessenger interface {
+	DebugMessages() []gl.DebugMessage
+}
+
+// debugCall is a traced call.
+type debugCall struct {
+	d *debugBackend
+	// idx is the index of the call in the trace.
+	idx int
+}
+
+// maxDebugErrors limits the errors reported for a single
+// call, because a lost context may report errors forever.
+const maxDebugErrors = 8
+
+func newDebugBackend(b backend) *debugBackend {
+	d := &debugBackend{b: b}
+	exts := strings.Split(b.GetString(gl.EXTENSIONS), " ")
+	if m, ok := b.(debugMessenger); ok && hasExtension(exts, "GL_KHR_debug") {
+		d.messages = m
+		// Report messages during the calls that caused them.
+		b.Enable(gl.DEBUG_OUTPUT)
+		b.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+		// Discard earlier messages.
+		m.DebugMessages()
+	}
+	return d
+}
+
+// beginFrame starts the trace of a frame. If dump is set, the
+// trace is logged by endFrame.
+func (d *debugBackend) beginFrame(dump bool) {
+	if d == nil {
+		return
+	}
+	d.trace = d.trace[:0]
+	d.dump = dump
+}
+
+func (d *debugBackend) endFrame() {
+	if d == nil || !d.dump {
+		return
+	}
+	d.dump = false
+	var b strings.Builder
+	fmt.Fprintf(&b, "gl: trace of %d calls:\n", len(d.trace))
+	for _, c := range d.trace {
+		b.WriteString("\t")
+		b.WriteString(c)
+		b.WriteString("\n")
+	}
+	log.Print(b.String())
+}
+
+// called traces a completed call and reports its errors and
+// debug messages.
+func (d *debugBackend) called(name string, args ...interface{}) debugCall {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('(')
+	for i, a := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(formatArg(a))
+	}
+	b.WriteByte(')')
+	call := b.String()
+	d.trace = append(d.trace, call)
+	for i := 0; i < maxDebugErrors; i++ {
+		err := d.b.GetError()
+		if err == 0 {
+			break
+		}
+		log.Printf("gl: %s: error %#x\n%s", call, err, callers())
+	}
+	if d.messages != nil {
+		for _, m := range d.messages.DebugMessages() {
+			log.Printf("gl: %s: %s (source %#x, type %#x, severity %#x, id %d)", call, m.Message, m.Source, m.Type, m.Severity, m.ID)
+		}
+	}
+	return debugCall{d: d, idx: len(d.trace) - 1}
+}
+
+// result adds the result of c to its trace.
+func (c debugCall) result(v interface{}) {
+	c.d.trace[c.idx] += " = " + formatArg(v)
+}
+
+func formatArg(a interface{}) string {
+	switch a := a.(type) {
+	case gl.Enum:
+		return fmt.Sprintf("%#x", uint(a))
+	case []byte:
+		return fmt.Sprintf("[%d bytes]", len(a))
+	case string:
+		if len(a) > 32 {
+			return fmt.Sprintf("[%d bytes]", len(a))
+		}
+		return fmt.Sprintf("%q", a)
+	default:
+		return fmt.Sprintf("%v", a)
+	}
+}
+
+// callers formats the Go stack of the call that is
+// being checked.
+func callers() string {
+	var pcs [32]uintptr
+	// Skip callers, called and the backend method.
+	n := runtime.Callers(4, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+func (d *debugBackend) CreateBuffer() gl.Buffer {
+	v := d.b.CreateBuffer()
+	d.called("CreateBuffer").result(v)
+	return v
+}
+
+func (d *debugBackend) DeleteBuffer(v gl.Buffer) {
+	d.b.DeleteBuffer(v)
+	d.called("DeleteBuffer", v)
+}
+
+func (d *debugBackend) BindBuffer(target gl.Enum, b gl.Buffer) {
+	d.b.BindBuffer(target, b)
+	d.called("BindBuffer", target, b)
+}
+
+func (d *debugBackend) BufferData(target gl.Enum, src []byte, usage gl.Enum) {
+	d.b.BufferData(target, src, usage)
+	d.called("BufferData", target, src, usage)
+}
+
+func (d *debugBackend) CreateTexture() gl.Texture {
+	v := d.b.CreateTexture()
+	d.called("CreateTexture").result(v)
+	return v
+}
+
+func (d *debugBackend) DeleteTexture(v gl.Texture) {
+	d.b.DeleteTexture(v)
+	d.called("DeleteTexture", v)
+}
+
+func (d *debugBackend) ActiveTexture(texture gl.Enum) {
+	d.b.ActiveTexture(texture)
+	d.called("ActiveTexture", texture)
+}
+
+func (d *debugBackend) BindTexture(target gl.Enum, t gl.Texture) {
+	d.b.BindTexture(target, t)
+	d.called("BindTexture", target, t)
+}
+
+func (d *debugBackend) TexParameteri(target, pname gl.Enum, param int) {
+	d.b.TexParameteri(target, pname, param)
+	d.called("TexParameteri", target, pname, param)
+}
+
+func (d *debugBackend) TexImage2D(target gl.Enum, level int, internalFormat int, width, height int, format, ty gl.Enum, data []byte) {
+	d.b.TexImage2D(target, level, internalFormat, width, height, format, ty, data)
+	d.called("TexImage2D", target, level, internalFormat, width, height, format, ty, data)
+}
+
+func (d *debugBackend) TexSubImage2D(target gl.Enum, level int, x, y, width, height int, format, ty gl.Enum, data []byte) {
+	d.b.TexSubImage2D(target, level, x, y, width, height, format, ty, data)
+	d.called("TexSubImage2D", target, level, x, y, width, height, format, ty, data)
+}
+
+func (d *debugBackend) CopyTexSubImage2D(target gl.Enum, level, xoffset, yoffset, x, y, width, height int) {
+	d.b.CopyTexSubImage2D(target, level, xoffset, yoffset, x, y, width, height)
+	d.called("CopyTexSubImage2D", target, level, xoffset, yoffset, x, y, width, height)
+}
+
+func (d *debugBackend) PixelStorei(pname gl.Enum, param int32) {
+	d.b.PixelStorei(pname, param)
+	d.called("PixelStorei", pname, param)
+}
+
+func (d *debugBackend) CreateFramebuffer() gl.Framebuffer {
+	v := d.b.CreateFramebuffer()
+	d.called("CreateFramebuffer").result(v)
+	return v
+}
+
+func (d *debugBackend) DeleteFramebuffer(v gl.Framebuffer) {
+	d.b.DeleteFramebuffer(v)
+	d.called("DeleteFramebuffer", v)
+}
+
+func (d *debugBackend) BindFramebuffer(target gl.Enum, fb gl.Framebuffer) {
+	d.b.BindFramebuffer(target, fb)
+	d.called("BindFramebuffer", target, fb)
+}
+
+func (d *debugBackend) FramebufferTexture2D(target, attachment, texTarget gl.Enum, t gl.Texture, level int) {
+	d.b.FramebufferTexture2D(target, attachment, texTarget, t, level)
+	d.called("FramebufferTexture2D", target, attachment, texTarget, t, level)
+}
+
+func (d *debugBackend) CheckFramebufferStatus(target gl.Enum) gl.Enum {
+	v := d.b.CheckFramebufferStatus(target)
+	d.called("CheckFramebufferStatus", target).result(v)
+	return v
+}
+
+func (d *debugBackend) InvalidateFramebuffer(target, attachment gl.Enum) {
+	d.b.InvalidateFramebuffer(target, attachment)
+	d.called("InvalidateFramebuffer", target, attachment)
+}
+
+func (d *debugBackend) CreateProgram(vsSrc, fsSrc string, attribs []string) (gl.Program, error) {
+	v, err := d.b.CreateProgram(vsSrc, fsSrc, attribs)
+	d.called("CreateProgram", vsSrc, fsSrc, attribs).result(v)
+	return v, err
+}
+
+func (d *debugBackend) DeleteProgram(p gl.Program) {
+	d.b.DeleteProgram(p)
+	d.called("DeleteProgram", p)
+}
+
+func (d *debugBackend) UseProgram(p gl.Program) {
+	d.b.UseProgram(p)
+	d.called("UseProgram", p)
+}
+
+func (d *debugBackend) GetUniformLocation(p gl.Program, name string) gl.Uniform {
+	v := d.b.GetUniformLocation(p, name)
+	d.called("GetUniformLocation", p, name).result(v)
+	return v
+}
+
+func (d *debugBackend) Uniform1f(dst gl.Uniform, v float32) {
+	d.b.Uniform1f(dst, v)
+	d.called("Uniform1f", dst, v)
+}
+
+func (d *debugBackend) Uniform1i(dst gl.Uniform, v int) {
+	d.b.Uniform1i(dst, v)
+	d.called("Uniform1i", dst, v)
+}
+
+func (d *debugBackend) Uniform2f(dst gl.Uniform, v0, v1 float32) {
+	d.b.Uniform2f(dst, v0, v1)
+	d.called("Uniform2f", dst, v0, v1)
+}
+
+func (d *debugBackend) Uniform3f(dst gl.Uniform, v0, v1, v2 float32) {
+	d.b.Uniform3f(dst, v0, v1, v2)
+	d.called("Uniform3f", dst, v0, v1, v2)
+}
+
+func (d *debugBackend) Uniform4f(dst gl.Uniform, v0, v1, v2, v3 float32) {
+	d.b.Uniform4f(dst, v0, v1, v2, v3)
+	d.called("Uniform4f", dst, v0, v1, v2, v3)
+}
+
+func (d *debugBackend) VertexAttribPointer(dst gl.Attrib, size int, ty gl.Enum, normalized bool, stride, offset int) {
+	d.b.VertexAttribPointer(dst, size, ty, normalized, stride, offset)
+	d.called("VertexAttribPointer", dst, size, ty, normalized, stride, offset)
+}
+
+func (d *debugBackend) EnableVertexAttribArray(a gl.Attrib) {
+	d.b.EnableVertexAttribArray(a)
+	d.called("EnableVertexAttribArray", a)
+}
+
+func (d *debugBackend) DisableVertexAttribArray(a gl.Attrib) {
+	d.b.DisableVertexAttribArray(a)
+	d.called("DisableVertexAttribArray", a)
+}
+
+func (d *debugBackend) Viewport(x, y, width, height int) {
+	d.b.Viewport(x, y, width, height)
+	d.called("Viewport", x, y, width, height)
+}
+
+func (d *debugBackend) Scissor(x, y, width, height int32) {
+	d.b.Scissor(x, y, width, height)
+	d.called("Scissor", x, y, width, height)
+}
+
+func (d *debugBackend) Enable(cap gl.Enum) {
+	d.b.Enable(cap)
+	d.called("Enable", cap)
+}
+
+func (d *debugBackend) Disable(cap gl.Enum) {
+	d.b.Disable(cap)
+	d.called("Disable", cap)
+}
+
+func (d *debugBackend) BlendFunc(sfactor, dfactor gl.Enum) {
+	d.b.BlendFunc(sfactor, dfactor)
+	d.called("BlendFunc", sfactor, dfactor)
+}
+
+func (d *debugBackend) DepthFunc(f gl.Enum) {
+	d.b.DepthFunc(f)
+	d.called("DepthFunc", f)
+}
+
+func (d *debugBackend) DepthMask(mask bool) {
+	d.b.DepthMask(mask)
+	d.called("DepthMask", mask)
+}
+
+func (d *debugBackend) ClearColor(red, green, blue, alpha float32) {
+	d.b.ClearColor(red, green, blue, alpha)
+	d.called("ClearColor", red, green, blue, alpha)
+}
+
+func (d *debugBackend) ClearDepthf(depth float32) {
+	d.b.ClearDepthf(depth)
+	d.called("ClearDepthf", depth)
+}
+
+func (d *debugBackend) Clear(mask gl.Enum) {
+	d.b.Clear(mask)
+	d.called("Clear", mask)
+}
+
+func (d *debugBackend) DrawArrays(mode gl.Enum, first, count int) {
+	d.b.DrawArrays(mode, first, count)
+	d.called("DrawArrays", mode, first, count)
+}
+
+func (d *debugBackend) DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int) {
+	d.b.DrawElements(mode, count, ty, offset)
+	d.called("DrawElements", mode, count, ty, offset)
+}
+
+func (d *debugBackend) CreateQuery() gl.Query {
+	v := d.b.CreateQuery()
+	d.called("CreateQuery").result(v)
+	return v
+}
+
+func (d *debugBackend) DeleteQuery(query gl.Query) {
+	d.b.DeleteQuery(query)
+	d.called("DeleteQuery", query)
+}
+
+func (d *debugBackend) BeginQuery(target gl.Enum, query gl.Query) {
+	d.b.BeginQuery(target, query)
+	d.called("BeginQuery", target, query)
+}
+
+func (d *debugBackend) EndQuery(target gl.Enum) {
+	d.b.EndQuery(target)
+	d.called("EndQuery", target)
+}
+
+func (d *debugBackend) GetQueryObjectuiv(query gl.Query, pname gl.Enum) uint {
+	v := d.b.GetQueryObjectuiv(query, pname)
+	d.called("GetQueryObjectuiv", query, pname).result(v)
+	return v
+}
+
+func (d *debugBackend) GetString(pname gl.Enum) string {
+	v := d.b.GetString(pname)
+	d.called("GetString", pname).result(v)
+	return v
+}
+
+func (d *debugBackend) GetInteger(pname gl.Enum) int {
+	v := d.b.GetInteger(pname)
+	d.called("GetInteger", pname).result(v)
+	return v
+}
+
+func (d *debugBackend) GetBinding(pname gl.Enum) gl.Object {
+	v := d.b.GetBinding(pname)
+	d.called("GetBinding", pname).result(v)
+	return v
+}
+
+func (d *debugBackend) GetError() gl.Enum {
+	// Errors are checked after every call.
+	return d.b.GetError()
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+
+	"gioui.org/ui/app/internal/gl"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/opconst"
+	"gioui.org/ui/paint"
+)
+
+// glyphAtlas caches glyph masks in alpha textures. Glyphs are
+// packed on the client goroutine during collect and uploaded
+// on the GL thread.
+type glyphAtlas struct {
+	packer packer
+	glyphs map[*image.Alpha]*glyph
+	// pending are the glyphs not yet uploaded.
+	pending []*glyph
+	// textures are the atlas pages.
+	textures []gl.Texture
+}
+
+type glyph struct {
+	mask  *image.Alpha
+	place placement
+}
+
+// glyphOp is the shadow of paint.GlyphOp.
+type glyphOp struct {
+	mask   *image.Alpha
+	origin image.Point
+}
+
+const (
+	// glyphAtlasSize is the width and height of the atlas pages.
+	glyphAtlasSize = 1024
+	// maxGlyphPages is the number of pages the atlas may use
+	// before it is cleared and the glyphs in use are packed
+	// again.
+	maxGlyphPages = 4
+)
+
+func newGlyphAtlas() *glyphAtlas {
+	a := &glyphAtlas{
+		glyphs: make(map[*image.Alpha]*glyph),
+	}
+	a.packer.maxDim = glyphAtlasSize
+	return a
+}
+
+// frame prepares the atlas for the glyphs of a new frame.
+func (a *glyphAtlas) frame() {
+	if len(a.packer.sizes) <= maxGlyphPages {
+		return
+	}
+	a.packer.clear()
+	for m := range a.glyphs {
+		delete(a.glyphs, m)
+	}
+	a.pending = a.pending[:0]
+}
+
+// get returns the glyph for mask, packing it into the atlas
+// if it is not already present.
+func (a *glyphAtlas) get(mask *image.Alpha) (*glyph, bool) {
+	if g, exists := a.glyphs[mask]; exists {
+		return g, true
+	}
+	if mask.Rect.Empty() {
+		return nil, false
+	}
+	place, ok := a.packer.add(mask.Rect.Size())
+	if !ok {
+		return nil, false
+	}
+	g := &glyph{mask: mask, place: place}
+	a.glyphs[mask] = g
+	a.pending = append(a.pending, g)
+	return g, true
+}
+
+// upload the pending glyphs to the atlas textures.
+func (a *glyphAtlas) upload(ctx *context) {
+	for len(a.textures) < len(a.packer.sizes) {
+		tex := createTexture(ctx)
+		// Glyphs are drawn at whole pixels.
+		ctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+		ctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		tt := ctx.caps.alphaTriple
+		ctx.TexImage2D(gl.TEXTURE_2D, 0, tt.internalFormat, glyphAtlasSize, glyphAtlasSize, tt.format, tt.typ, nil)
+		a.textures = append(a.textures, tex)
+	}
+	if len(a.pending) == 0 {
+		return
+	}
+	// Glyph rows are not aligned.
+	ctx.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	tt := ctx.caps.alphaTriple
+	for _, g := range a.pending {
+		m := g.mask
+		sz := m.Rect.Size()
+		pixels := m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):]
+		if m.Stride != sz.X {
+			pixels = make([]byte, sz.X*sz.Y)
+			for y := 0; y < sz.Y; y++ {
+				start := m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y+y)
+				copy(pixels[y*sz.X:], m.Pix[start:start+sz.X])
+			}
+		}
+		ctx.BindTexture(gl.TEXTURE_2D, a.textures[g.place.Idx])
+		ctx.TexSubImage2D(gl.TEXTURE_2D, 0, g.place.Pos.X, g.place.Pos.Y, sz.X, sz.Y, tt.format, tt.typ, pixels)
+	}
+	ctx.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	a.pending = a.pending[:0]
+}
+
+func (a *glyphAtlas) release(ctx *context) {
+	for _, t := range a.textures {
+		ctx.DeleteTexture(t)
+	}
+	a.textures = nil
+}
+
+// addGlyphs adds an operation for drawing the material of state
+// through each glyph of the state.
+func (d *drawOps) addGlyphs(state *drawState, rect f32.Rectangle, clip image.Rectangle) {
+	// Snap the glyphs to the pixel grid.
+	_, off := state.t.Affine2D().Split()
+	pos := image.Point{X: round(off.X), Y: round(off.Y)}
+	state.z++
+	z := float32(state.z)*2/zdepth - 1.0
+	for _, g := range state.glyphs {
+		r := g.mask.Rect.Add(g.origin).Add(pos)
+		gclip := clip.Intersect(r)
+		if gclip.Empty() {
+			continue
+		}
+		gly, ok := d.glyphAtlas.get(g.mask)
+		if !ok {
+			continue
+		}
+		place := gly.place
+		place.Pos = place.Pos.Add(gclip.Min.Sub(r.Min))
+		d.imageOps = append(d.imageOps, imageOp{
+			z:        z,
+			path:     state.glyphPath.parent,
+			off:      off,
+			clip:     gclip,
+			material: state.materialFor(d.cache, rect, state.t, gclip),
+			clipType: clipTypeGlyph,
+			place:    place,
+			mask:     g.mask,
+		})
+	}
+}
+
+// demoteGlyphs replaces the glyph clip of state, if any,
+// with its path.
+func (d *drawOps) demoteGlyphs(state *drawState) {
+	if state.glyphs == nil {
+		return
+	}
+	if p := state.glyphPath; !p.path {
+		p.path = true
+		d.pathOps = append(d.pathOps, p)
+	}
+	state.glyphs = nil
+	state.glyphPath = nil
+}
+
+func decodeGlyphOp(data []byte, refs []interface{}) paint.GlyphOp {
+	bo := binary.LittleEndian
+	if opconst.OpType(data[0]) != opconst.TypeGlyph {
+		panic("invalid op")
+	}
+	return paint.GlyphOp{
+		Mask: refs[0].(*image.Alpha),
+		Origin: image.Point{
+			X: int(int32(bo.Uint32(data[1:]))),
+			Y: int(int32(bo.Uint32(data[5:]))),
+		},
+	}
+}
+
+func round(v float32) int {
+	return int(math.Floor(float64(v) + .5))
+}
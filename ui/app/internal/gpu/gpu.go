@@ -17,14 +17,17 @@ import (
 	"gioui.org/ui/f32"
 	"gioui.org/ui/internal/opconst"
 	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/internal/path"
 	"gioui.org/ui/paint"
 	"golang.org/x/image/draw"
 )
 
 type GPU struct {
 	drawing bool
-	summary string
+	stats   FrameStats
 	err     error
+	// Cache counters of the most recent frame.
+	texStats, pathStats CacheStats
 
 	pathCache *opCache
 	cache     *resourceCache
@@ -36,18 +39,36 @@ type GPU struct {
 	ack        chan struct{}
 	stop       chan struct{}
 	stopped    chan struct{}
-	ops        drawOps
+	// debug enables error checking and tracing
+	// of the backend calls.
+	debug bool
+	// trace requests the trace of the next frame.
+	trace  bool
+	ops    drawOps
+	damage damageTracker
+	glyphs *glyphAtlas
 }
 
 type frame struct {
 	collectStats bool
-	viewport     image.Point
-	ops          drawOps
+	// trace requests the trace of the backend calls
+	// of the frame.
+	trace    bool
+	viewport image.Point
+	ops      drawOps
+	// damage is the changed area of the frame.
+	damage []image.Rectangle
+	// collect is the time spent collecting ops.
+	collect time.Duration
+	// texStats are the texture cache counters before
+	// the ops were collected.
+	texStats CacheStats
 }
 
 type frameResult struct {
-	summary string
-	err     error
+	err                 error
+	stats               FrameStats
+	texStats, pathStats CacheStats
 }
 
 type renderer struct {
@@ -56,6 +77,21 @@ type renderer struct {
 	pather        *pather
 	packer        packer
 	intersections packer
+	// layers are the offscreen targets of the
+	// layerOps of a frame.
+	layers  fboSet
+	blurrer *blurrer
+	// effects are the offscreen targets of the
+	// effectOps of a frame.
+	effects fboSet
+	history damageHistory
+	// damaged is the area being redrawn, or the
+	// empty rectangle for a full redraw.
+	damaged image.Rectangle
+	// scissored tracks whether drawing is
+	// clipped to damaged.
+	scissored bool
+	glyphs    *glyphAtlas
 }
 
 type drawOps struct {
@@ -70,6 +106,24 @@ type drawOps struct {
 	zimageOps   []imageOp
 	pathOps     []*pathOp
 	pathOpCache []pathOp
+	// layers are the offscreen layers in the order
+	// they must be drawn: nested layers before the
+	// layers that contain them.
+	layers []*layerOp
+	// effects are the shadows and blurs of a frame.
+	effects []*effectOp
+	// blurred is set after the first backdrop blur. Later
+	// opaque images must not be drawn before the blur.
+	blurred bool
+	// pathScratch holds the vertices of transformed paths.
+	pathScratch ui.Ops
+	// pathHashes map path keys to the hashes
+	// of their vertices.
+	pathHashes     map[ops.Key]uint64
+	prevPathHashes map[ops.Key]uint64
+	glyphAtlas     *glyphAtlas
+	// glyphOps holds the GlyphOps of the frame.
+	glyphOps []glyphOp
 }
 
 type drawState struct {
@@ -78,12 +132,24 @@ type drawState struct {
 	cpath *pathOp
 	rect  bool
 	z     int
+	// layer is set inside an OpacityOp layer.
+	layer bool
 
 	// Current ImageOp image and rect, if any.
-	img     image.Image
-	imgRect image.Rectangle
+	img        image.Image
+	imgRect    image.Rectangle
+	imgVersion int
+	imgDirty   image.Rectangle
 	// Current ColorOp, if any.
 	color color.RGBA
+	// Current gradient, if any.
+	grad    ops.Gradient
+	hasGrad bool
+
+	// glyphs replace the clip path glyphPath
+	// if set.
+	glyphs    []glyphOp
+	glyphPath *pathOp
 }
 
 type pathOp struct {
@@ -94,8 +160,26 @@ type pathOp struct {
 	pathKey   ops.Key
 	path      bool
 	pathVerts []byte
-	parent    *pathOp
-	place     placement
+	// evenOdd is set for paths filled with
+	// the even-odd rule.
+	evenOdd bool
+	// hash identifies the clip path and its parents.
+	hash   uint64
+	parent *pathOp
+	place  placement
+}
+
+// layerOp is a group of operations drawn offscreen
+// and blended into its parent with an opacity.
+type layerOp struct {
+	// bounds is the area of the layer in
+	// window coordinates.
+	bounds   image.Rectangle
+	imageOps []imageOp
+	// fbo is the index of the layer target.
+	fbo int
+	// used is cleared for culled layers.
+	used bool
 }
 
 type imageOp struct {
@@ -106,6 +190,11 @@ type imageOp struct {
 	material material
 	clipType clipType
 	place    placement
+	// evenOdd is the fill rule of the cover
+	// of a clipTypePath.
+	evenOdd bool
+	// mask is the glyph of a clipTypeGlyph.
+	mask *image.Alpha
 }
 
 type material struct {
@@ -114,14 +203,26 @@ type material struct {
 	// For materialTypeColor.
 	color [4]float32
 	// For materialTypeTexture.
-	texture  *texture
-	uvScale  f32.Point
-	uvOffset f32.Point
+	texture *texture
+	// layer is the source of a materialTypeTexture
+	// that blends a layer, instead of texture.
+	layer   *layerOp
+	opacity float32
+	// effect is the source of a materialTexture
+	// that blends a shadow or a blur.
+	effect *effectOp
+	// uvTrans transforms quad coordinates to texture
+	// coordinates or gradient space.
+	uvTrans f32.Affine2D
+	// For materialLinearGradient and materialRadialGradient.
+	stopColors  [opconst.MaxGradientStops][4]float32
+	stopOffsets [opconst.MaxGradientStops]float32
 }
 
 // clipOp is the shadow of draw.ClipOp.
 type clipOp struct {
-	bounds f32.Rectangle
+	bounds  f32.Rectangle
+	evenOdd bool
 }
 
 func (op *clipOp) decode(data []byte) {
@@ -140,7 +241,8 @@ func (op *clipOp) decode(data []byte) {
 		},
 	}
 	*op = clipOp{
-		bounds: r,
+		bounds:  r,
+		evenOdd: data[17] == byte(paint.EvenOdd),
 	}
 }
 
@@ -159,9 +261,21 @@ func decodeImageOp(data []byte, refs []interface{}) paint.ImageOp {
 			Y: int(int32(bo.Uint32(data[13:]))),
 		},
 	}
+	dirty := image.Rectangle{
+		Min: image.Point{
+			X: int(int32(bo.Uint32(data[21:]))),
+			Y: int(int32(bo.Uint32(data[25:]))),
+		},
+		Max: image.Point{
+			X: int(int32(bo.Uint32(data[29:]))),
+			Y: int(int32(bo.Uint32(data[33:]))),
+		},
+	}
 	return paint.ImageOp{
-		Src:  refs[0].(image.Image),
-		Rect: sr,
+		Src:     refs[0].(image.Image),
+		Rect:    sr,
+		Version: int(int32(bo.Uint32(data[17:]))),
+		Dirty:   dirty,
 	}
 }
 
@@ -208,17 +322,37 @@ type resource interface {
 type texture struct {
 	src image.Image
 	id  gl.Texture
+	// version is the most recent version of src.
+	version int
+	// dirty is the area of src to upload.
+	dirty  image.Rectangle
+	format texFormat
 }
 
+// texFormat describes the pixels of a texture.
+type texFormat uint8
+
+const (
+	// texRGBA is premultiplied sRGB.
+	texRGBA texFormat = iota
+	// texNRGBA is non-premultiplied sRGB.
+	texNRGBA
+	// texGray is single channel sRGB.
+	texGray
+)
+
 type blitter struct {
 	ctx      *context
 	viewport image.Point
-	prog     [2]gl.Program
-	vars     [2]struct {
-		z                   gl.Uniform
-		uScale, uOffset     gl.Uniform
-		uUVScale, uUVOffset gl.Uniform
-		uColor              gl.Uniform
+	prog     [numMaterials]gl.Program
+	vars     [numMaterials]struct {
+		z                              gl.Uniform
+		uScale, uOffset                gl.Uniform
+		uUVTransformR1, uUVTransformR2 gl.Uniform
+		uColor                         gl.Uniform
+		uOpacity                       gl.Uniform
+		uFormat                        gl.Uniform
+		gradientUniforms
 	}
 	quadVerts gl.Buffer
 }
@@ -229,20 +363,51 @@ const (
 	clipTypeNone clipType = iota
 	clipTypePath
 	clipTypeIntersection
+	// clipTypeGlyph clips to a glyph in
+	// the glyph atlas.
+	clipTypeGlyph
 )
 
 const (
 	materialTexture materialType = iota
 	materialColor
+	materialLinearGradient
+	materialRadialGradient
+	numMaterials
 )
 
+// Assume 16-bit depth buffer.
+const zdepth = 1 << 16
+
+// gradientUniforms are the color stop uniforms
+// of the gradient programs.
+type gradientUniforms struct {
+	uStopColors  [opconst.MaxGradientStops]gl.Uniform
+	uStopOffsets [opconst.MaxGradientStops]gl.Uniform
+}
+
 var (
 	blitAttribs           = []string{"pos", "uv"}
 	attribPos   gl.Attrib = 0
 	attribUV    gl.Attrib = 1
 )
 
-func NewGPU(ctx gl.Context) (*GPU, error) {
+// NewGPU creates a GPU renderer for ctx with resource caches
+// limited by budget. If debug is set, every GL call is checked
+// for errors.
+func NewGPU(ctx gl.Context, budget CacheBudget, debug bool) (*GPU, error) {
+	return newGPU(ctx, glBackend{ctx.Functions()}, budget, debug)
+}
+
+// newGPU is like NewGPU but draws with the backend b. The
+// context ctx is used for presenting frames.
+func newGPU(ctx gl.Context, b backend, budget CacheBudget, debug bool) (*GPU, error) {
+	if budget.TextureBytes == 0 {
+		budget.TextureBytes = defaultTextureBudget
+	}
+	if budget.VertexBytes == 0 {
+		budget.VertexBytes = defaultVertexBudget
+	}
 	g := &GPU{
 		frames:     make(chan frame),
 		results:    make(chan frameResult),
@@ -251,16 +416,18 @@ func NewGPU(ctx gl.Context) (*GPU, error) {
 		ack:        make(chan struct{}),
 		stop:       make(chan struct{}),
 		stopped:    make(chan struct{}),
-		pathCache:  newOpCache(),
-		cache:      newResourceCache(),
+		pathCache:  newOpCache(budget.VertexBytes),
+		cache:      newResourceCache(budget.TextureBytes),
+		glyphs:     newGlyphAtlas(),
+		debug:      debug,
 	}
-	if err := g.renderLoop(ctx); err != nil {
+	if err := g.renderLoop(ctx, b); err != nil {
 		return nil, err
 	}
 	return g, nil
 }
 
-func (g *GPU) renderLoop(glctx gl.Context) error {
+func (g *GPU) renderLoop(glctx gl.Context, b backend) error {
 	// GL Operations must happen on a single OS thread, so
 	// pass initialization result through a channel.
 	initErr := make(chan error)
@@ -274,17 +441,26 @@ func (g *GPU) renderLoop(glctx gl.Context) error {
 			initErr <- err
 			return
 		}
-		ctx, err := newContext(glctx)
+		var dbg *debugBackend
+		if g.debug {
+			dbg = newDebugBackend(b)
+			b = dbg
+		}
+		ctx, err := newContext(b)
 		if err != nil {
 			initErr <- err
 			return
 		}
 		defer g.cache.release(ctx)
 		defer g.pathCache.release(ctx)
+		defer g.glyphs.release(ctx)
 		r := newRenderer(ctx)
+		r.glyphs = g.glyphs
 		defer r.release()
 		var timers *timers
-		var zopsTimer, stencilTimer, coverTimer, cleanupTimer *timer
+		var blitTimer, stencilTimer, coverTimer, cleanupTimer *timer
+		// gpuTime is the most recent GPU time measured.
+		var gpuTime time.Duration
 		initErr <- nil
 	loop:
 		for {
@@ -293,67 +469,141 @@ func (g *GPU) renderLoop(glctx gl.Context) error {
 				g.refreshErr <- glctx.MakeCurrent()
 			case frame := <-g.frames:
 				glctx.Lock()
+				dbg.beginFrame(frame.trace)
 				if frame.collectStats && timers == nil && ctx.caps.EXT_disjoint_timer_query {
 					timers = newTimers(ctx)
-					zopsTimer = timers.newTimer()
+					blitTimer = timers.newTimer()
 					stencilTimer = timers.newTimer()
 					coverTimer = timers.newTimer()
 					cleanupTimer = timers.newTimer()
 					defer timers.release()
 				}
+				// Textures are looked up while collecting the ops.
+				texStats, pathStats := frame.texStats, g.pathCache.lru.stats
 				ops := frame.ops
 				// Upload path data to GPU before ack'ing the frame data for re-use.
 				for _, p := range ops.pathOps {
 					if _, exists := g.pathCache.get(p.pathKey); !exists {
 						data := buildPath(r.ctx, p.pathVerts)
-						g.pathCache.put(p.pathKey, data)
+						g.pathCache.put(p.pathKey, data, len(p.pathVerts))
 					}
 					p.pathVerts = nil
 				}
+				// The op lists are not reset before the ack.
+				g.pathCache.dropStale(ctx)
+				g.glyphs.upload(ctx)
+				r.uploadTextures(ops.imageOps)
 				g.ack <- struct{}{}
+				if len(frame.damage) == 0 {
+					// Nothing changed.
+					var err error
+					if pp, ok := glctx.(gl.PartialPresenter); ok {
+						err = pp.PresentDamage(nil)
+					}
+					g.cache.frame(ctx)
+					g.pathCache.frame(ctx)
+					stats := g.frameStats(ctx, texStats, pathStats)
+					stats.Collect = frame.collect
+					stats.GPU = gpuTime
+					dbg.endFrame()
+					glctx.Unlock()
+					g.results <- frameResult{
+						err:       err,
+						stats:     stats,
+						texStats:  g.cache.lru.stats,
+						pathStats: g.pathCache.lru.stats,
+					}
+					continue
+				}
+				damage := frame.damage
+				age := 0
+				pp, partial := glctx.(gl.PartialPresenter)
+				if partial {
+					age = pp.BufferAge()
+				}
+				reg := r.history.region(damage, age, frame.viewport)
+				reg, damage = expandDamage(reg, damage, ops.effects, frame.viewport)
+				r.damaged = image.Rectangle{}
+				if reg != (image.Rectangle{Max: frame.viewport}) {
+					r.damaged = reg
+					ops.cull(reg)
+					ctx.Scissor(int32(reg.Min.X), int32(frame.viewport.Y-reg.Max.Y), int32(reg.Dx()), int32(reg.Dy()))
+				}
 				r.blitter.viewport = frame.viewport
 				r.pather.viewport = frame.viewport
 				for _, img := range ops.imageOps {
 					expandPathOp(img.path, img.clip)
 				}
+				for _, l := range ops.layers {
+					for _, img := range l.imageOps {
+						expandPathOp(img.path, img.clip)
+					}
+				}
 				if frame.collectStats {
-					zopsTimer.begin()
+					blitTimer.begin()
 				}
+				blitStart := time.Now()
+				r.scissor(true)
 				ctx.DepthFunc(gl.GREATER)
 				ctx.ClearColor(ops.clearColor[0], ops.clearColor[1], ops.clearColor[2], 1.0)
 				ctx.ClearDepthf(0.0)
 				ctx.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 				ctx.Viewport(0, 0, frame.viewport.X, frame.viewport.Y)
 				r.drawZOps(ops.zimageOps)
-				zopsTimer.end()
+				r.scissor(false)
+				blitTimer.end()
+				stencilStart := time.Now()
 				stencilTimer.begin()
 				ctx.Enable(gl.BLEND)
 				r.packStencils(&ops.pathOps)
 				r.stencilClips(g.pathCache, ops.pathOps)
+				r.intersections.clear()
 				r.packIntersections(ops.imageOps)
-				r.intersect(ops.imageOps)
+				for _, l := range ops.layers {
+					r.packIntersections(l.imageOps)
+				}
+				r.intersect(ops.imageOps, ops.layers)
 				stencilTimer.end()
+				coverStart := time.Now()
 				coverTimer.begin()
+				r.prepareEffects(ops.effects)
+				r.drawLayers(ops.layers)
 				ctx.Viewport(0, 0, frame.viewport.X, frame.viewport.Y)
-				r.drawOps(ops.imageOps)
+				r.scissor(true)
+				r.drawOps(ops.imageOps, image.Rectangle{Max: frame.viewport}, r.pather.stenciler.defFBO)
+				r.scissor(false)
 				ctx.Disable(gl.BLEND)
+				r.layers.invalidate(ctx)
+				r.effects.invalidate(ctx)
 				r.pather.stenciler.invalidateFBO()
 				coverTimer.end()
-				err := glctx.Present()
+				coverEnd := time.Now()
+				var err error
+				if partial {
+					err = pp.PresentDamage(damage)
+				} else {
+					err = glctx.Present()
+				}
+				r.history.add(damage)
 				cleanupTimer.begin()
 				g.cache.frame(ctx)
 				g.pathCache.frame(ctx)
 				cleanupTimer.end()
-				var res frameResult
 				if frame.collectStats && timers.ready() {
-					zt, st, covt, cleant := zopsTimer.Elapsed, stencilTimer.Elapsed, coverTimer.Elapsed, cleanupTimer.Elapsed
-					ft := zt + st + covt + cleant
-					q := 100 * time.Microsecond
-					zt, st, covt = zt.Round(q), st.Round(q), covt.Round(q)
-					ft = ft.Round(q)
-					res.summary = fmt.Sprintf("f:%7s zt:%7s st:%7s cov:%7s", ft, zt, st, covt)
+					gpuTime = blitTimer.Elapsed + stencilTimer.Elapsed + coverTimer.Elapsed + cleanupTimer.Elapsed
 				}
+				var res frameResult
+				res.stats = g.frameStats(ctx, texStats, pathStats)
+				res.stats.Collect = frame.collect
+				res.stats.Blit = stencilStart.Sub(blitStart)
+				res.stats.Stencil = coverStart.Sub(stencilStart)
+				res.stats.Cover = coverEnd.Sub(coverStart)
+				res.stats.GPU = gpuTime
+				res.stats.Paths = len(ops.pathOps)
 				res.err = err
+				res.texStats = g.cache.lru.stats
+				res.pathStats = g.pathCache.lru.stats
+				dbg.endFrame()
 				glctx.Unlock()
 				g.results <- res
 			case <-g.stop:
@@ -376,16 +626,30 @@ func (g *GPU) Flush() error {
 	if g.drawing {
 		st := <-g.results
 		g.setErr(st.err)
-		if st.summary != "" {
-			g.summary = st.summary
-		}
+		g.stats = st.stats
+		g.texStats, g.pathStats = st.texStats, st.pathStats
 		g.drawing = false
 	}
 	return g.err
 }
 
-func (g *GPU) Timings() string {
-	return g.summary
+// TraceFrame requests that the calls of the next frame are
+// logged. TraceFrame has no effect unless the GPU was created
+// in debug mode.
+func (g *GPU) TraceFrame() {
+	g.trace = true
+}
+
+// Stats returns the statistics of the most recently
+// completed frame.
+func (g *GPU) Stats() FrameStats {
+	return g.stats
+}
+
+// CacheStats returns the counters of the texture and path
+// caches after the most recently completed frame.
+func (g *GPU) CacheStats() (textures, paths CacheStats) {
+	return g.texStats, g.pathStats
 }
 
 func (g *GPU) Refresh() {
@@ -394,6 +658,8 @@ func (g *GPU) Refresh() {
 	}
 	// Make sure any pending frame is complete.
 	g.Flush()
+	// The window contents may be lost.
+	g.damage.invalidate()
 	g.refresh <- struct{}{}
 	g.setErr(<-g.refreshErr)
 }
@@ -403,9 +669,19 @@ func (g *GPU) Draw(profile bool, viewport image.Point, root *ui.Ops) {
 		return
 	}
 	g.Flush()
+	start := time.Now()
+	texStats := g.cache.lru.stats
+	g.ops.glyphAtlas = g.glyphs
 	g.ops.reset(g.cache, viewport)
 	g.ops.collect(g.cache, root, viewport)
-	g.frames <- frame{profile, viewport, g.ops}
+	if g.trace {
+		// Redraw everything for a complete trace.
+		g.damage.invalidate()
+	}
+	damage := g.damage.frame(&g.ops)
+	collect := time.Since(start)
+	g.frames <- frame{profile, g.trace, viewport, g.ops, damage, collect, texStats}
+	g.trace = false
 	<-g.ack
 	g.drawing = true
 }
@@ -416,16 +692,50 @@ func (g *GPU) setErr(err error) {
 	}
 }
 
+// texHandle returns the texture object of t, after
+// uploading its new or modified pixels.
 func (r *renderer) texHandle(t *texture) gl.Texture {
-	if t.id != (gl.Texture{}) {
-		return t.id
+	switch {
+	case t.id == (gl.Texture{}):
+		t.id = createTexture(r.ctx)
+		t.format = textureFormat(t.src)
+		r.uploadTexture(t, t.src.Bounds(), true)
+	case !t.dirty.Empty():
+		r.ctx.BindTexture(gl.TEXTURE_2D, t.id)
+		r.uploadTexture(t, t.dirty, false)
 	}
-	t.id = createTexture(r.ctx)
-	r.ctx.BindTexture(gl.TEXTURE_2D, t.id)
-	r.uploadTexture(t.src)
+	t.dirty = image.Rectangle{}
 	return t.id
 }
 
+// uploadTextures uploads the textures of ops, so the images
+// are not accessed after the frame is acknowledged.
+func (r *renderer) uploadTextures(ops []imageOp) {
+	for _, img := range ops {
+		m := img.material
+		if m.texture != nil {
+			r.texHandle(m.texture)
+		}
+		if m.layer != nil {
+			r.uploadTextures(m.layer.imageOps)
+		}
+	}
+}
+
+// update records the version of the contents of t, where
+// dirty is the area modified since the previous version.
+func (t *texture) update(version int, dirty image.Rectangle) {
+	if version == t.version {
+		return
+	}
+	b := t.src.Bounds()
+	if dirty.Empty() || version != t.version+1 {
+		dirty = b
+	}
+	t.dirty = t.dirty.Union(dirty.Intersect(b))
+	t.version = version
+}
+
 func (t *texture) release(ctx *context) {
 	if t.id != (gl.Texture{}) {
 		ctx.DeleteTexture(t.id)
@@ -437,13 +747,31 @@ func newRenderer(ctx *context) *renderer {
 		ctx:     ctx,
 		blitter: newBlitter(ctx),
 		pather:  newPather(ctx),
+		blurrer: newBlurrer(ctx),
 	}
 	r.packer.maxDim = ctx.GetInteger(gl.MAX_TEXTURE_SIZE)
 	r.intersections.maxDim = r.packer.maxDim
 	return r
 }
 
+// scissor enables or disables clipping to the damaged
+// area of a partial redraw.
+func (r *renderer) scissor(enable bool) {
+	if r.damaged.Empty() || enable == r.scissored {
+		return
+	}
+	r.scissored = enable
+	if enable {
+		r.ctx.Enable(gl.SCISSOR_TEST)
+	} else {
+		r.ctx.Disable(gl.SCISSOR_TEST)
+	}
+}
+
 func (r *renderer) release() {
+	r.layers.delete(r.ctx, 0)
+	r.effects.delete(r.ctx, 0)
+	r.blurrer.release()
 	r.pather.release()
 	r.blitter.release()
 }
@@ -472,16 +800,22 @@ func newBlitter(ctx *context) *blitter {
 		ctx.UseProgram(prog)
 		switch materialType(i) {
 		case materialTexture:
-			uTex := gl.GetUniformLocation(ctx.Functions, prog, "tex")
+			uTex := getUniformLocation(ctx, prog, "tex")
 			ctx.Uniform1i(uTex, 0)
-			b.vars[i].uUVScale = gl.GetUniformLocation(ctx.Functions, prog, "uvScale")
-			b.vars[i].uUVOffset = gl.GetUniformLocation(ctx.Functions, prog, "uvOffset")
+			b.vars[i].uUVTransformR1 = getUniformLocation(ctx, prog, "uvTransformR1")
+			b.vars[i].uUVTransformR2 = getUniformLocation(ctx, prog, "uvTransformR2")
+			b.vars[i].uOpacity = getUniformLocation(ctx, prog, "opacity")
+			b.vars[i].uFormat = getUniformLocation(ctx, prog, "format")
 		case materialColor:
-			b.vars[i].uColor = gl.GetUniformLocation(ctx.Functions, prog, "color")
+			b.vars[i].uColor = getUniformLocation(ctx, prog, "color")
+		case materialLinearGradient, materialRadialGradient:
+			b.vars[i].uUVTransformR1 = getUniformLocation(ctx, prog, "uvTransformR1")
+			b.vars[i].uUVTransformR2 = getUniformLocation(ctx, prog, "uvTransformR2")
+			b.vars[i].gradientUniforms.init(ctx, prog)
 		}
-		b.vars[i].z = gl.GetUniformLocation(ctx.Functions, prog, "z")
-		b.vars[i].uScale = gl.GetUniformLocation(ctx.Functions, prog, "scale")
-		b.vars[i].uOffset = gl.GetUniformLocation(ctx.Functions, prog, "offset")
+		b.vars[i].z = getUniformLocation(ctx, prog, "z")
+		b.vars[i].uScale = getUniformLocation(ctx, prog, "scale")
+		b.vars[i].uOffset = getUniformLocation(ctx, prog, "offset")
 	}
 	return b
 }
@@ -493,35 +827,74 @@ func (b *blitter) release() {
 	}
 }
 
-func createColorPrograms(ctx *context, vsSrc, fsSrc string) ([2]gl.Program, error) {
-	var prog [2]gl.Program
-	frep := strings.NewReplacer(
-		"HEADER", `
+func createColorPrograms(ctx *context, vsSrc, fsSrc string) ([numMaterials]gl.Program, error) {
+	var prog [numMaterials]gl.Program
+	reps := [numMaterials]*strings.Replacer{
+		materialTexture: strings.NewReplacer(
+			"HEADER", `
 uniform sampler2D tex;
-`,
-		"GET_COLOR", `texture2D(tex, vUV)`,
-	)
-	fsSrcTex := frep.Replace(fsSrc)
-	var err error
-	prog[materialTexture], err = gl.CreateProgram(ctx.Functions, vsSrc, fsSrcTex, blitAttribs)
-	if err != nil {
-		return prog, err
+uniform float opacity;
+// format is 1 for non-premultiplied textures
+// and 2 for gray textures.
+uniform int format;
+
+vec4 texColor(vec2 uv) {
+	vec4 c = texture2D(tex, uv);
+	if (format == 1) {
+		c.rgb *= c.a;
+	} else if (format == 2) {
+		// Gray textures are not sRGB textures.
+		float g = c.r;
+		g = g <= 0.04045 ? g/12.92 : pow((g + 0.055)/1.055, 2.4);
+		c = vec4(g, g, g, 1.0);
 	}
-	frep = strings.NewReplacer(
-		"HEADER", `
+	return c;
+}
+`,
+			"GET_COLOR", `texColor(vUV)*opacity`,
+		),
+		materialColor: strings.NewReplacer(
+			"HEADER", `
 uniform vec4 color;
 `,
-		"GET_COLOR", `color`,
-	)
-	fsSrcCol := frep.Replace(fsSrc)
-	prog[materialColor], err = gl.CreateProgram(ctx.Functions, vsSrc, fsSrcCol, blitAttribs)
-	if err != nil {
-		ctx.DeleteProgram(prog[materialTexture])
-		return prog, err
+			"GET_COLOR", `color`,
+		),
+		materialLinearGradient: strings.NewReplacer(
+			"HEADER", gradientHeader,
+			"GET_COLOR", `gradient(vUV.x)`,
+		),
+		materialRadialGradient: strings.NewReplacer(
+			"HEADER", gradientHeader,
+			"GET_COLOR", `gradient(length(vUV))`,
+		),
+	}
+	for i, frep := range reps {
+		var err error
+		prog[i], err = ctx.CreateProgram(vsSrc, frep.Replace(fsSrc), blitAttribs)
+		if err != nil {
+			for _, p := range prog[:i] {
+				ctx.DeleteProgram(p)
+			}
+			return prog, err
+		}
 	}
 	return prog, nil
 }
 
+func (u *gradientUniforms) init(ctx *context, prog gl.Program) {
+	for i := range u.uStopColors {
+		u.uStopColors[i] = getUniformLocation(ctx, prog, fmt.Sprintf("stopColors[%d]", i))
+		u.uStopOffsets[i] = getUniformLocation(ctx, prog, fmt.Sprintf("stopOffsets[%d]", i))
+	}
+}
+
+func (u *gradientUniforms) set(ctx *context, m *material) {
+	for i, c := range m.stopColors {
+		ctx.Uniform4f(u.uStopColors[i], c[0], c[1], c[2], c[3])
+		ctx.Uniform1f(u.uStopOffsets[i], m.stopOffsets[i])
+	}
+}
+
 func (r *renderer) stencilClips(pathCache *opCache, ops []*pathOp) {
 	if len(r.packer.sizes) == 0 {
 		return
@@ -541,7 +914,9 @@ func (r *renderer) stencilClips(pathCache *opCache, ops []*pathOp) {
 	r.pather.end()
 }
 
-func (r *renderer) intersect(ops []imageOp) {
+// intersect the clip paths of ops and the operations of layers,
+// in the order they were packed.
+func (r *renderer) intersect(ops []imageOp, layers []*layerOp) {
 	if len(r.intersections.sizes) == 0 {
 		return
 	}
@@ -552,18 +927,24 @@ func (r *renderer) intersect(ops []imageOp) {
 	r.ctx.VertexAttribPointer(attribUV, 2, gl.FLOAT, false, 4*4, 4*2)
 	r.ctx.EnableVertexAttribArray(attribPos)
 	r.ctx.EnableVertexAttribArray(attribUV)
-	for _, img := range ops {
-		if img.clipType != clipTypeIntersection {
-			continue
-		}
-		if fbo != img.place.Idx {
-			fbo = img.place.Idx
-			f := r.pather.stenciler.intersections.fbos[fbo]
-			bindFramebuffer(r.ctx, f.fbo)
-			r.ctx.Clear(gl.COLOR_BUFFER_BIT)
+	intersect := func(ops []imageOp) {
+		for _, img := range ops {
+			if img.clipType != clipTypeIntersection {
+				continue
+			}
+			if fbo != img.place.Idx {
+				fbo = img.place.Idx
+				f := r.pather.stenciler.intersections.fbos[fbo]
+				bindFramebuffer(r.ctx, f.fbo)
+				r.ctx.Clear(gl.COLOR_BUFFER_BIT)
+			}
+			r.ctx.Viewport(img.place.Pos.X, img.place.Pos.Y, img.clip.Dx(), img.clip.Dy())
+			r.intersectPath(img.path, img.clip)
 		}
-		r.ctx.Viewport(img.place.Pos.X, img.place.Pos.Y, img.clip.Dx(), img.clip.Dy())
-		r.intersectPath(img.path, img.clip)
+	}
+	intersect(ops)
+	for _, l := range layers {
+		intersect(l.imageOps)
 	}
 	r.ctx.DisableVertexAttribArray(attribPos)
 	r.ctx.DisableVertexAttribArray(attribUV)
@@ -587,11 +968,11 @@ func (r *renderer) intersectPath(p *pathOp, clip image.Rectangle) {
 	coverScale, coverOff := texSpaceTransform(uv, fbo.size)
 	r.ctx.Uniform2f(r.pather.stenciler.uIntersectUVScale, coverScale.X, coverScale.Y)
 	r.ctx.Uniform2f(r.pather.stenciler.uIntersectUVOffset, coverOff.X, coverOff.Y)
+	r.ctx.Uniform1i(r.pather.stenciler.uIntersectEvenOdd, boolInt(p.evenOdd))
 	r.ctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
 }
 
 func (r *renderer) packIntersections(ops []imageOp) {
-	r.intersections.clear()
 	for i, img := range ops {
 		var npaths int
 		var onePath *pathOp
@@ -608,6 +989,7 @@ func (r *renderer) packIntersections(ops []imageOp) {
 			place.Pos = place.Pos.Sub(onePath.clip.Min).Add(img.clip.Min)
 			ops[i].place = place
 			ops[i].clipType = clipTypePath
+			ops[i].evenOdd = onePath.evenOdd
 		default:
 			sz := image.Point{X: img.clip.Dx(), Y: img.clip.Dy()}
 			place, ok := r.intersections.add(sz)
@@ -676,6 +1058,11 @@ func (d *drawOps) reset(cache *resourceCache, viewport image.Point) {
 	d.zimageOps = d.zimageOps[:0]
 	d.pathOps = d.pathOps[:0]
 	d.pathOpCache = d.pathOpCache[:0]
+	d.layers = d.layers[:0]
+	d.effects = d.effects[:0]
+	d.blurred = false
+	d.pathScratch.Reset()
+	d.glyphOps = d.glyphOps[:0]
 }
 
 func (d *drawOps) collect(cache *resourceCache, root *ui.Ops, viewport image.Point) {
@@ -683,6 +1070,15 @@ func (d *drawOps) collect(cache *resourceCache, root *ui.Ops, viewport image.Poi
 	clip := f32.Rectangle{
 		Max: f32.Point{X: float32(viewport.X), Y: float32(viewport.Y)},
 	}
+	// Keep the path hashes of the previous frame.
+	d.pathHashes, d.prevPathHashes = d.prevPathHashes, d.pathHashes
+	if d.pathHashes == nil {
+		d.pathHashes = make(map[ops.Key]uint64)
+	}
+	for k := range d.pathHashes {
+		delete(d.pathHashes, k)
+	}
+	d.glyphAtlas.frame()
 	d.reader.Reset(root)
 	state := drawState{
 		clip:  clip,
@@ -700,6 +1096,9 @@ func (d *drawOps) newPathOp() *pathOp {
 func (d *drawOps) collectOps(r *ops.Reader, state drawState) int {
 	var aux []byte
 	var auxKey ops.Key
+	// glyphStart is the index of the GlyphOps
+	// of the next clip, or -1.
+	glyphStart := -1
 loop:
 	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
 		switch opconst.OpType(encOp.Data[0]) {
@@ -712,69 +1111,153 @@ loop:
 		case opconst.TypeClip:
 			var op clipOp
 			op.decode(encOp.Data)
-			off := state.t.Transform(f32.Point{})
-			state.clip = state.clip.Intersect(op.bounds.Add(off))
-			if state.clip.Empty() {
-				continue
-			}
-			npath := d.newPathOp()
-			*npath = pathOp{
-				parent: state.cpath,
-				off:    off,
+			bounds := op.bounds
+			trans, off := state.t.Affine2D().Split()
+			switch {
+			case len(aux) > 0 && !trans.IsOffset():
+				// Transform the path on the CPU; the stencil
+				// programs support only offsets.
+				aux, bounds = d.transformPath(aux, trans)
+				auxKey = auxKey.SetTransform(trans)
+			case len(aux) == 0 && !trans.IsAxisAligned():
+				// Rotated or sheared rectangles need a path.
+				aux, bounds = d.rectPath(bounds, trans)
+				auxKey = encOp.Key.SetTransform(trans)
+			case len(aux) == 0:
+				bounds = transformBounds(trans, bounds)
 			}
-			state.cpath = npath
-			if len(aux) > 0 {
-				state.rect = false
-				state.cpath.pathKey = auxKey
-				state.cpath.path = true
-				state.cpath.pathVerts = aux
-				d.pathOps = append(d.pathOps, state.cpath)
+			// Glyph masks are drawn at whole pixels and
+			// can't be intersected with paths.
+			glyphs := glyphStart != -1 && len(aux) > 0 && trans.IsOffset() && state.rect
+			npaths := len(d.pathOps)
+			d.addClip(&state, bounds, off, aux, auxKey, op.evenOdd)
+			if glyphs && len(d.pathOps) > npaths {
+				// Clip to the glyphs instead of the path.
+				d.pathOps = d.pathOps[:npaths]
+				state.cpath.path = false
+				state.glyphPath = state.cpath
+				state.glyphs = d.glyphOps[glyphStart:]
 			}
 			aux = nil
 			auxKey = ops.Key{}
+			glyphStart = -1
+		case opconst.TypeGlyph:
+			op := decodeGlyphOp(encOp.Data, encOp.Refs)
+			if glyphStart == -1 {
+				glyphStart = len(d.glyphOps)
+			}
+			d.glyphOps = append(d.glyphOps, glyphOp{mask: op.Mask, origin: op.Origin})
 		case opconst.TypeColor:
 			op := decodeColorOp(encOp.Data)
 			state.img = nil
+			state.hasGrad = false
 			state.color = op.Color
+		case opconst.TypeLinearGradient, opconst.TypeRadialGradient:
+			state.img = nil
+			state.hasGrad = true
+			state.grad = ops.DecodeGradientOp(encOp.Data)
 		case opconst.TypeImage:
 			op := decodeImageOp(encOp.Data, encOp.Refs)
+			state.hasGrad = false
 			state.img = op.Src
 			state.imgRect = op.Rect
+			state.imgVersion = op.Version
+			state.imgDirty = op.Dirty
 		case opconst.TypePaint:
 			op := decodePaintOp(encOp.Data)
-			off := state.t.Transform(f32.Point{})
-			clip := state.clip.Intersect(op.Rect.Add(off))
+			trans, off := state.t.Affine2D().Split()
+			pstate := state
+			if !trans.IsAxisAligned() {
+				// Clip to the transformed rectangle.
+				aux, bounds := d.rectPath(op.Rect, trans)
+				d.addClip(&pstate, bounds, off, aux, encOp.Key.SetTransform(trans), false)
+			}
+			dst := transformBounds(trans, op.Rect).Add(off)
+			clip := pstate.clip.Intersect(dst)
 			if clip.Empty() {
 				continue
 			}
 			bounds := boundRectF(clip)
-			mat := state.materialFor(d.cache, op.Rect, off, bounds)
-			if bounds.Min == (image.Point{}) && bounds.Max == d.viewport && state.rect && mat.opaque && mat.material == materialColor {
+			if pstate.glyphs != nil {
+				d.addGlyphs(&pstate, op.Rect, bounds)
+				state.z = pstate.z
+				continue
+			}
+			mat := state.materialFor(d.cache, op.Rect, state.t, bounds)
+			if bounds.Min == (image.Point{}) && bounds.Max == d.viewport && pstate.rect && mat.opaque && mat.material == materialColor && !state.layer {
 				// The image is a uniform opaque color and takes up the whole screen.
 				// Scrap images up to and including this image and set clear color.
 				d.zimageOps = d.zimageOps[:0]
 				d.imageOps = d.imageOps[:0]
+				d.blurred = false
 				state.z = 0
 				copy(d.clearColor[:], mat.color[:3])
 				continue
 			}
 			state.z++
-			// Assume 16-bit depth buffer.
-			const zdepth = 1 << 16
 			// Convert z to window-space, assuming depth range [0;1].
 			zf := float32(state.z)*2/zdepth - 1.0
 			img := imageOp{
 				z:        zf,
-				path:     state.cpath,
+				path:     pstate.cpath,
 				off:      off,
 				clip:     bounds,
 				material: mat,
 			}
-			if state.rect && img.material.opaque {
+			// Layers are drawn without a depth buffer and in order.
+			if pstate.rect && img.material.opaque && !state.layer && !d.blurred {
 				d.zimageOps = append(d.zimageOps, img)
 			} else {
 				d.imageOps = append(d.imageOps, img)
 			}
+		case opconst.TypeShadow:
+			s := ops.DecodeShadowOp(encOp.Data)
+			t := state.t.Affine2D()
+			// Scale the shadow shape and blur with the transformation.
+			scale := transformScale(t)
+			shape := transformBounds(t, s.Rect)
+			if shape.Empty() || s.Color.A == 0 {
+				continue
+			}
+			sigma := s.Sigma * scale
+			ext := float32(blurExtent(sigma))
+			area := f32.Rectangle{
+				Min: shape.Min.Sub(f32.Point{X: ext, Y: ext}),
+				Max: shape.Max.Add(f32.Point{X: ext, Y: ext}),
+			}
+			state.z = d.addEffect(state, area, &effectOp{
+				shadow: true,
+				sigma:  sigma,
+				rect:   shape,
+				radius: s.Radius * scale,
+				color:  gamma(s.Color.RGBA()),
+			})
+		case opconst.TypeBlur:
+			rect, sigma := ops.DecodeBlurOp(encOp.Data)
+			trans, off := state.t.Affine2D().Split()
+			pstate := state
+			if !trans.IsAxisAligned() {
+				// Clip to the transformed rectangle.
+				aux, bounds := d.rectPath(rect, trans)
+				d.addClip(&pstate, bounds, off, aux, encOp.Key.SetTransform(trans), false)
+			}
+			dst := transformBounds(trans, rect).Add(off)
+			z := d.addEffect(pstate, dst, &effectOp{
+				sigma: sigma * transformScale(trans),
+			})
+			if z != state.z {
+				state.z = z
+				d.blurred = true
+			}
+		case opconst.TypeLayer:
+			switch o := ops.DecodeOpacityOp(encOp.Data); {
+			case o == 0:
+				// Skip the invisible operations.
+				state.clip = f32.Rectangle{}
+			case o < 1:
+				state.z = d.collectLayer(r, state, o)
+				break loop
+			}
 		case opconst.TypePush:
 			state.z = d.collectOps(r, state)
 		case opconst.TypePop:
@@ -784,6 +1267,193 @@ loop:
 	return state.z
 }
 
+// collectLayer collects the remaining operations of the current
+// stack level into a new layer and adds an operation for blending
+// the layer with the given opacity.
+func (d *drawOps) collectLayer(r *ops.Reader, state drawState, opacity float32) int {
+	bounds := boundRectF(state.clip).Intersect(image.Rectangle{Max: d.viewport})
+	l := &layerOp{bounds: bounds}
+	parentOps := d.imageOps
+	d.imageOps = nil
+	lstate := state
+	lstate.layer = true
+	state.z = d.collectOps(r, lstate)
+	l.imageOps = d.imageOps
+	d.imageOps = parentOps
+	if bounds.Empty() || len(l.imageOps) == 0 {
+		return state.z
+	}
+	d.layers = append(d.layers, l)
+	state.z++
+	// The layer operations are already clipped.
+	d.imageOps = append(d.imageOps, imageOp{
+		z:    float32(state.z)*2/zdepth - 1.0,
+		clip: bounds,
+		material: material{
+			material: materialTexture,
+			layer:    l,
+			opacity:  opacity,
+		},
+	})
+	return state.z
+}
+
+// addEffect adds an operation for blending the effect e into
+// area, clipped by the clip state. It returns the new z value.
+func (d *drawOps) addEffect(state drawState, area f32.Rectangle, e *effectOp) int {
+	d.demoteGlyphs(&state)
+	clip := state.clip.Intersect(area)
+	if clip.Empty() {
+		return state.z
+	}
+	bounds := boundRectF(clip).Intersect(image.Rectangle{Max: d.viewport})
+	if bounds.Empty() {
+		return state.z
+	}
+	// Include the pixels that contribute to the blurred bounds.
+	e.src = bounds.Inset(-blurExtent(e.sigma))
+	if e.shadow {
+		e.src = e.src.Intersect(boundRectF(area))
+	}
+	d.effects = append(d.effects, e)
+	state.z++
+	d.imageOps = append(d.imageOps, imageOp{
+		z:    float32(state.z)*2/zdepth - 1.0,
+		path: state.cpath,
+		clip: bounds,
+		material: material{
+			material: materialTexture,
+			effect:   e,
+			opacity:  1,
+		},
+	})
+	return state.z
+}
+
+// addClip intersects the clip state with bounds offset by
+// off. If aux contains path data, the path with the key
+// auxKey and the fill rule given by evenOdd is added to the
+// clip as well.
+func (d *drawOps) addClip(state *drawState, bounds f32.Rectangle, off f32.Point, aux []byte, auxKey ops.Key, evenOdd bool) {
+	if len(aux) > 0 {
+		d.demoteGlyphs(state)
+	}
+	state.clip = state.clip.Intersect(bounds.Add(off))
+	if state.clip.Empty() {
+		return
+	}
+	npath := d.newPathOp()
+	*npath = pathOp{
+		parent: state.cpath,
+		off:    off,
+	}
+	if p := state.cpath; p != nil {
+		npath.hash = p.hash
+	}
+	state.cpath = npath
+	if len(aux) > 0 {
+		npath.hash = clipHash(npath.hash, d.pathHash(auxKey, aux), off, evenOdd)
+		state.rect = false
+		state.cpath.pathKey = auxKey
+		state.cpath.path = true
+		state.cpath.pathVerts = aux
+		state.cpath.evenOdd = evenOdd
+		d.pathOps = append(d.pathOps, state.cpath)
+	}
+}
+
+// transformPath transforms the encoded path vertices in aux by t and
+// returns the encoded result along with its bounds.
+func (d *drawOps) transformPath(aux []byte, t f32.Affine2D) ([]byte, f32.Rectangle) {
+	var b paint.PathBuilder
+	start := d.beginPath(&b)
+	var pen f32.Point
+	// Each quadratic curve is encoded as 4 vertices, one for each
+	// corner of its bounding quad. Only the first is needed.
+	for len(aux) >= path.VertStride*4 {
+		from := t.Transform(decodePoint(aux[8:]))
+		ctrl := t.Transform(decodePoint(aux[16:]))
+		to := t.Transform(decodePoint(aux[24:]))
+		if from != pen {
+			b.Move(from.Sub(pen))
+		}
+		b.Quad(ctrl.Sub(from), to.Sub(from))
+		pen = to
+		aux = aux[path.VertStride*4:]
+	}
+	return d.endPath(&b, start)
+}
+
+// rectPath returns the encoded path of the rectangle r
+// transformed by t, along with its bounds.
+func (d *drawOps) rectPath(r f32.Rectangle, t f32.Affine2D) ([]byte, f32.Rectangle) {
+	var b paint.PathBuilder
+	start := d.beginPath(&b)
+	corners := [...]f32.Point{
+		t.Transform(r.Min),
+		t.Transform(f32.Point{X: r.Max.X, Y: r.Min.Y}),
+		t.Transform(r.Max),
+		t.Transform(f32.Point{X: r.Min.X, Y: r.Max.Y}),
+	}
+	b.Move(corners[0])
+	pen := corners[0]
+	for i := 1; i <= len(corners); i++ {
+		c := corners[i%len(corners)]
+		b.Line(c.Sub(pen))
+		pen = c
+	}
+	return d.endPath(&b, start)
+}
+
+func (d *drawOps) beginPath(b *paint.PathBuilder) int {
+	b.Init(&d.pathScratch)
+	return len(d.pathScratch.Data())
+}
+
+// endPath ends the path being built by b and returns its encoded
+// vertices and bounds.
+func (d *drawOps) endPath(b *paint.PathBuilder, start int) ([]byte, f32.Rectangle) {
+	b.End()
+	// The path is encoded as an aux op followed by a clip op.
+	data := d.pathScratch.Data()[start:]
+	var op clipOp
+	op.decode(data[len(data)-opconst.TypeClipLen:])
+	if len(data) == opconst.TypeClipLen {
+		// Empty path.
+		return nil, op.bounds
+	}
+	return data[opconst.TypeAuxLen : len(data)-opconst.TypeClipLen], op.bounds
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func decodePoint(data []byte) f32.Point {
+	bo := binary.LittleEndian
+	return f32.Point{
+		X: math.Float32frombits(bo.Uint32(data)),
+		Y: math.Float32frombits(bo.Uint32(data[4:])),
+	}
+}
+
+// transformBounds returns the bounds of the
+// rectangle r transformed by t.
+func transformBounds(t f32.Affine2D, r f32.Rectangle) f32.Rectangle {
+	if t == (f32.Affine2D{}) {
+		return r
+	}
+	b := f32.Rectangle{Min: t.Transform(r.Min), Max: t.Transform(r.Max)}.Canon()
+	b = b.Union(f32.Rectangle{
+		Min: t.Transform(f32.Point{X: r.Max.X, Y: r.Min.Y}),
+		Max: t.Transform(f32.Point{X: r.Min.X, Y: r.Max.Y}),
+	}.Canon())
+	return b
+}
+
 func expandPathOp(p *pathOp, clip image.Rectangle) {
 	for p != nil {
 		pclip := p.clip
@@ -795,9 +1465,27 @@ func expandPathOp(p *pathOp, clip image.Rectangle) {
 	}
 }
 
-func (d *drawState) materialFor(cache *resourceCache, rect f32.Rectangle, off f32.Point, clip image.Rectangle) material {
+func (d *drawState) materialFor(cache *resourceCache, rect f32.Rectangle, t ui.TransformOp, clip image.Rectangle) material {
 	var m material
-	if d.img == nil {
+	if d.hasGrad {
+		m.material = materialLinearGradient
+		if d.grad.Radial {
+			m.material = materialRadialGradient
+		}
+		m.uvTrans = d.grad.Trans.Mul(quadTransform(t, clip))
+		n := d.grad.NStops
+		m.opaque = n > 0
+		for i := 0; i < n; i++ {
+			m.stopColors[i] = gamma(d.grad.Colors[i].RGBA())
+			m.stopOffsets[i] = d.grad.Offsets[i]
+			m.opaque = m.opaque && m.stopColors[i][3] == 1.0
+		}
+		// Repeat the last stop to fill the remaining stops.
+		for i := n; i > 0 && i < len(m.stopColors); i++ {
+			m.stopColors[i] = m.stopColors[n-1]
+			m.stopOffsets[i] = m.stopOffsets[n-1]
+		}
+	} else if d.img == nil {
 		m.material = materialColor
 		m.color = gamma(d.color.RGBA())
 		m.opaque = m.color[3] == 1.0
@@ -807,36 +1495,53 @@ func (d *drawState) materialFor(cache *resourceCache, rect f32.Rectangle, off f3
 		m.opaque = m.color[3] == 1.0
 	} else {
 		m.material = materialTexture
-		dr := boundRectF(rect.Add(off))
-		sr := d.imgRect
-		if dx := dr.Dx(); dx != 0 {
-			// Don't clip 1 px width sources.
-			if sdx := sr.Dx(); sdx > 1 {
-				sr.Min.X += ((clip.Min.X-dr.Min.X)*sdx + dx/2) / dx
-				sr.Max.X -= ((dr.Max.X-clip.Max.X)*sdx + dx/2) / dx
-			}
-		}
-		if dy := dr.Dy(); dy != 0 {
-			// Don't clip 1 px height sources.
-			if sdy := sr.Dy(); sdy > 1 {
-				sr.Min.Y += ((clip.Min.Y-dr.Min.Y)*sdy + dy/2) / dy
-				sr.Max.Y -= ((dr.Max.Y-clip.Max.Y)*sdy + dy/2) / dy
-			}
-		}
 		tex, exists := cache.get(d.img)
 		if !exists {
 			t := &texture{
-				src: d.img,
+				src:     d.img,
+				version: d.imgVersion,
 			}
-			cache.put(d.img, t)
+			sz := d.img.Bounds().Size()
+			cache.put(d.img, t, sz.X*sz.Y*4)
 			tex = t
+		} else {
+			tex.(*texture).update(d.imgVersion, d.imgDirty)
 		}
 		m.texture = tex.(*texture)
-		m.uvScale, m.uvOffset = texSpaceTransform(sr, d.img.Bounds().Size())
+		m.opacity = 1
+		m.uvTrans = uvTransform(rect, t, clip, d.imgRect, d.img.Bounds())
 	}
 	return m
 }
 
+// quadTransform returns the transformation from quad coordinates
+// of the clip rectangle to the coordinate space of t.
+func quadTransform(t ui.TransformOp, clip image.Rectangle) f32.Affine2D {
+	// Map quad coordinates to the clip rectangle in window coordinates.
+	quad := f32.NewAffine2D(
+		float32(clip.Dx()), 0, float32(clip.Min.X),
+		0, float32(clip.Dy()), float32(clip.Min.Y),
+	)
+	// Then to the coordinate space of t.
+	return t.Invert().Affine2D().Mul(quad)
+}
+
+// uvTransform returns the transformation from quad coordinates of
+// the clip rectangle to texture coordinates, for the image source
+// rectangle src mapped to the rectangle dst transformed by t.
+func uvTransform(dst f32.Rectangle, t ui.TransformOp, clip image.Rectangle, src, bounds image.Rectangle) f32.Affine2D {
+	local := quadTransform(t, clip)
+	// Map dst to src in texture coordinates.
+	size := bounds.Size()
+	sx := float32(src.Dx()) / (dst.Dx() * float32(size.X))
+	sy := float32(src.Dy()) / (dst.Dy() * float32(size.Y))
+	tex := f32.NewAffine2D(
+		sx, 0, float32(src.Min.X-bounds.Min.X)/float32(size.X)-dst.Min.X*sx,
+		0, sy, float32(src.Min.Y-bounds.Min.Y)/float32(size.Y)-dst.Min.Y*sy,
+	)
+	return tex.Mul(local)
+}
+
 func (r *renderer) drawZOps(ops []imageOp) {
 	r.ctx.Enable(gl.DEPTH_TEST)
 	r.ctx.BindBuffer(gl.ARRAY_BUFFER, r.blitter.quadVerts)
@@ -854,14 +1559,51 @@ func (r *renderer) drawZOps(ops []imageOp) {
 		}
 		drc := img.clip
 		scale, off := clipSpaceTransform(drc, r.blitter.viewport)
-		r.blitter.blit(img.z, m.material, m.color, scale, off, m.uvScale, m.uvOffset)
+		r.blitter.blit(img.z, &m, scale, off)
 	}
 	r.ctx.DisableVertexAttribArray(attribPos)
 	r.ctx.DisableVertexAttribArray(attribUV)
 	r.ctx.Disable(gl.DEPTH_TEST)
 }
 
-func (r *renderer) drawOps(ops []imageOp) {
+// drawLayers draws the operations of each layer
+// into its offscreen target.
+func (r *renderer) drawLayers(layers []*layerOp) {
+	if len(layers) == 0 {
+		return
+	}
+	sizes := make([]image.Point, len(layers))
+	for i, l := range layers {
+		sizes[i] = l.bounds.Size()
+		l.fbo = i
+	}
+	r.layers.resize(r.ctx, r.ctx.caps.srgbaTriple, sizes)
+	r.ctx.ClearColor(0, 0, 0, 0)
+	for _, l := range layers {
+		f := r.layers.fbos[l.fbo]
+		bindFramebuffer(r.ctx, f.fbo)
+		sz := l.bounds.Size()
+		r.ctx.Viewport(0, 0, sz.X, sz.Y)
+		r.ctx.Clear(gl.COLOR_BUFFER_BIT)
+		r.drawOps(l.imageOps, l.bounds, f.fbo)
+	}
+	r.ctx.BindFramebuffer(gl.FRAMEBUFFER, r.pather.stenciler.defFBO)
+}
+
+// layerTransform returns the transformation from quad coordinates
+// to the texture coordinates of a layer target. The target is
+// upside down because it is drawn in OpenGL coordinates.
+func (r *renderer) layerTransform(l *layerOp) f32.Affine2D {
+	f := r.layers.fbos[l.fbo]
+	sz := l.bounds.Size()
+	sx := float32(sz.X) / float32(f.size.X)
+	sy := float32(sz.Y) / float32(f.size.Y)
+	return f32.NewAffine2D(sx, 0, 0, 0, -sy, sy)
+}
+
+// drawOps draws ops to the framebuffer targetFBO, which covers
+// the target rectangle in window coordinates.
+func (r *renderer) drawOps(ops []imageOp, target image.Rectangle, targetFBO gl.Framebuffer) {
 	r.ctx.Enable(gl.DEPTH_TEST)
 	r.ctx.DepthMask(false)
 	r.ctx.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
@@ -873,21 +1615,31 @@ func (r *renderer) drawOps(ops []imageOp) {
 	var coverTex gl.Texture
 	for _, img := range ops {
 		m := img.material
-		switch m.material {
-		case materialTexture:
+		switch {
+		case m.layer != nil:
+			r.ctx.BindTexture(gl.TEXTURE_2D, r.layers.fbos[m.layer.fbo].tex)
+			m.uvTrans = r.layerTransform(m.layer)
+		case m.effect != nil:
+			m.uvTrans = r.drawEffect(m.effect, img.clip, target, targetFBO)
+		case m.material == materialTexture:
 			r.ctx.BindTexture(gl.TEXTURE_2D, r.texHandle(m.texture))
 		}
 		drc := img.clip
-		scale, off := clipSpaceTransform(drc, r.blitter.viewport)
+		scale, off := clipSpaceTransform(drc.Sub(target.Min), target.Size())
 		var fbo stencilFBO
 		switch img.clipType {
 		case clipTypeNone:
-			r.blitter.blit(img.z, m.material, m.color, scale, off, m.uvScale, m.uvOffset)
+			r.blitter.blit(img.z, &m, scale, off)
 			continue
 		case clipTypePath:
 			fbo = r.pather.stenciler.cover(img.place.Idx)
 		case clipTypeIntersection:
 			fbo = r.pather.stenciler.intersections.fbos[img.place.Idx]
+		case clipTypeGlyph:
+			fbo = stencilFBO{
+				tex:  r.glyphs.textures[img.place.Idx],
+				size: image.Point{X: glyphAtlasSize, Y: glyphAtlasSize},
+			}
 		}
 		if coverTex != fbo.tex {
 			coverTex = fbo.tex
@@ -900,7 +1652,7 @@ func (r *renderer) drawOps(ops []imageOp) {
 			Max: img.place.Pos.Add(drc.Size()),
 		}
 		coverScale, coverOff := texSpaceTransform(uv, fbo.size)
-		r.pather.cover(img.z, m.material, m.color, scale, off, m.uvScale, m.uvOffset, coverScale, coverOff)
+		r.pather.cover(img.z, &m, scale, off, coverScale, coverOff, img.evenOdd)
 	}
 	r.ctx.DisableVertexAttribArray(attribPos)
 	r.ctx.DisableVertexAttribArray(attribUV)
@@ -908,25 +1660,78 @@ func (r *renderer) drawOps(ops []imageOp) {
 	r.ctx.Disable(gl.DEPTH_TEST)
 }
 
-func (r *renderer) uploadTexture(img image.Image) {
+// uploadTexture uploads the rows of the texture source covered
+// by rect. If alloc is set, the texture storage is allocated
+// as well.
+func (r *renderer) uploadTexture(t *texture, rect image.Rectangle, alloc bool) {
+	b := t.src.Bounds()
+	// Upload entire rows to avoid copying tightly packed
+	// sources.
+	rect = image.Rect(b.Min.X, rect.Min.Y, b.Max.X, rect.Max.Y)
 	var pixels []byte
-	b := img.Bounds()
-	w, h := b.Dx(), b.Dy()
-	switch img := img.(type) {
+	tt := r.ctx.caps.srgbaTriple
+	switch img := t.src.(type) {
 	case *image.RGBA:
-		if img.Stride == w*4 {
-			start := (b.Min.X + b.Min.Y*w) * 4
-			end := (b.Max.X + (b.Max.Y-1)*w) * 4
-			pixels = img.Pix[start:end]
-		} else {
-			pixels = copyImage(img, b).Pix
+		pixels = packedRows(img.Pix, img.Stride, 4, b, rect)
+	case *image.NRGBA:
+		pixels = packedRows(img.Pix, img.Stride, 4, b, rect)
+		if pixels == nil {
+			tmp := image.NewNRGBA(rect)
+			draw.Draw(tmp, rect, img, rect.Min, draw.Src)
+			pixels = tmp.Pix
 		}
+	case *image.Gray:
+		tt = r.ctx.caps.alphaTriple
+		pixels = packedRows(img.Pix, img.Stride, 1, b, rect)
+		if pixels == nil {
+			tmp := image.NewGray(rect)
+			draw.Draw(tmp, rect, img, rect.Min, draw.Src)
+			pixels = tmp.Pix
+		}
+		// Gray rows are not aligned.
+		r.ctx.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+		defer r.ctx.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	}
+	if pixels == nil {
+		pixels = copyImage(t.src, rect).Pix
+	}
+	if alloc {
+		r.ctx.TexImage2D(gl.TEXTURE_2D, 0, tt.internalFormat, rect.Dx(), rect.Dy(), tt.format, tt.typ, pixels)
+	} else {
+		r.ctx.TexSubImage2D(gl.TEXTURE_2D, 0, 0, rect.Min.Y-b.Min.Y, rect.Dx(), rect.Dy(), tt.format, tt.typ, pixels)
+	}
+}
+
+// packedRows returns the pixels of the rows of rect from an image
+// with the given bounds and pixel size in bytes, or nil if the
+// image rows are not tightly packed.
+func packedRows(pix []byte, stride, bpp int, bounds, rect image.Rectangle) []byte {
+	if stride != bounds.Dx()*bpp {
+		return nil
+	}
+	start := (rect.Min.Y - bounds.Min.Y) * stride
+	end := (rect.Max.Y - bounds.Min.Y) * stride
+	return pix[start:end]
+}
+
+// textureFormat returns the texture format for uploading img.
+func textureFormat(img image.Image) texFormat {
+	switch img.(type) {
+	case *image.NRGBA:
+		return texNRGBA
+	case *image.Gray:
+		return texGray
 	default:
-		pixels = copyImage(img, b).Pix
+		return texRGBA
 	}
-	tt := r.ctx.caps.srgbaTriple
-	r.ctx.TexImage2D(gl.TEXTURE_2D, 0, tt.internalFormat, w, h, tt.format, tt.typ, pixels)
+}
 
+// texFormat returns the format of the texture of m, if any.
+func (m *material) texFormat() texFormat {
+	if m.texture == nil {
+		return texRGBA
+	}
+	return m.texture.format
 }
 
 func gamma(r, g, b, a uint32) [4]float32 {
@@ -944,14 +1749,23 @@ func gamma(r, g, b, a uint32) [4]float32 {
 	return color
 }
 
-func (b *blitter) blit(z float32, mat materialType, col [4]float32, scale, off, uvScale, uvOff f32.Point) {
+func (b *blitter) blit(z float32, m *material, scale, off f32.Point) {
+	mat := m.material
 	b.ctx.UseProgram(b.prog[mat])
 	switch mat {
 	case materialColor:
+		col := m.color
 		b.ctx.Uniform4f(b.vars[mat].uColor, col[0], col[1], col[2], col[3])
-	case materialTexture:
-		b.ctx.Uniform2f(b.vars[mat].uUVScale, uvScale.X, uvScale.Y)
-		b.ctx.Uniform2f(b.vars[mat].uUVOffset, uvOff.X, uvOff.Y)
+	case materialTexture, materialLinearGradient, materialRadialGradient:
+		t1, t2, t3, t4, t5, t6 := m.uvTrans.Elems()
+		b.ctx.Uniform3f(b.vars[mat].uUVTransformR1, t1, t2, t3)
+		b.ctx.Uniform3f(b.vars[mat].uUVTransformR2, t4, t5, t6)
+		if mat == materialTexture {
+			b.ctx.Uniform1f(b.vars[mat].uOpacity, m.opacity)
+			b.ctx.Uniform1i(b.vars[mat].uFormat, int(m.texFormat()))
+		} else {
+			b.vars[mat].gradientUniforms.set(b.ctx, m)
+		}
 	}
 	b.ctx.Uniform1f(b.vars[mat].z, z)
 	b.ctx.Uniform2f(b.vars[mat].uScale, scale.X, scale.Y)
@@ -1026,8 +1840,8 @@ uniform vec2 offset;
 attribute vec2 pos;
 
 attribute vec2 uv;
-uniform vec2 uvScale;
-uniform vec2 uvOffset;
+uniform vec3 uvTransformR1;
+uniform vec3 uvTransformR2;
 
 varying vec2 vUV;
 
@@ -1036,10 +1850,29 @@ void main() {
 	p *= scale;
 	p += offset;
 	gl_Position = vec4(p, z, 1);
-	vUV = uv*uvScale + uvOffset;
+	vUV = vec2(dot(vec3(uv, 1), uvTransformR1), dot(vec3(uv, 1), uvTransformR2));
 }
 `
 
+// gradientHeader evaluates a gradient with MaxGradientStops color
+// stops. Unused stops repeat the last stop.
+var gradientHeader = fmt.Sprintf(`
+#define MAX_STOPS %d
+
+uniform vec4 stopColors[MAX_STOPS];
+uniform float stopOffsets[MAX_STOPS];
+
+vec4 gradient(float t) {
+	vec4 c = stopColors[0];
+	for (int i = 1; i < MAX_STOPS; i++) {
+		float o0 = stopOffsets[i-1];
+		float o1 = stopOffsets[i];
+		c = mix(c, stopColors[i], clamp((t - o0)/max(o1 - o0, 1e-4), 0.0, 1.0));
+	}
+	return c;
+}
+`, opconst.MaxGradientStops)
+
 const blitFSrc = `
 #version 100
 
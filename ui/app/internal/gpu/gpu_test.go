@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/paint"
+)
+
+func TestDrawSequence(t *testing.T) {
+	r := new(recorder)
+	g, err := newRecordingGPU(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Release()
+	ops := new(ui.Ops)
+	red := color.RGBA{R: 0xff, A: 0xff}
+	paint.ColorOp{Color: red}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 50, Y: 50}}}.Add(ops)
+	var p paint.PathBuilder
+	p.Init(ops)
+	p.Line(f32.Point{X: 50})
+	p.Line(f32.Point{Y: 50})
+	p.End()
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 50, Y: 50}}}.Add(ops)
+	frames := []struct {
+		name  string
+		draws []string
+	}{
+		{"first frame", []string{
+			// Clear the framebuffer and blit the opaque rectangle.
+			"Clear(0x4100)",
+			"DrawArrays(TRIANGLE_STRIP, 0, 4)",
+			// Stencil the path and cover it.
+			"Clear(0x4000)",
+			"DrawElements(TRIANGLES, 12, 0)",
+			"DrawArrays(TRIANGLE_STRIP, 0, 4)",
+		}},
+		// Unchanged frames are not redrawn.
+		{"unchanged frame", nil},
+	}
+	for _, f := range frames {
+		r.calls = nil
+		g.Draw(false, image.Point{X: 100, Y: 100}, ops)
+		if err := g.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.draws(); !reflect.DeepEqual(got, f.draws) {
+			t.Errorf("%s: got draw calls\n%q\nexpected\n%q", f.name, got, f.draws)
+		}
+	}
+}
+
+func TestDebugTrace(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	g, err := newGPU(recorderContext{}, new(recorder), CacheBudget{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Release()
+	ops := new(ui.Ops)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 50, Y: 50}}}.Add(ops)
+	sz := image.Point{X: 100, Y: 100}
+	g.Draw(false, sz, ops)
+	g.Flush()
+	if logs.Len() > 0 {
+		t.Errorf("untraced frame logged %q", logs.String())
+	}
+	// Trace an unchanged frame.
+	g.TraceFrame()
+	g.Draw(false, sz, ops)
+	g.Flush()
+	trace := logs.String()
+	for _, call := range []string{"Clear(0x4100)", "DrawArrays(0x5, 0, 4)"} {
+		if !strings.Contains(trace, call) {
+			t.Errorf("trace %q doesn't contain %s", trace, call)
+		}
+	}
+}
+
+func TestTextureUpload(t *testing.T) {
+	r := new(recorder)
+	g, err := newRecordingGPU(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Release()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	frames := []struct {
+		name    string
+		version int
+		dirty   image.Rectangle
+		uploads []string
+	}{
+		{"first frame", 1, image.Rectangle{}, []string{"TexImage2D(8x8)"}},
+		{"unchanged version", 1, image.Rect(0, 0, 8, 8), nil},
+		// Dirty rectangles are uploaded as entire rows.
+		{"dirty rows", 2, image.Rect(2, 2, 4, 4), []string{"TexSubImage2D(0, 2, 8x2)"}},
+		{"dirty outside", 3, image.Rect(0, 6, 8, 20), []string{"TexSubImage2D(0, 6, 8x2)"}},
+		// Skipped versions upload the whole image.
+		{"skipped version", 5, image.Rect(0, 0, 1, 1), []string{"TexSubImage2D(0, 0, 8x8)"}},
+		{"empty dirty", 6, image.Rectangle{}, []string{"TexSubImage2D(0, 0, 8x8)"}},
+	}
+	for _, f := range frames {
+		ops := new(ui.Ops)
+		paint.ImageOp{Src: img, Rect: img.Bounds(), Version: f.version, Dirty: f.dirty}.Add(ops)
+		paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 8, Y: 8}}}.Add(ops)
+		r.calls = nil
+		g.Draw(false, image.Point{X: 100, Y: 100}, ops)
+		if err := g.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		var uploads []string
+		for _, c := range r.calls {
+			if strings.HasPrefix(c, "TexImage2D") || strings.HasPrefix(c, "TexSubImage2D") {
+				uploads = append(uploads, c)
+			}
+		}
+		if !reflect.DeepEqual(uploads, f.uploads) {
+			t.Errorf("%s: got uploads %q, expected %q", f.name, uploads, f.uploads)
+		}
+	}
+}
+
+func TestTextureStats(t *testing.T) {
+	g, err := newRecordingGPU(new(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Release()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	frames := []struct {
+		name  string
+		off   float32
+		stats CacheStats
+	}{
+		{"first frame", 0, CacheStats{Misses: 1, Bytes: 8 * 8 * 4}},
+		// The image moved, but the texture is reused.
+		{"second frame", 10, CacheStats{Hits: 1}},
+	}
+	for _, f := range frames {
+		ops := new(ui.Ops)
+		ui.TransformOp{}.Offset(f32.Point{X: f.off}).Add(ops)
+		paint.ImageOp{Src: img, Rect: img.Bounds()}.Add(ops)
+		paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 8, Y: 8}}}.Add(ops)
+		g.Draw(false, image.Point{X: 100, Y: 100}, ops)
+		if err := g.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := g.Stats().Textures; got != f.stats {
+			t.Errorf("%s: got texture stats %+v, expected %+v", f.name, got, f.stats)
+		}
+	}
+}
+
+func TestGlyphAtlas(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, -4, 4, 0))
+	for i := range mask.Pix {
+		mask.Pix[i] = 0xff
+	}
+	tests := []struct {
+		name   string
+		t      ui.TransformOp
+		glyphs bool
+		// clips are the glyph clips, if any.
+		clips []image.Rectangle
+	}{
+		// Glyphs are snapped to whole pixels.
+		{
+			"offset", ui.TransformOp{}.Offset(f32.Point{X: 10.3, Y: 20.6}), true,
+			[]image.Rectangle{image.Rect(10, 17, 14, 21), image.Rect(15, 17, 19, 21)},
+		},
+		// Large text has no glyphs.
+		{"no glyphs", ui.TransformOp{}, false, nil},
+		// Other transformations fall back to the path.
+		{"scaled", ui.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Point{X: 2, Y: 2})), true, nil},
+		{"rotated", ui.Affine(f32.Affine2D{}.Rotate(f32.Point{}, 0.5)), true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := new(recorder)
+			g, err := newRecordingGPU(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Release()
+			ops := new(ui.Ops)
+			ui.TransformOp{}.Offset(f32.Point{Y: 50}).Add(ops)
+			test.t.Add(ops)
+			if test.glyphs {
+				// Glyphs share masks.
+				paint.GlyphOp{Mask: mask}.Add(ops)
+				paint.GlyphOp{Mask: mask, Origin: image.Point{X: 5}}.Add(ops)
+			}
+			var p paint.PathBuilder
+			p.Init(ops)
+			p.Move(f32.Point{Y: -4})
+			p.Line(f32.Point{X: 9})
+			p.Line(f32.Point{Y: 4})
+			p.Line(f32.Point{X: -9})
+			p.End()
+			paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+			paint.PaintOp{Rect: f32.Rectangle{Min: f32.Point{Y: -4}, Max: f32.Point{X: 9}}}.Add(ops)
+			g.Draw(false, image.Point{X: 100, Y: 100}, ops)
+			if err := g.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			var clips []image.Rectangle
+			for _, img := range g.ops.imageOps {
+				if img.clipType == clipTypeGlyph {
+					clips = append(clips, img.clip.Sub(image.Point{Y: 50}))
+				}
+			}
+			if !reflect.DeepEqual(clips, test.clips) {
+				t.Errorf("got glyph clips %v, expected %v", clips, test.clips)
+			}
+			var uploads, stencils int
+			for _, c := range r.calls {
+				if c == "TexSubImage2D(0, 0, 4x4)" {
+					uploads++
+				}
+				if strings.HasPrefix(c, "DrawElements") {
+					stencils++
+				}
+			}
+			if len(test.clips) > 0 {
+				if uploads != 1 {
+					t.Errorf("got %d mask uploads, expected 1", uploads)
+				}
+				if stencils != 0 {
+					t.Errorf("got %d path stencils, expected none", stencils)
+				}
+			} else if stencils == 0 {
+				t.Error("path not stenciled")
+			}
+		})
+	}
+}
@@ -25,13 +25,17 @@ type pather struct {
 
 type coverer struct {
 	ctx  *context
-	prog [2]gl.Program
-	vars [2]struct {
-		z                             gl.Uniform
-		uScale, uOffset               gl.Uniform
-		uUVScale, uUVOffset           gl.Uniform
-		uCoverUVScale, uCoverUVOffset gl.Uniform
-		uColor                        gl.Uniform
+	prog [numMaterials]gl.Program
+	vars [numMaterials]struct {
+		z                              gl.Uniform
+		uScale, uOffset                gl.Uniform
+		uUVTransformR1, uUVTransformR2 gl.Uniform
+		uCoverUVScale, uCoverUVOffset  gl.Uniform
+		uColor                         gl.Uniform
+		uOpacity                       gl.Uniform
+		uFormat                        gl.Uniform
+		uEvenOdd                       gl.Uniform
+		gradientUniforms
 	}
 }
 
@@ -47,6 +51,7 @@ type stenciler struct {
 	uPathOffset        gl.Uniform
 	uIntersectUVOffset gl.Uniform
 	uIntersectUVScale  gl.Uniform
+	uIntersectEvenOdd  gl.Uniform
 	indexBuf           gl.Buffer
 }
 
@@ -97,36 +102,43 @@ func newCoverer(ctx *context) *coverer {
 		ctx.UseProgram(prog)
 		switch materialType(i) {
 		case materialTexture:
-			uTex := gl.GetUniformLocation(ctx.Functions, prog, "tex")
+			uTex := getUniformLocation(ctx, prog, "tex")
 			ctx.Uniform1i(uTex, 0)
-			c.vars[i].uUVScale = gl.GetUniformLocation(ctx.Functions, prog, "uvScale")
-			c.vars[i].uUVOffset = gl.GetUniformLocation(ctx.Functions, prog, "uvOffset")
+			c.vars[i].uUVTransformR1 = getUniformLocation(ctx, prog, "uvTransformR1")
+			c.vars[i].uUVTransformR2 = getUniformLocation(ctx, prog, "uvTransformR2")
+			c.vars[i].uOpacity = getUniformLocation(ctx, prog, "opacity")
+			c.vars[i].uFormat = getUniformLocation(ctx, prog, "format")
 		case materialColor:
-			c.vars[i].uColor = gl.GetUniformLocation(ctx.Functions, prog, "color")
+			c.vars[i].uColor = getUniformLocation(ctx, prog, "color")
+		case materialLinearGradient, materialRadialGradient:
+			c.vars[i].uUVTransformR1 = getUniformLocation(ctx, prog, "uvTransformR1")
+			c.vars[i].uUVTransformR2 = getUniformLocation(ctx, prog, "uvTransformR2")
+			c.vars[i].gradientUniforms.init(ctx, prog)
 		}
-		uCover := gl.GetUniformLocation(ctx.Functions, prog, "cover")
+		uCover := getUniformLocation(ctx, prog, "cover")
 		ctx.Uniform1i(uCover, 1)
-		c.vars[i].z = gl.GetUniformLocation(ctx.Functions, prog, "z")
-		c.vars[i].uScale = gl.GetUniformLocation(ctx.Functions, prog, "scale")
-		c.vars[i].uOffset = gl.GetUniformLocation(ctx.Functions, prog, "offset")
-		c.vars[i].uCoverUVScale = gl.GetUniformLocation(ctx.Functions, prog, "uvCoverScale")
-		c.vars[i].uCoverUVOffset = gl.GetUniformLocation(ctx.Functions, prog, "uvCoverOffset")
+		c.vars[i].z = getUniformLocation(ctx, prog, "z")
+		c.vars[i].uScale = getUniformLocation(ctx, prog, "scale")
+		c.vars[i].uOffset = getUniformLocation(ctx, prog, "offset")
+		c.vars[i].uCoverUVScale = getUniformLocation(ctx, prog, "uvCoverScale")
+		c.vars[i].uCoverUVOffset = getUniformLocation(ctx, prog, "uvCoverOffset")
+		c.vars[i].uEvenOdd = getUniformLocation(ctx, prog, "evenOdd")
 	}
 	return c
 }
 
 func newStenciler(ctx *context) *stenciler {
 	defFBO := gl.Framebuffer(ctx.GetBinding(gl.FRAMEBUFFER_BINDING))
-	prog, err := gl.CreateProgram(ctx.Functions, stencilVSrc, stencilFSrc, pathAttribs)
+	prog, err := ctx.CreateProgram(stencilVSrc, stencilFSrc, pathAttribs)
 	if err != nil {
 		panic(err)
 	}
 	ctx.UseProgram(prog)
-	iprog, err := gl.CreateProgram(ctx.Functions, intersectVSrc, intersectFSrc, intersectAttribs)
+	iprog, err := ctx.CreateProgram(intersectVSrc, intersectFSrc, intersectAttribs)
 	if err != nil {
 		panic(err)
 	}
-	coverLoc := gl.GetUniformLocation(ctx.Functions, iprog, "cover")
+	coverLoc := getUniformLocation(ctx, iprog, "cover")
 	ctx.UseProgram(iprog)
 	ctx.Uniform1i(coverLoc, 0)
 	return &stenciler{
@@ -134,16 +146,17 @@ func newStenciler(ctx *context) *stenciler {
 		defFBO:             defFBO,
 		prog:               prog,
 		iprog:              iprog,
-		uScale:             gl.GetUniformLocation(ctx.Functions, prog, "scale"),
-		uOffset:            gl.GetUniformLocation(ctx.Functions, prog, "offset"),
-		uPathOffset:        gl.GetUniformLocation(ctx.Functions, prog, "pathOffset"),
-		uIntersectUVScale:  gl.GetUniformLocation(ctx.Functions, iprog, "uvScale"),
-		uIntersectUVOffset: gl.GetUniformLocation(ctx.Functions, iprog, "uvOffset"),
+		uScale:             getUniformLocation(ctx, prog, "scale"),
+		uOffset:            getUniformLocation(ctx, prog, "offset"),
+		uPathOffset:        getUniformLocation(ctx, prog, "pathOffset"),
+		uIntersectUVScale:  getUniformLocation(ctx, iprog, "uvScale"),
+		uIntersectUVOffset: getUniformLocation(ctx, iprog, "uvOffset"),
+		uIntersectEvenOdd:  getUniformLocation(ctx, iprog, "evenOdd"),
 		indexBuf:           ctx.CreateBuffer(),
 	}
 }
 
-func (s *fboSet) resize(ctx *context, sizes []image.Point) {
+func (s *fboSet) resize(ctx *context, tt textureTriple, sizes []image.Point) {
 	// Add fbos.
 	for i := len(s.fbos); i < len(sizes); i++ {
 		tex := ctx.CreateTexture()
@@ -169,7 +182,6 @@ func (s *fboSet) resize(ctx *context, sizes []image.Point) {
 		if resize {
 			f.size = sz
 			ctx.BindTexture(gl.TEXTURE_2D, f.tex)
-			tt := ctx.caps.floatTriple
 			ctx.TexImage2D(gl.TEXTURE_2D, 0, tt.internalFormat, sz.X, sz.Y, tt.format, tt.typ, nil)
 			ctx.BindFramebuffer(gl.FRAMEBUFFER, f.fbo)
 			ctx.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, f.tex, 0)
@@ -246,7 +258,7 @@ func (s *stenciler) beginIntersect(sizes []image.Point) {
 	// 8 bit coverage is enough, but OpenGL ES only supports single channel
 	// floating point formats. Replace with GL_RGB+GL_UNSIGNED_BYTE if
 	// no floating point support is available.
-	s.intersections.resize(s.ctx, sizes)
+	s.intersections.resize(s.ctx, s.ctx.caps.floatTriple, sizes)
 	s.ctx.ClearColor(1.0, 0.0, 0.0, 0.0)
 	s.ctx.UseProgram(s.iprog)
 }
@@ -270,7 +282,7 @@ func (s *stenciler) begin(sizes []image.Point) {
 	s.ctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
 	s.ctx.ActiveTexture(gl.TEXTURE0)
 	s.ctx.BlendFunc(gl.ONE, gl.ONE)
-	s.fbos.resize(s.ctx, sizes)
+	s.fbos.resize(s.ctx, s.ctx.caps.floatTriple, sizes)
 	s.ctx.ClearColor(0.0, 0.0, 0.0, 0.0)
 	s.ctx.UseProgram(s.prog)
 	s.ctx.EnableVertexAttribArray(attribPathCorner)
@@ -334,24 +346,34 @@ func (s *stenciler) end() {
 	s.ctx.BindFramebuffer(gl.FRAMEBUFFER, s.defFBO)
 }
 
-func (p *pather) cover(z float32, mat materialType, col [4]float32, scale, off, uvScale, uvOff, coverScale, coverOff f32.Point) {
-	p.coverer.cover(z, mat, col, scale, off, uvScale, uvOff, coverScale, coverOff)
+func (p *pather) cover(z float32, m *material, scale, off, coverScale, coverOff f32.Point, evenOdd bool) {
+	p.coverer.cover(z, m, scale, off, coverScale, coverOff, evenOdd)
 }
 
-func (c *coverer) cover(z float32, mat materialType, col [4]float32, scale, off, uvScale, uvOff, coverScale, coverOff f32.Point) {
+func (c *coverer) cover(z float32, m *material, scale, off, coverScale, coverOff f32.Point, evenOdd bool) {
+	mat := m.material
 	c.ctx.UseProgram(c.prog[mat])
 	switch mat {
 	case materialColor:
+		col := m.color
 		c.ctx.Uniform4f(c.vars[mat].uColor, col[0], col[1], col[2], col[3])
-	case materialTexture:
-		c.ctx.Uniform2f(c.vars[mat].uUVScale, uvScale.X, uvScale.Y)
-		c.ctx.Uniform2f(c.vars[mat].uUVOffset, uvOff.X, uvOff.Y)
+	case materialTexture, materialLinearGradient, materialRadialGradient:
+		t1, t2, t3, t4, t5, t6 := m.uvTrans.Elems()
+		c.ctx.Uniform3f(c.vars[mat].uUVTransformR1, t1, t2, t3)
+		c.ctx.Uniform3f(c.vars[mat].uUVTransformR2, t4, t5, t6)
+		if mat == materialTexture {
+			c.ctx.Uniform1f(c.vars[mat].uOpacity, m.opacity)
+			c.ctx.Uniform1i(c.vars[mat].uFormat, int(m.texFormat()))
+		} else {
+			c.vars[mat].gradientUniforms.set(c.ctx, m)
+		}
 	}
 	c.ctx.Uniform1f(c.vars[mat].z, z)
 	c.ctx.Uniform2f(c.vars[mat].uScale, scale.X, scale.Y)
 	c.ctx.Uniform2f(c.vars[mat].uOffset, off.X, off.Y)
 	c.ctx.Uniform2f(c.vars[mat].uCoverUVScale, coverScale.X, coverScale.Y)
 	c.ctx.Uniform2f(c.vars[mat].uCoverUVOffset, coverOff.X, coverOff.Y)
+	c.ctx.Uniform1i(c.vars[mat].uEvenOdd, boolInt(evenOdd))
 	c.ctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
 }
 
@@ -496,8 +518,8 @@ precision highp float;
 uniform float z;
 uniform vec2 scale;
 uniform vec2 offset;
-uniform vec2 uvScale;
-uniform vec2 uvOffset;
+uniform vec3 uvTransformR1;
+uniform vec3 uvTransformR2;
 uniform vec2 uvCoverScale;
 uniform vec2 uvCoverOffset;
 
@@ -510,7 +532,7 @@ varying vec2 vUV;
 
 void main() {
     gl_Position = vec4(pos*scale + offset, z, 1);
-	vUV = uv*uvScale + uvOffset;
+	vUV = vec2(dot(vec3(uv, 1), uvTransformR1), dot(vec3(uv, 1), uvTransformR2));
 	vCoverUV = uv*uvCoverScale+uvCoverOffset;
 }
 `
@@ -524,6 +546,7 @@ precision mediump float;
 // large cover atlases.
 varying highp vec2 vCoverUV;
 uniform sampler2D cover;
+uniform bool evenOdd;
 varying vec2 vUV;
 
 HEADER
@@ -531,6 +554,15 @@ HEADER
 void main() {
     gl_FragColor = GET_COLOR;
 	float cover = abs(texture2D(cover, vCoverUV).r);
+	if (evenOdd) {
+		// Map odd winding numbers to 1 and
+		// even winding numbers to 0.
+		cover = 1.0 - abs(1.0 - mod(cover, 2.0));
+	} else {
+		// Clamp to implement the nonzero fill rule for
+		// overlapping contours.
+		cover = min(cover, 1.0);
+	}
 	gl_FragColor *= cover;
 }
 `
@@ -565,9 +597,15 @@ precision mediump float;
 // large cover atlases.
 varying highp vec2 vUV;
 uniform sampler2D cover;
+uniform bool evenOdd;
 
 void main() {
 	float cover = abs(texture2D(cover, vUV).r);
+	if (evenOdd) {
+		cover = 1.0 - abs(1.0 - mod(cover, 2.0));
+	} else {
+		cover = min(cover, 1.0);
+	}
     gl_FragColor.r = cover;
 }
 `
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"fmt"
+	"strings"
+
+	"gioui.org/ui/app/internal/gl"
+)
+
+// recorder is a backend that records the calls made to it
+// instead of drawing.
+type recorder struct {
+	calls []string
+	// objects is the number of objects created.
+	objects uint
+}
+
+// recorderContext is a gl.Context for drawing with a recorder.
+type recorderContext struct{}
+
+var _ backend = (*recorder)(nil)
+
+// draws returns the recorded clears and draw calls.
+func (r *recorder) draws() []string {
+	var draws []string
+	for _, c := range r.calls {
+		if strings.HasPrefix(c, "Clear(") || strings.HasPrefix(c, "Draw") {
+			draws = append(draws, c)
+		}
+	}
+	return draws
+}
+
+func (r *recorder) record(format string, args ...interface{}) {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) newObject() uint {
+	r.objects++
+	return r.objects
+}
+
+func (r *recorder) CreateBuffer() gl.Buffer {
+	return gl.Buffer{V: r.newObject()}
+}
+
+func (r *recorder) DeleteBuffer(v gl.Buffer) {}
+
+func (r *recorder) BindBuffer(target gl.Enum, b gl.Buffer) {
+	r.record("BindBuffer(%#x, %d)", target, b.V)
+}
+
+func (r *recorder) BufferData(target gl.Enum, src []byte, usage gl.Enum) {
+	r.record("BufferData(%#x, %d bytes)", target, len(src))
+}
+
+func (r *recorder) CreateTexture() gl.Texture {
+	return gl.Texture{V: r.newObject()}
+}
+
+func (r *recorder) DeleteTexture(v gl.Texture) {}
+
+func (r *recorder) ActiveTexture(texture gl.Enum) {}
+
+func (r *recorder) BindTexture(target gl.Enum, t gl.Texture) {
+	r.record("BindTexture(%#x, %d)", target, t.V)
+}
+
+func (r *recorder) TexParameteri(target, pname gl.Enum, param int) {}
+
+func (r *recorder) TexImage2D(target gl.Enum, level int, internalFormat int, width, height int, format, ty gl.Enum, data []byte) {
+	r.record("TexImage2D(%dx%d)", width, height)
+}
+
+func (r *recorder) TexSubImage2D(target gl.Enum, level int, x, y, width, height int, format, ty gl.Enum, data []byte) {
+	r.record("TexSubImage2D(%d, %d, %dx%d)", x, y, width, height)
+}
+
+func (r *recorder) CopyTexSubImage2D(target gl.Enum, level, xoffset, yoffset, x, y, width, height int) {
+	r.record("CopyTexSubImage2D(%dx%d)", width, height)
+}
+
+func (r *recorder) PixelStorei(pname gl.Enum, param int32) {}
+
+func (r *recorder) CreateFramebuffer() gl.Framebuffer {
+	return gl.Framebuffer{V: r.newObject()}
+}
+
+func (r *recorder) DeleteFramebuffer(v gl.Framebuffer) {}
+
+func (r *recorder) BindFramebuffer(target gl.Enum, fb gl.Framebuffer) {
+	r.record("BindFramebuffer(%d)", fb.V)
+}
+
+func (r *recorder) FramebufferTexture2D(target, attachment, texTarget gl.Enum, t gl.Texture, level int) {
+}
+
+func (r *recorder) CheckFramebufferStatus(target gl.Enum) gl.Enum {
+	return gl.FRAMEBUFFER_COMPLETE
+}
+
+func (r *recorder) InvalidateFramebuffer(target, attachment gl.Enum) {}
+
+func (r *recorder) CreateProgram(vsSrc, fsSrc string, attribs []string) (gl.Program, error) {
+	return gl.Program{V: r.newObject()}, nil
+}
+
+func (r *recorder) DeleteProgram(p gl.Program) {}
+
+func (r *recorder) UseProgram(p gl.Program) {
+	r.record("UseProgram(%d)", p.V)
+}
+
+func (r *recorder) GetUniformLocation(p gl.Program, name string) gl.Uniform {
+	return gl.Uniform{V: int(r.newObject())}
+}
+
+func (r *recorder) Uniform1f(dst gl.Uniform, v float32)              {}
+func (r *recorder) Uniform1i(dst gl.Uniform, v int)                  {}
+func (r *recorder) Uniform2f(dst gl.Uniform, v0, v1 float32)         {}
+func (r *recorder) Uniform3f(dst gl.Uniform, v0, v1, v2 float32)     {}
+func (r *recorder) Uniform4f(dst gl.Uniform, v0, v1, v2, v3 float32) {}
+
+func (r *recorder) VertexAttribPointer(dst gl.Attrib, size int, ty gl.Enum, normalized bool, stride, offset int) {
+}
+
+func (r *recorder) EnableVertexAttribArray(a gl.Attrib)  {}
+func (r *recorder) DisableVertexAttribArray(a gl.Attrib) {}
+
+func (r *recorder) Viewport(x, y, width, height int) {
+	r.record("Viewport(%d, %d, %d, %d)", x, y, width, height)
+}
+
+func (r *recorder) Scissor(x, y, width, height int32) {
+	r.record("Scissor(%d, %d, %d, %d)", x, y, width, height)
+}
+
+func (r *recorder) Enable(cap gl.Enum) {
+	r.record("Enable(%#x)", cap)
+}
+
+func (r *recorder) Disable(cap gl.Enum) {
+	r.record("Disable(%#x)", cap)
+}
+
+func (r *recorder) BlendFunc(sfactor, dfactor gl.Enum) {}
+func (r *recorder) DepthFunc(f gl.Enum)                {}
+func (r *recorder) DepthMask(mask bool)                {}
+
+func (r *recorder) ClearColor(red, green, blue, alpha float32) {}
+func (r *recorder) ClearDepthf(d float32)                      {}
+
+func (r *recorder) Clear(mask gl.Enum) {
+	r.record("Clear(%#x)", mask)
+}
+
+func (r *recorder) DrawArrays(mode gl.Enum, first, count int) {
+	r.record("DrawArrays(%s, %d, %d)", modeName(mode), first, count)
+}
+
+func (r *recorder) DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int) {
+	r.record("DrawElements(%s, %d, %d)", modeName(mode), count, offset)
+}
+
+func (r *recorder) CreateQuery() gl.Query {
+	return gl.Query{V: r.newObject()}
+}
+
+func (r *recorder) DeleteQuery(query gl.Query)                {}
+func (r *recorder) BeginQuery(target gl.Enum, query gl.Query) {}
+func (r *recorder) EndQuery(target gl.Enum)                   {}
+
+func (r *recorder) GetQueryObjectuiv(query gl.Query, pname gl.Enum) uint {
+	return 0
+}
+
+func (r *recorder) GetString(pname gl.Enum) string {
+	switch pname {
+	case gl.VERSION:
+		return "OpenGL ES 3.0"
+	default:
+		return ""
+	}
+}
+
+func (r *recorder) GetInteger(pname gl.Enum) int {
+	switch pname {
+	case gl.MAX_TEXTURE_SIZE:
+		return 4096
+	default:
+		return 0
+	}
+}
+
+func (r *recorder) GetBinding(pname gl.Enum) gl.Object {
+	return gl.Object{}
+}
+
+func (r *recorder) GetError() gl.Enum {
+	return 0
+}
+
+func modeName(mode gl.Enum) string {
+	switch mode {
+	case gl.TRIANGLES:
+		return "TRIANGLES"
+	case gl.TRIANGLE_STRIP:
+		return "TRIANGLE_STRIP"
+	default:
+		return fmt.Sprintf("%#x", mode)
+	}
+}
+
+func (recorderContext) Functions() *gl.Functions { return nil }
+func (recorderContext) Present() error           { return nil }
+func (recorderContext) MakeCurrent() error       { return nil }
+func (recorderContext) Release()                 {}
+func (recorderContext) Lock()                    {}
+func (recorderContext) Unlock()                  {}
+
+// newRecordingGPU returns a GPU that records its calls
+// in r.
+func newRecordingGPU(r *recorder) (*GPU, error) {
+	return newGPU(recorderContext{}, r, CacheBudget{}, false)
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"time"
+
+	"gioui.org/ui/app/internal/gl"
+)
+
+// FrameStats describe the rendering of a frame.
+type FrameStats struct {
+	// Collect is the time spent converting the frame
+	// operations to draw operations.
+	Collect time.Duration
+	// Blit, Stencil and Cover are the times spent
+	// issuing the blitting of opaque images, the
+	// stenciling of paths and the drawing of the
+	// remaining operations.
+	Blit, Stencil, Cover time.Duration
+	// GPU is the GPU time of the most recently
+	// measured frame, or zero if GPU timers are
+	// not supported.
+	GPU time.Duration
+	// DrawCalls is the number of draw calls.
+	DrawCalls int
+	// Paths is the number of paths drawn.
+	Paths int
+	// UploadBytes is the number of bytes uploaded
+	// to textures and buffers.
+	UploadBytes int
+	// Textures and PathCache count the hits and misses
+	// of the resource caches during the frame.
+	Textures, PathCache CacheStats
+}
+
+// frameCounters count the GL calls of a frame.
+type frameCounters struct {
+	drawCalls   int
+	uploadBytes int
+}
+
+func (c *context) DrawArrays(mode gl.Enum, first int, count int) {
+	c.counters.drawCalls++
+	c.backend.DrawArrays(mode, first, count)
+}
+
+func (c *context) DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int) {
+	c.counters.drawCalls++
+	c.backend.DrawElements(mode, count, ty, offset)
+}
+
+func (c *context) BufferData(target gl.Enum, src []byte, usage gl.Enum) {
+	c.counters.uploadBytes += len(src)
+	c.backend.BufferData(target, src, usage)
+}
+
+func (c *context) TexImage2D(target gl.Enum, level int, internalFormat int, width int, height int, format gl.Enum, ty gl.Enum, data []byte) {
+	c.counters.uploadBytes += len(data)
+	c.backend.TexImage2D(target, level, internalFormat, width, height, format, ty, data)
+}
+
+func (c *context) TexSubImage2D(target gl.Enum, level int, x int, y int, width int, height int, format gl.Enum, ty gl.Enum, data []byte) {
+	c.counters.uploadBytes += len(data)
+	c.backend.TexSubImage2D(target, level, x, y, width, height, format, ty, data)
+}
+
+// frameStats returns the counters of the current frame and
+// resets them. The cache statistics are the changes since
+// tex and paths.
+func (g *GPU) frameStats(ctx *context, tex, paths CacheStats) FrameStats {
+	c := ctx.counters
+	ctx.counters = frameCounters{}
+	return FrameStats{
+		DrawCalls:   c.drawCalls,
+		UploadBytes: c.uploadBytes,
+		Textures:    cacheDelta(g.cache.lru.stats, tex),
+		PathCache:   cacheDelta(g.pathCache.lru.stats, paths),
+	}
+}
+
+// cacheDelta returns the change from old to s.
+func cacheDelta(s, old CacheStats) CacheStats {
+	return CacheStats{
+		Hits:      s.Hits - old.Hits,
+		Misses:    s.Misses - old.Misses,
+		Evictions: s.Evictions - old.Evictions,
+		Bytes:     s.Bytes - old.Bytes,
+	}
+}
@@ -3,7 +3,13 @@
 package input
 
 import (
+	"encoding/binary"
+	"image"
+	"math"
+	"sort"
+
 	"gioui.org/ui"
+	"gioui.org/ui/f32"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/opconst"
 	"gioui.org/ui/internal/ops"
@@ -12,15 +18,76 @@ import (
 
 type TextInputState uint8
 
+// TextInput describes the caret and surrounding text of
+// the focused handler, as reported by its key.TextInputOp.
+type TextInput struct {
+	// Caret is the caret rectangle in window coordinates.
+	Caret          image.Rectangle
+	Text           string
+	Cursor, Anchor int
+}
+
 type keyQueue struct {
 	focus    input.Key
 	handlers map[input.Key]*keyHandler
 	reader   ops.Reader
 	state    TextInputState
+	// order is the focus order of the handlers.
+	order []focusEntry
+	// requests are the FocusOps and MoveFocusOps of
+	// the current frame.
+	requests []focusRequest
+	// nodes is the StackOp tree of the frame, with the
+	// root at index 0.
+	nodes     []keyNode
+	shortcuts []shortcut
+	// inputs are the TextInputOps of the current frame.
+	inputs []textInputEntry
+	// input is the TextInput of the focused handler.
+	input   TextInput
+	inputOK bool
+	// moved is set when Push moved the focus since the
+	// last Frame.
+	moved bool
+	// pressed maps the names of the keys pressed for a
+	// shortcut to the shortcut, so the releases follow
+	// the presses.
+	pressed map[rune]key.ShortcutOp
+}
+
+type textInputEntry struct {
+	key   input.Key
+	input TextInput
 }
 
 type keyHandler struct {
 	active bool
+	// node is the StackOp node of the handler.
+	node int
+	// tab is set when the handler takes Tab and Shift-Tab.
+	tab bool
+}
+
+type keyNode struct {
+	parent int
+	depth  int
+}
+
+type shortcut struct {
+	op   key.ShortcutOp
+	node int
+}
+
+type focusEntry struct {
+	key      input.Key
+	tabIndex int
+}
+
+type focusRequest struct {
+	// key is the FocusOp handler, if move is false.
+	key  input.Key
+	move bool
+	dir  key.FocusDirection
 }
 
 type listenerPriority uint8
@@ -38,6 +105,12 @@ const (
 	TextInputOpen
 )
 
+// TextInput returns the TextInput of the focused handler as
+// determined in Frame, if any.
+func (q *keyQueue) TextInput() (TextInput, bool) {
+	return q.input, q.inputOK
+}
+
 // InputState returns the last text input state as
 // determined in Frame.
 func (q *keyQueue) InputState() TextInputState {
@@ -52,7 +125,14 @@ func (q *keyQueue) Frame(root *ui.Ops, events *handlerEvents) {
 		h.active = false
 	}
 	q.reader.Reset(root)
-	focus, pri, hide := q.resolveFocus(events)
+	q.order = q.order[:0]
+	q.requests = q.requests[:0]
+	q.shortcuts = q.shortcuts[:0]
+	q.inputs = q.inputs[:0]
+	q.nodes = append(q.nodes[:0], keyNode{parent: -1})
+	focus, pri, hide := q.resolveFocus(events, 0, ui.TransformOp{})
+	q.sortOrder()
+	q.dropPressed()
 	for k, h := range q.handlers {
 		if !h.active {
 			delete(q.handlers, k)
@@ -62,19 +142,31 @@ func (q *keyQueue) Frame(root *ui.Ops, events *handlerEvents) {
 			}
 		}
 	}
-	if focus != q.focus {
-		if q.focus != nil {
-			events.Add(q.focus, key.FocusEvent{Focus: false})
+	// Focus requests act like the Focus flag of InputOp.
+	for _, r := range q.requests {
+		switch {
+		case r.move:
+			focus, pri = q.moveFocus(focus, r.dir), priNewFocus
+		case q.handlers[r.key] != nil:
+			focus, pri = r.key, priNewFocus
 		}
-		q.focus = focus
-		if q.focus != nil {
-			events.Add(q.focus, key.FocusEvent{Focus: true})
-		} else {
+	}
+	if focus != q.focus {
+		q.setFocus(focus, events)
+		if focus == nil {
 			hide = true
 		}
 	}
+	q.input, q.inputOK = TextInput{}, false
+	for _, in := range q.inputs {
+		if in.key == q.focus {
+			q.input, q.inputOK = in.input, true
+		}
+	}
+	moved := q.moved
+	q.moved = false
 	switch {
-	case pri == priNewFocus:
+	case pri == priNewFocus, moved && q.focus != nil:
 		q.state = TextInputOpen
 	case hide:
 		q.state = TextInputClose
@@ -84,12 +176,176 @@ func (q *keyQueue) Frame(root *ui.Ops, events *handlerEvents) {
 }
 
 func (q *keyQueue) Push(e input.Event, events *handlerEvents) {
+	if ke, ok := e.(key.Event); ok {
+		s, ok := q.pressed[ke.Name]
+		switch {
+		case ke.State == key.Press:
+			s, ok = q.shortcut(ke)
+			if ok {
+				if q.pressed == nil {
+					q.pressed = make(map[rune]key.ShortcutOp)
+				}
+				q.pressed[ke.Name] = s
+			}
+		case ok:
+			// Release the key where it was pressed, even if
+			// the modifiers no longer match.
+			delete(q.pressed, ke.Name)
+		}
+		if ok {
+			events.Add(s.Key, e)
+			if !s.Pass || s.Key == q.focus {
+				return
+			}
+		}
+	}
+	// Tab and Shift-Tab move the focus, unless the focused
+	// handler takes them.
+	if ke, ok := e.(key.Event); ok && ke.Name == key.NameTab && ke.Modifiers&^key.ModShift == 0 && !q.takesTab() {
+		if ke.State == key.Press {
+			dir := key.FocusNext
+			if ke.Modifiers.Contain(key.ModShift) {
+				dir = key.FocusPrevious
+			}
+			if k := q.moveFocus(q.focus, dir); k != q.focus {
+				q.setFocus(k, events)
+				q.moved = true
+			}
+		}
+		return
+	}
 	if q.focus != nil {
 		events.Add(q.focus, e)
 	}
 }
 
-func (q *keyQueue) resolveFocus(events *handlerEvents) (input.Key, listenerPriority, bool) {
+// takesTab reports whether the focused handler takes
+// Tab and Shift-Tab.
+func (q *keyQueue) takesTab() bool {
+	h, ok := q.handlers[q.focus]
+	return ok && h.tab
+}
+
+// dropPressed forgets the pressed keys of shortcuts
+// that are no longer present.
+func (q *keyQueue) dropPressed() {
+	for n, p := range q.pressed {
+		found := false
+		for _, s := range q.shortcuts {
+			if s.op.Key == p.Key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(q.pressed, n)
+		}
+	}
+}
+
+// shortcut returns the ShortcutOp in scope that
+// matches e, if any. Without a focused handler, every
+// ShortcutOp is in scope.
+func (q *keyQueue) shortcut(e key.Event) (key.ShortcutOp, bool) {
+	h, focused := q.handlers[q.focus]
+	best := -1
+	for i, s := range q.shortcuts {
+		if !matchShortcut(s.op, e) || focused && !q.contains(s.node, h.node) {
+			continue
+		}
+		if best == -1 || q.nodes[s.node].depth >= q.nodes[q.shortcuts[best].node].depth {
+			best = i
+		}
+	}
+	if best == -1 {
+		return key.ShortcutOp{}, false
+	}
+	return q.shortcuts[best].op, true
+}
+
+// contains reports whether node n is n2 or one of
+// its ancestors.
+func (q *keyQueue) contains(n, n2 int) bool {
+	for ; n2 != -1; n2 = q.nodes[n2].parent {
+		if n2 == n {
+			return true
+		}
+	}
+	return false
+}
+
+func matchShortcut(op key.ShortcutOp, e key.Event) bool {
+	if e.Name != op.Name || !e.Modifiers.Contain(op.Modifiers) {
+		return false
+	}
+	extra := e.Modifiers &^ op.Modifiers
+	// ModCommand is reported along with the key
+	// that generates it.
+	if op.Modifiers.Contain(key.ModCommand) {
+		extra &^= key.ModCtrl | key.ModSuper
+	}
+	if op.Modifiers&(key.ModCtrl|key.ModSuper) != 0 {
+		extra &^= key.ModCommand
+	}
+	return extra == 0
+}
+
+// setFocus moves the focus to k and notifies the handlers
+// losing and gaining focus.
+func (q *keyQueue) setFocus(k input.Key, events *handlerEvents) {
+	if k == q.focus {
+		return
+	}
+	if q.focus != nil {
+		events.Add(q.focus, key.FocusEvent{Focus: false})
+	}
+	q.focus = k
+	if q.focus != nil {
+		events.Add(q.focus, key.FocusEvent{Focus: true})
+	}
+}
+
+// moveFocus returns the handler after or before k in the
+// focus order. If k is not in the order, moveFocus returns
+// the first or last handler.
+func (q *keyQueue) moveFocus(k input.Key, dir key.FocusDirection) input.Key {
+	n := len(q.order)
+	if n == 0 {
+		return k
+	}
+	idx := -1
+	for i, e := range q.order {
+		if e.key == k {
+			idx = i
+			break
+		}
+	}
+	switch {
+	case idx == -1 && dir == key.FocusNext:
+		idx = 0
+	case idx == -1:
+		idx = n - 1
+	case dir == key.FocusNext:
+		idx = (idx + 1) % n
+	default:
+		idx = (idx - 1 + n) % n
+	}
+	return q.order[idx].key
+}
+
+// sortOrder sorts the focus order by tab index, keeping
+// the op order of handlers with equal index.
+func (q *keyQueue) sortOrder() {
+	sort.SliceStable(q.order, func(i, j int) bool {
+		ti, tj := q.order[i].tabIndex, q.order[j].tabIndex
+		if (ti > 0) != (tj > 0) {
+			return ti > 0
+		}
+		return ti < tj
+	})
+}
+
+func (q *keyQueue) resolveFocus(events *handlerEvents, node int, t ui.TransformOp) (input.Key, listenerPriority, bool) {
 	var k input.Key
 	var pri listenerPriority
 	var hide bool
@@ -118,11 +374,45 @@ loop:
 				// Reset the handler on (each) first appearance.
 				events.Set(op.Key, []input.Event{key.FocusEvent{Focus: false}})
 			}
+			if !h.active {
+				h.node = node
+				h.tab = op.Tab
+				if op.TabIndex >= 0 {
+					q.order = append(q.order, focusEntry{key: op.Key, tabIndex: op.TabIndex})
+				}
+			}
 			h.active = true
+		case opconst.TypeShortcut:
+			q.shortcuts = append(q.shortcuts, shortcut{
+				op:   decodeShortcutOp(encOp.Data, encOp.Refs),
+				node: node,
+			})
+		case opconst.TypeFocus:
+			q.requests = append(q.requests, focusRequest{key: encOp.Refs[0]})
+		case opconst.TypeMoveFocus:
+			q.requests = append(q.requests, focusRequest{
+				move: true,
+				dir:  key.FocusDirection(encOp.Data[1]),
+			})
+		case opconst.TypeTransform:
+			op := ops.DecodeTransformOp(encOp.Data)
+			t = t.Multiply(ui.TransformOp(op))
+		case opconst.TypeTextInput:
+			op := decodeTextInputOp(encOp.Data, encOp.Refs)
+			q.inputs = append(q.inputs, textInputEntry{
+				key: op.Key,
+				input: TextInput{
+					Caret:  transformRect(t, op.Caret),
+					Text:   op.Text,
+					Cursor: op.Cursor,
+					Anchor: op.Anchor,
+				},
+			})
 		case opconst.TypeHideInput:
 			hide = true
 		case opconst.TypePush:
-			newK, newPri, h := q.resolveFocus(events)
+			q.nodes = append(q.nodes, keyNode{parent: node, depth: q.nodes[node].depth + 1})
+			newK, newPri, h := q.resolveFocus(events, len(q.nodes)-1, t)
 			hide = hide || h
 			if newPri.replaces(pri) {
 				k, pri = newK, newPri
@@ -143,8 +433,78 @@ func decodeKeyInputOp(d []byte, refs []interface{}) key.InputOp {
 	if opconst.OpType(d[0]) != opconst.TypeKeyInput {
 		panic("invalid op")
 	}
+	bo := binary.LittleEndian
 	return key.InputOp{
-		Focus: d[1] != 0,
-		Key:   refs[0].(input.Key),
+		Focus:    d[1] != 0,
+		Key:      refs[0].(input.Key),
+		TabIndex: int(int32(bo.Uint32(d[2:]))),
+		Tab:      d[6] != 0,
+	}
+}
+
+func decodeShortcutOp(d []byte, refs []interface{}) key.ShortcutOp {
+	if opconst.OpType(d[0]) != opconst.TypeShortcut {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	return key.ShortcutOp{
+		Key:       refs[0].(input.Key),
+		Name:      rune(bo.Uint32(d[1:])),
+		Modifiers: key.Modifiers(bo.Uint32(d[5:])),
+		Pass:      d[9] != 0,
+	}
+}
+
+// transformRect returns the bounding box of r transformed
+// by t. Every corner is transformed, because rotations and
+// flips may move any corner to the top left.
+func transformRect(t ui.TransformOp, r image.Rectangle) image.Rectangle {
+	corners := [4]f32.Point{
+		{X: float32(r.Min.X), Y: float32(r.Min.Y)},
+		{X: float32(r.Max.X), Y: float32(r.Min.Y)},
+		{X: float32(r.Min.X), Y: float32(r.Max.Y)},
+		{X: float32(r.Max.X), Y: float32(r.Max.Y)},
+	}
+	b := f32.Rectangle{Min: t.Transform(corners[0])}
+	b.Max = b.Min
+	for _, c := range corners[1:] {
+		c = t.Transform(c)
+		b.Min.X = float32(math.Min(float64(b.Min.X), float64(c.X)))
+		b.Min.Y = float32(math.Min(float64(b.Min.Y), float64(c.Y)))
+		b.Max.X = float32(math.Max(float64(b.Max.X), float64(c.X)))
+		b.Max.Y = float32(math.Max(float64(b.Max.Y), float64(c.Y)))
+	}
+	return image.Rectangle{
+		Min: image.Point{
+			X: int(math.Round(float64(b.Min.X))),
+			Y: int(math.Round(float64(b.Min.Y))),
+		},
+		Max: image.Point{
+			X: int(math.Round(float64(b.Max.X))),
+			Y: int(math.Round(float64(b.Max.Y))),
+		},
+	}
+}
+
+func decodeTextInputOp(d []byte, refs []interface{}) key.TextInputOp {
+	if opconst.OpType(d[0]) != opconst.TypeTextInput {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	return key.TextInputOp{
+		Key: refs[0].(input.Key),
+		Caret: image.Rectangle{
+			Min: image.Point{
+				X: int(int32(bo.Uint32(d[1:]))),
+				Y: int(int32(bo.Uint32(d[5:]))),
+			},
+			Max: image.Point{
+				X: int(int32(bo.Uint32(d[9:]))),
+				Y: int(int32(bo.Uint32(d[13:]))),
+			},
+		},
+		Text:   refs[1].(string),
+		Cursor: int(int32(bo.Uint32(d[17:]))),
+		Anchor: int(int32(bo.Uint32(d[21:]))),
 	}
 }
@@ -0,0 +1,332 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/key"
+)
+
+func TestTextInputCaret(t *testing.T) {
+	caret := image.Rect(10, 20, 12, 40)
+	tests := []struct {
+		name string
+		t    ui.TransformOp
+		exp  image.Rectangle
+	}{
+		{"identity", ui.TransformOp{}, caret},
+		{"offset", ui.TransformOp{}.Offset(f32.Point{X: 5, Y: -5}), image.Rect(15, 15, 17, 35)},
+		{
+			"flip",
+			ui.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Point{X: -1, Y: -1})),
+			image.Rect(-12, -40, -10, -20),
+		},
+		{
+			"rotate",
+			ui.Affine(f32.Affine2D{}.Rotate(f32.Point{}, math.Pi/2)),
+			image.Rect(-40, 10, -20, 12),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ops := new(ui.Ops)
+			h := new(int)
+			test.t.Add(ops)
+			key.InputOp{Key: h, Focus: true}.Add(ops)
+			key.TextInputOp{Key: h, Caret: caret, Text: "text", Cursor: 1, Anchor: 3}.Add(ops)
+			var r Router
+			r.Frame(ops)
+			ti, ok := r.TextInput()
+			if !ok {
+				t.Fatal("no TextInput for the focused handler")
+			}
+			if ti.Caret != test.exp {
+				t.Errorf("got caret %v, expected %v", ti.Caret, test.exp)
+			}
+			if ti.Text != "text" || ti.Cursor != 1 || ti.Anchor != 3 {
+				t.Errorf("got %+v", ti)
+			}
+		})
+	}
+}
+
+func TestTextInputFocus(t *testing.T) {
+	ops := new(ui.Ops)
+	h1, h2 := new(int), new(int)
+	key.InputOp{Key: h1}.Add(ops)
+	key.TextInputOp{Key: h1, Text: "h1"}.Add(ops)
+	key.InputOp{Key: h2, Focus: true}.Add(ops)
+	key.TextInputOp{Key: h2, Text: "h2"}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	if ti, ok := r.TextInput(); !ok || ti.Text != "h2" {
+		t.Errorf("got %+v, expected the TextInput of the focused handler", ti)
+	}
+	ops.Reset()
+	key.InputOp{Key: h1, Focus: true}.Add(ops)
+	r.Frame(ops)
+	if ti, ok := r.TextInput(); ok {
+		t.Errorf("got %+v for a focused handler without a TextInputOp", ti)
+	}
+}
+
+func TestFocusOrder(t *testing.T) {
+	a, b, c, d, e := new(int), new(int), new(int), new(int), new(int)
+	ops := new(ui.Ops)
+	key.InputOp{Key: a}.Add(ops)
+	key.InputOp{Key: b, TabIndex: 2}.Add(ops)
+	key.InputOp{Key: c, TabIndex: -1}.Add(ops)
+	key.InputOp{Key: d, TabIndex: 1}.Add(ops)
+	key.InputOp{Key: e}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	keys := []input.Key{a, b, c, d, e}
+	// The earliest handler has the default focus.
+	assertFocus(t, &r, keys, a)
+	for _, exp := range []input.Key{e, d, b, a, e} {
+		r.Add(key.Event{Name: key.NameTab, State: key.Press})
+		r.Add(key.Event{Name: key.NameTab, State: key.Release})
+		assertFocus(t, &r, keys, exp)
+	}
+	for _, exp := range []input.Key{a, b, d, e} {
+		r.Add(key.Event{Name: key.NameTab, Modifiers: key.ModShift, State: key.Press})
+		assertFocus(t, &r, keys, exp)
+	}
+	// The focus survives a frame with the same handlers.
+	r.Frame(ops)
+	assertFocus(t, &r, keys, e)
+}
+
+func TestFocusTab(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	ops := new(ui.Ops)
+	key.InputOp{Key: h1, Tab: true}.Add(ops)
+	key.InputOp{Key: h2}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	keys := []input.Key{h1, h2}
+	keyEvents(&r, h1)
+	tab := key.Event{Name: key.NameTab, State: key.Press}
+	r.Add(tab)
+	if evts := keyEvents(&r, h1); len(evts) != 1 || evts[0] != tab {
+		t.Errorf("got events %v, expected Tab", evts)
+	}
+	assertFocus(t, &r, keys, h1)
+
+	// Handlers without Tab don't receive it.
+	ops.Reset()
+	key.InputOp{Key: h1, Tab: true}.Add(ops)
+	key.InputOp{Key: h2, Focus: true}.Add(ops)
+	r.Frame(ops)
+	keyEvents(&r, h1)
+	keyEvents(&r, h2)
+	r.Add(tab)
+	if evts := keyEvents(&r, h2); len(evts) != 1 || evts[0] != (key.FocusEvent{Focus: false}) {
+		t.Errorf("got events %v, expected a focus loss", evts)
+	}
+	assertFocus(t, &r, keys, h1)
+}
+
+func TestFocusOp(t *testing.T) {
+	h1, h2, h3 := new(int), new(int), new(int)
+	keys := []input.Key{h1, h2, h3}
+	ops := new(ui.Ops)
+	key.InputOp{Key: h1}.Add(ops)
+	key.InputOp{Key: h2}.Add(ops)
+	key.FocusOp{Key: h2}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	assertFocus(t, &r, keys, h2)
+	if s := r.TextInputState(); s != TextInputOpen {
+		t.Errorf("got text input state %v after a FocusOp, expected TextInputOpen", s)
+	}
+
+	// FocusOps for keys without an InputOp are ignored.
+	ops.Reset()
+	key.InputOp{Key: h1}.Add(ops)
+	key.InputOp{Key: h2}.Add(ops)
+	key.FocusOp{Key: h3}.Add(ops)
+	r.Frame(ops)
+	assertFocus(t, &r, keys, h2)
+	if s := r.TextInputState(); s != TextInputKeep {
+		t.Errorf("got text input state %v, expected TextInputKeep", s)
+	}
+}
+
+func TestMoveFocusOp(t *testing.T) {
+	h1, h2, h3 := new(int), new(int), new(int)
+	keys := []input.Key{h1, h2, h3}
+	frame := func(r *Router, dir key.FocusDirection) {
+		ops := new(ui.Ops)
+		key.InputOp{Key: h1}.Add(ops)
+		key.InputOp{Key: h2}.Add(ops)
+		key.InputOp{Key: h3}.Add(ops)
+		key.MoveFocusOp{Dir: dir}.Add(ops)
+		r.Frame(ops)
+	}
+	var r Router
+	frame(&r, key.FocusNext)
+	assertFocus(t, &r, keys, h2)
+	frame(&r, key.FocusNext)
+	assertFocus(t, &r, keys, h3)
+	frame(&r, key.FocusNext)
+	assertFocus(t, &r, keys, h1)
+	frame(&r, key.FocusPrevious)
+	assertFocus(t, &r, keys, h3)
+}
+
+func TestFocusTabOpensTextInput(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	ops := new(ui.Ops)
+	key.InputOp{Key: h1, Focus: true}.Add(ops)
+	key.InputOp{Key: h2}.Add(ops)
+	key.TextInputOp{Key: h2, Text: "h2"}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	ops.Reset()
+	key.InputOp{Key: h1}.Add(ops)
+	key.InputOp{Key: h2}.Add(ops)
+	key.TextInputOp{Key: h2, Text: "h2"}.Add(ops)
+	r.Frame(ops)
+	if s := r.TextInputState(); s != TextInputKeep {
+		t.Fatalf("got text input state %v, expected TextInputKeep", s)
+	}
+	r.Add(key.Event{Name: key.NameTab, State: key.Press})
+	r.Frame(ops)
+	if s := r.TextInputState(); s != TextInputOpen {
+		t.Errorf("got text input state %v after Tab, expected TextInputOpen", s)
+	}
+	if ti, ok := r.TextInput(); !ok || ti.Text != "h2" {
+		t.Errorf("got %+v, expected the TextInput of h2", ti)
+	}
+	// The state is only refreshed once.
+	r.Frame(ops)
+	if s := r.TextInputState(); s != TextInputKeep {
+		t.Errorf("got text input state %v, expected TextInputKeep", s)
+	}
+}
+
+// assertFocus checks that exp is the focused handler among
+// keys by sending it an EditEvent. The events of keys are
+// discarded.
+func assertFocus(t *testing.T, r *Router, keys []input.Key, exp input.Key) {
+	t.Helper()
+	for _, k := range keys {
+		keyEvents(r, k)
+	}
+	probe := key.EditEvent{Text: "probe"}
+	r.Add(probe)
+	for _, k := range keys {
+		got := false
+		for _, e := range keyEvents(r, k) {
+			got = got || e == probe
+		}
+		if got != (k == exp) {
+			t.Errorf("focus: handler %p received EditEvent: %v, expected focus on %p", k, got, exp)
+		}
+	}
+}
+
+func keyEvents(r *Router, k input.Key) []input.Event {
+	var evts []input.Event
+	for e, ok := r.Next(k); ok; e, ok = r.Next(k) {
+		evts = append(evts, e)
+	}
+	return evts
+}
+
+func TestShortcutScope(t *testing.T) {
+	app, panel, other, h := new(int), new(int), new(int), new(int)
+	save := key.Event{Name: 'S', Modifiers: key.ModCtrl, State: key.Press}
+	frame := func(r *Router, focus bool) {
+		ops := new(ui.Ops)
+		var stack ui.StackOp
+		stack.Push(ops)
+		key.ShortcutOp{Key: app, Name: 'S', Modifiers: key.ModCtrl}.Add(ops)
+		var stack2 ui.StackOp
+		stack2.Push(ops)
+		key.ShortcutOp{Key: panel, Name: 'S', Modifiers: key.ModCtrl}.Add(ops)
+		if focus {
+			key.InputOp{Key: h}.Add(ops)
+		}
+		stack2.Pop()
+		stack2.Push(ops)
+		key.ShortcutOp{Key: other, Name: 'S', Modifiers: key.ModCtrl}.Add(ops)
+		stack2.Pop()
+		stack.Pop()
+		r.Frame(ops)
+	}
+	tests := []struct {
+		name  string
+		focus bool
+		exp   input.Key
+	}{
+		// The innermost shortcut around the focus wins.
+		{"focus", true, panel},
+		// Without focus, the last of the innermost shortcuts wins.
+		{"no focus", false, other},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var r Router
+			frame(&r, test.focus)
+			keyEvents(&r, h)
+			r.Add(save)
+			for _, k := range []input.Key{app, panel, other, h} {
+				evts := keyEvents(&r, k)
+				if got := len(evts) > 0; got != (k == test.exp) {
+					t.Errorf("handler %p got events %v, expected the shortcut at %p", k, evts, test.exp)
+				}
+			}
+		})
+	}
+}
+
+func TestShortcutRelease(t *testing.T) {
+	s, h := new(int), new(int)
+	ops := new(ui.Ops)
+	key.InputOp{Key: h}.Add(ops)
+	key.ShortcutOp{Key: s, Name: 'S', Modifiers: key.ModCtrl}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	keyEvents(&r, h)
+	press := key.Event{Name: 'S', Modifiers: key.ModCtrl, State: key.Press}
+	// The modifier may be released before the key.
+	release := key.Event{Name: 'S', State: key.Release}
+	r.Add(press)
+	r.Add(release)
+	if evts := keyEvents(&r, s); len(evts) != 2 || evts[0] != press || evts[1] != release {
+		t.Errorf("got shortcut events %v, expected the press and release", evts)
+	}
+	if evts := keyEvents(&r, h); len(evts) != 0 {
+		t.Errorf("got focus events %v, expected none", evts)
+	}
+	// Later releases go to the focus.
+	r.Add(release)
+	if evts := keyEvents(&r, h); len(evts) != 1 || evts[0] != release {
+		t.Errorf("got focus events %v, expected the release", evts)
+	}
+}
+
+func TestShortcutPass(t *testing.T) {
+	s, h := new(int), new(int)
+	ops := new(ui.Ops)
+	key.InputOp{Key: h}.Add(ops)
+	key.ShortcutOp{Key: s, Name: 'S', Pass: true}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	keyEvents(&r, h)
+	r.Add(key.Event{Name: 'S', State: key.Press})
+	r.Add(key.Event{Name: 'S', State: key.Release})
+	for _, k := range []input.Key{s, h} {
+		if evts := keyEvents(&r, k); len(evts) != 2 {
+			t.Errorf("handler %p got events %v, expected the press and release", k, evts)
+		}
+	}
+}
@@ -21,6 +21,8 @@ type pointerQueue struct {
 	pointers []pointerInfo
 	reader   ops.Reader
 	scratch  []input.Key
+	// hits is scratch space for hover tracking.
+	hits []input.Key
 }
 
 type hitNode struct {
@@ -37,6 +39,11 @@ type pointerInfo struct {
 	id       pointer.ID
 	pressed  bool
 	handlers []input.Key
+	// entered tracks the handlers the pointer is
+	// hovering.
+	entered []input.Key
+	// last is the most recent event for the pointer.
+	last pointer.Event
 }
 
 type pointerHandler struct {
@@ -171,25 +178,90 @@ func (q *pointerQueue) Frame(root *ui.Ops, events *handlerEvents) {
 	for k, h := range q.handlers {
 		if !h.active {
 			q.dropHandler(k)
+			for i := range q.pointers {
+				p := &q.pointers[i]
+				p.entered = removeKey(p.entered, k)
+			}
 			delete(q.handlers, k)
 		}
 	}
+	// The handlers may have moved under the pointers.
+	for i := range q.pointers {
+		p := &q.pointers[i]
+		q.hits = q.hits[:0]
+		q.opHit(&q.hits, p.last.Position)
+		q.deliverEnterLeave(p, q.hits, p.last, events)
+	}
 }
 
 func (q *pointerQueue) dropHandler(k input.Key) {
 	for i := range q.pointers {
 		p := &q.pointers[i]
-		for i := len(p.handlers) - 1; i >= 0; i-- {
-			if p.handlers[i] == k {
-				p.handlers = append(p.handlers[:i], p.handlers[i+1:]...)
-			}
+		p.handlers = removeKey(p.handlers, k)
+	}
+}
+
+// deliverEnterLeave updates the handlers entered by p to
+// hits. Handlers no longer hit receive a Leave event and
+// newly hit handlers receive an Enter event, both derived
+// from e.
+func (q *pointerQueue) deliverEnterLeave(p *pointerInfo, hits []input.Key, e pointer.Event, events *handlerEvents) {
+	entered := p.entered[:0]
+	for _, k := range p.entered {
+		if containsKey(hits, k) {
+			entered = append(entered, k)
+			continue
+		}
+		if h, ok := q.handlers[k]; ok {
+			e := e
+			e.Type = pointer.Leave
+			e.Priority = pointer.Shared
+			e.Hit = false
+			e.Position = h.transform.Invert().Transform(e.Position)
+			events.Add(k, e)
 		}
 	}
+	p.entered = entered
+	for _, k := range hits {
+		if containsKey(p.entered, k) {
+			continue
+		}
+		p.entered = append(p.entered, k)
+		h := q.handlers[k]
+		e := e
+		e.Type = pointer.Enter
+		e.Priority = pointer.Shared
+		e.Hit = true
+		e.Position = h.transform.Invert().Transform(e.Position)
+		events.Add(k, e)
+	}
+}
+
+func containsKey(keys []input.Key, k input.Key) bool {
+	for _, k2 := range keys {
+		if k2 == k {
+			return true
+		}
+	}
+	return false
+}
+
+// removeKey removes all occurrences of k from keys.
+func removeKey(keys []input.Key, k input.Key) []input.Key {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i] == k {
+			keys = append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
 }
 
 func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 	q.init()
 	if e.Type == pointer.Cancel {
+		for i := range q.pointers {
+			q.deliverEnterLeave(&q.pointers[i], nil, q.pointers[i].last, events)
+		}
 		q.pointers = q.pointers[:0]
 		for k := range q.handlers {
 			q.dropHandler(k)
@@ -208,6 +280,14 @@ func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 		pidx = len(q.pointers) - 1
 	}
 	p := &q.pointers[pidx]
+	p.last = e
+	// Touch pointers leave their handlers after the release.
+	touchUp := e.Type == pointer.Release && e.Source == pointer.Touch
+	if !touchUp {
+		q.hits = q.hits[:0]
+		q.opHit(&q.hits, e.Position)
+		q.deliverEnterLeave(p, q.hits, e, events)
+	}
 	if !p.pressed && (e.Type == pointer.Move || e.Type == pointer.Press) {
 		p.handlers, q.scratch = q.scratch[:0], p.handlers
 		q.opHit(&p.handlers, e.Position)
@@ -231,14 +311,15 @@ func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 			q.dropHandler(k)
 		}
 	}
+	pressed := p.pressed
 	if e.Type == pointer.Release {
-		q.pointers = append(q.pointers[:pidx], q.pointers[pidx+1:]...)
+		p.pressed = false
 	}
 	for i, k := range p.handlers {
 		h := q.handlers[k]
 		e := e
 		switch {
-		case p.pressed && len(p.handlers) == 1:
+		case pressed && len(p.handlers) == 1:
 			e.Priority = pointer.Grabbed
 		case i == 0:
 			e.Priority = pointer.Foremost
@@ -259,6 +340,10 @@ func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 			}
 		}
 	}
+	if touchUp {
+		q.deliverEnterLeave(p, nil, e, events)
+		q.pointers = append(q.pointers[:pidx], q.pointers[pidx+1:]...)
+	}
 }
 
 func (op *areaOp) Decode(d []byte) {
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/pointer"
+)
+
+func TestPointerEnterLeave(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	var r Router
+	r.Frame(twoAreas(h1, h2))
+	// Drain the initial Cancel events.
+	pointerTypes(&r, h1)
+	pointerTypes(&r, h2)
+
+	r.Add(mouseMove(5, 5))
+	assertPointerTypes(t, &r, h1, pointer.Enter, pointer.Move)
+	assertPointerTypes(t, &r, h2)
+
+	r.Add(mouseMove(6, 5))
+	assertPointerTypes(t, &r, h1, pointer.Move)
+
+	r.Add(mouseMove(25, 5))
+	assertPointerTypes(t, &r, h1, pointer.Leave)
+	assertPointerTypes(t, &r, h2, pointer.Enter, pointer.Move)
+
+	// Leaving every handler.
+	r.Add(mouseMove(50, 50))
+	assertPointerTypes(t, &r, h2, pointer.Leave)
+}
+
+func TestPointerEnterLeavePosition(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	var r Router
+	r.Frame(twoAreas(h1, h2))
+	pointerTypes(&r, h2)
+	r.Add(mouseMove(25, 5))
+	evts := pointerEvents(&r, h2)
+	if len(evts) == 0 || evts[0].Type != pointer.Enter {
+		t.Fatalf("got %v, expected an Enter event", evts)
+	}
+	// The position is in the handler's coordinates.
+	if exp := (f32.Point{X: 5, Y: 5}); evts[0].Position != exp {
+		t.Errorf("got Enter position %v, expected %v", evts[0].Position, exp)
+	}
+}
+
+func TestPointerEnterLeaveFrame(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	var r Router
+	r.Frame(twoAreas(h1, h2))
+	r.Add(mouseMove(5, 5))
+	pointerTypes(&r, h1)
+
+	// Move h2 under the pointer and h1 away from it.
+	ops := new(ui.Ops)
+	addArea(ops, h2, image.Rect(0, 0, 10, 10))
+	addArea(ops, h1, image.Rect(20, 0, 30, 10))
+	r.Frame(ops)
+	assertPointerTypes(t, &r, h1, pointer.Leave)
+	assertPointerTypes(t, &r, h2, pointer.Enter)
+
+	// Removing a handler doesn't send Leave events to
+	// the remaining handlers.
+	ops.Reset()
+	addArea(ops, h2, image.Rect(0, 0, 10, 10))
+	r.Frame(ops)
+	assertPointerTypes(t, &r, h2)
+}
+
+func TestPointerCancelLeaves(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	var r Router
+	r.Frame(twoAreas(h1, h2))
+	r.Add(mouseMove(5, 5))
+	pointerTypes(&r, h1)
+	r.Add(pointer.Event{Type: pointer.Cancel})
+	assertPointerTypes(t, &r, h1, pointer.Leave)
+	// The pointer is forgotten.
+	r.Frame(twoAreas(h1, h2))
+	assertPointerTypes(t, &r, h1)
+}
+
+func TestPointerTouchRelease(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	var r Router
+	r.Frame(twoAreas(h1, h2))
+	pointerTypes(&r, h1)
+	r.Add(pointer.Event{Type: pointer.Press, Source: pointer.Touch, Position: f32.Point{X: 5, Y: 5}})
+	assertPointerTypes(t, &r, h1, pointer.Enter, pointer.Press)
+	r.Add(pointer.Event{Type: pointer.Release, Source: pointer.Touch, Position: f32.Point{X: 5, Y: 5}})
+	assertPointerTypes(t, &r, h1, pointer.Release, pointer.Leave)
+	// The touch pointer is removed after the release.
+	r.Frame(twoAreas(h1, h2))
+	assertPointerTypes(t, &r, h1)
+}
+
+func TestPointerMouseRelease(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	var r Router
+	r.Frame(twoAreas(h1, h2))
+	pointerTypes(&r, h1)
+	r.Add(pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Point{X: 5, Y: 5}})
+	assertPointerTypes(t, &r, h1, pointer.Enter, pointer.Press)
+	r.Add(pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Position: f32.Point{X: 5, Y: 5}})
+	// A mouse keeps hovering after the release.
+	assertPointerTypes(t, &r, h1, pointer.Release)
+	r.Add(mouseMove(6, 5))
+	assertPointerTypes(t, &r, h1, pointer.Move)
+}
+
+// twoAreas returns ops with h1 covering (0,0)-(10,10) and h2
+// covering (20,0)-(30,10).
+func twoAreas(h1, h2 input.Key) *ui.Ops {
+	ops := new(ui.Ops)
+	addArea(ops, h1, image.Rect(0, 0, 10, 10))
+	var stack ui.StackOp
+	stack.Push(ops)
+	ui.TransformOp{}.Offset(f32.Point{X: 20}).Add(ops)
+	addArea(ops, h2, image.Rect(0, 0, 10, 10))
+	stack.Pop()
+	return ops
+}
+
+func addArea(ops *ui.Ops, k input.Key, r image.Rectangle) {
+	var stack ui.StackOp
+	stack.Push(ops)
+	pointer.RectAreaOp{Rect: r}.Add(ops)
+	pointer.InputOp{Key: k}.Add(ops)
+	stack.Pop()
+}
+
+func mouseMove(x, y float32) pointer.Event {
+	return pointer.Event{
+		Type:     pointer.Move,
+		Source:   pointer.Mouse,
+		Position: f32.Point{X: x, Y: y},
+	}
+}
+
+func pointerEvents(r *Router, k input.Key) []pointer.Event {
+	var evts []pointer.Event
+	for e, ok := r.Next(k); ok; e, ok = r.Next(k) {
+		if e, ok := e.(pointer.Event); ok {
+			evts = append(evts, e)
+		}
+	}
+	return evts
+}
+
+func pointerTypes(r *Router, k input.Key) []pointer.Type {
+	var types []pointer.Type
+	for _, e := range pointerEvents(r, k) {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func assertPointerTypes(t *testing.T, r *Router, k input.Key, exp ...pointer.Type) {
+	t.Helper()
+	got := pointerTypes(r, k)
+	if len(got) == 0 && len(exp) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got pointer events %v, expected %v", got, exp)
+	}
+}
@@ -61,7 +61,7 @@ func (q *Router) Add(e input.Event) bool {
 	switch e := e.(type) {
 	case pointer.Event:
 		q.pqueue.Push(e, &q.handlers)
-	case key.EditEvent, key.Event, key.FocusEvent:
+	case key.EditEvent, key.Event, key.FocusEvent, key.PreeditEvent:
 		q.kqueue.Push(e, &q.handlers)
 	}
 	return q.handlers.Updated()
@@ -71,6 +71,12 @@ func (q *Router) TextInputState() TextInputState {
 	return q.kqueue.InputState()
 }
 
+// TextInput returns the caret and surrounding text of the
+// focused handler, if it added a key.TextInputOp.
+func (q *Router) TextInput() (TextInput, bool) {
+	return q.kqueue.TextInput()
+}
+
 func (q *Router) collect() {
 	for encOp, ok := q.reader.Decode(); ok; encOp, ok = q.reader.Decode() {
 		switch opconst.OpType(encOp.Data[0]) {
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package textinput implements the client side of the Wayland
+text-input-v3 protocol, independent of the wire format.
+
+A State tracks the text input focus and the double buffered
+state of the protocol. Requests are issued through the Requests
+interface and protocol events are converted to key.EditEvent and
+key.PreeditEvent events.
+*/
+package textinput
+
+import (
+	"image"
+	"unicode/utf8"
+
+	iinput "gioui.org/ui/app/internal/input"
+	"gioui.org/ui/input"
+	"gioui.org/ui/key"
+)
+
+// Requests is the set of text-input-v3 requests used
+// by State.
+type Requests interface {
+	Enable()
+	Disable()
+	// SetSurroundingText sets the text around the cursor.
+	// The cursor and anchor are byte offsets in text.
+	SetSurroundingText(text string, cursor, anchor int)
+	// SetCursorRectangle sets the caret rectangle, in
+	// surface local coordinates.
+	SetCursorRectangle(r image.Rectangle)
+	Commit()
+}
+
+// State is the client state of a text-input-v3 object.
+type State struct {
+	req Requests
+	// entered is set when the text input has entered
+	// a surface.
+	entered bool
+	// show is set when a text handler is focused.
+	show    bool
+	enabled bool
+	input   iinput.TextInput
+	// preedit is the preedit text after the last done
+	// event.
+	preedit key.PreeditEvent
+	pending pendingState
+}
+
+// pendingState is the state accumulated by the events
+// before a done event.
+type pendingState struct {
+	preedit      key.PreeditEvent
+	commit       string
+	deleteBefore int
+	deleteAfter  int
+}
+
+// maxSurroundingText is the maximum length in bytes of
+// the surrounding text. The Wayland wire format limits
+// messages to 4096 bytes.
+const maxSurroundingText = 4000
+
+// New creates a State that issues requests to req.
+func New(req Requests) *State {
+	return &State{req: req}
+}
+
+// Enter is called for the enter event.
+func (s *State) Enter() {
+	s.entered = true
+	s.update()
+}
+
+// Leave is called for the leave event. Leave returns the
+// events that end an active composition.
+func (s *State) Leave() []input.Event {
+	s.entered = false
+	s.update()
+	s.pending = pendingState{}
+	if s.preedit == (key.PreeditEvent{}) {
+		return nil
+	}
+	s.preedit = key.PreeditEvent{}
+	return []input.Event{key.PreeditEvent{}}
+}
+
+// Show enables or disables text input for the focused
+// text handler.
+func (s *State) Show(show bool) {
+	s.show = show
+	s.update()
+}
+
+// Update sets the caret rectangle and surrounding text of the
+// focused text handler.
+func (s *State) Update(ti iinput.TextInput) {
+	s.input = ti
+	if s.enabled {
+		s.sendState()
+		s.req.Commit()
+	}
+}
+
+// PreeditString is called for the preedit_string event.
+func (s *State) PreeditString(text string, begin, end int) {
+	s.pending.preedit = key.PreeditEvent{Text: text, Begin: begin, End: end}
+}
+
+// CommitString is called for the commit_string event.
+func (s *State) CommitString(text string) {
+	s.pending.commit = text
+}
+
+// DeleteSurroundingText is called for the delete_surrounding_text
+// event.
+func (s *State) DeleteSurroundingText(before, after int) {
+	s.pending.deleteBefore = before
+	s.pending.deleteAfter = after
+}
+
+// Done is called for the done event and returns the events
+// that apply the pending state. The protocol requires the
+// pending state to be applied even if serial doesn't match
+// the number of commits, so serial is ignored.
+func (s *State) Done(serial uint32) []input.Event {
+	p := s.pending
+	s.pending = pendingState{}
+	var events []input.Event
+	edit := p.commit != "" || p.deleteBefore != 0 || p.deleteAfter != 0
+	if edit {
+		events = append(events, key.EditEvent{
+			Text:         p.commit,
+			DeleteBefore: p.deleteBefore,
+			DeleteAfter:  p.deleteAfter,
+		})
+	}
+	// An EditEvent replaces the preedit text, so the new
+	// preedit text must be sent again.
+	if p.preedit != s.preedit || (edit && p.preedit.Text != "") {
+		events = append(events, p.preedit)
+	}
+	s.preedit = p.preedit
+	return events
+}
+
+// update enables or disables the text input according
+// to the focus state.
+func (s *State) update() {
+	enable := s.entered && s.show
+	if enable == s.enabled {
+		return
+	}
+	s.enabled = enable
+	if enable {
+		s.req.Enable()
+		// Enable resets the text input state.
+		s.sendState()
+	} else {
+		s.req.Disable()
+	}
+	s.req.Commit()
+}
+
+func (s *State) sendState() {
+	text, cursor, anchor := clampText(s.input.Text, s.input.Cursor, s.input.Anchor)
+	s.req.SetSurroundingText(text, cursor, anchor)
+	s.req.SetCursorRectangle(s.input.Caret)
+}
+
+// clampText returns the part of text around cursor that fits
+// the maximum surrounding text length, and the cursor and
+// anchor offsets adjusted to it.
+func clampText(text string, cursor, anchor int) (string, int, int) {
+	cursor = clamp(cursor, 0, len(text))
+	anchor = clamp(anchor, 0, len(text))
+	if len(text) <= maxSurroundingText {
+		return text, cursor, anchor
+	}
+	start := clamp(cursor-maxSurroundingText/2, 0, len(text)-maxSurroundingText)
+	end := start + maxSurroundingText
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	text = text[start:end]
+	return text, clamp(cursor-start, 0, len(text)), clamp(anchor-start, 0, len(text))
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package textinput
+
+import (
+	"fmt"
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+
+	iinput "gioui.org/ui/app/internal/input"
+	"gioui.org/ui/input"
+	"gioui.org/ui/key"
+)
+
+// compositor is a stand-in for a Wayland compositor. It
+// applies the requests of a State on Commit and records
+// them.
+type compositor struct {
+	requests []string
+	pending  compositorState
+	// current is the committed state.
+	current compositorState
+	serial  uint32
+}
+
+type compositorState struct {
+	enabled        bool
+	text           string
+	cursor, anchor int
+	caret          image.Rectangle
+}
+
+func (c *compositor) Enable() {
+	c.requests = append(c.requests, "enable")
+	// Enable resets the state.
+	c.pending = compositorState{enabled: true}
+}
+
+func (c *compositor) Disable() {
+	c.requests = append(c.requests, "disable")
+	c.pending.enabled = false
+}
+
+func (c *compositor) SetSurroundingText(text string, cursor, anchor int) {
+	c.requests = append(c.requests, fmt.Sprintf("surrounding(%d,%d)", cursor, anchor))
+	c.pending.text, c.pending.cursor, c.pending.anchor = text, cursor, anchor
+}
+
+func (c *compositor) SetCursorRectangle(r image.Rectangle) {
+	c.requests = append(c.requests, fmt.Sprintf("cursor%v", r))
+	c.pending.caret = r
+}
+
+func (c *compositor) Commit() {
+	c.requests = append(c.requests, "commit")
+	c.current = c.pending
+	c.serial++
+}
+
+// reset returns and clears the recorded requests.
+func (c *compositor) reset() string {
+	r := strings.Join(c.requests, " ")
+	c.requests = nil
+	return r
+}
+
+func TestEnable(t *testing.T) {
+	c := new(compositor)
+	s := New(c)
+	ti := iinput.TextInput{
+		Caret:  image.Rect(10, 20, 11, 40),
+		Text:   "hello",
+		Cursor: 5,
+		Anchor: 5,
+	}
+	s.Update(ti)
+	s.Enter()
+	if r := c.reset(); r != "" {
+		t.Errorf("got requests %q without a focused handler", r)
+	}
+	s.Show(true)
+	if got, exp := c.reset(), "enable surrounding(5,5) cursor(10,20)-(11,40) commit"; got != exp {
+		t.Errorf("got requests %q, expected %q", got, exp)
+	}
+	if !c.current.enabled || c.current.text != "hello" {
+		t.Errorf("got compositor state %+v", c.current)
+	}
+	ti.Cursor, ti.Anchor = 1, 3
+	s.Update(ti)
+	if got, exp := c.reset(), "surrounding(1,3) cursor(10,20)-(11,40) commit"; got != exp {
+		t.Errorf("got requests %q, expected %q", got, exp)
+	}
+	s.Show(false)
+	if got, exp := c.reset(), "disable commit"; got != exp {
+		t.Errorf("got requests %q, expected %q", got, exp)
+	}
+	s.Show(true)
+	c.reset()
+	s.Leave()
+	if got, exp := c.reset(), "disable commit"; got != exp {
+		t.Errorf("got requests %q, expected %q", got, exp)
+	}
+	if c.current.enabled {
+		t.Error("text input enabled after leave")
+	}
+}
+
+func TestComposition(t *testing.T) {
+	c := new(compositor)
+	s := New(c)
+	s.Enter()
+	s.Show(true)
+
+	s.PreeditString("ka", 2, 2)
+	assertEvents(t, s.Done(c.serial), key.PreeditEvent{Text: "ka", Begin: 2, End: 2})
+
+	s.PreeditString("か", 0, 3)
+	assertEvents(t, s.Done(c.serial), key.PreeditEvent{Text: "か", Begin: 0, End: 3})
+
+	// Done without changes.
+	s.PreeditString("か", 0, 3)
+	assertEvents(t, s.Done(c.serial))
+
+	// Commit ends the composition.
+	s.CommitString("か")
+	assertEvents(t, s.Done(c.serial),
+		key.EditEvent{Text: "か"},
+		key.PreeditEvent{},
+	)
+
+	// Commit followed by a new composition.
+	s.PreeditString("a", 1, 1)
+	s.Done(c.serial)
+	s.CommitString("あ")
+	s.PreeditString("k", 1, 1)
+	assertEvents(t, s.Done(c.serial),
+		key.EditEvent{Text: "あ"},
+		key.PreeditEvent{Text: "k", Begin: 1, End: 1},
+	)
+
+	s.DeleteSurroundingText(3, 0)
+	s.CommitString("ア")
+	assertEvents(t, s.Done(c.serial),
+		key.EditEvent{Text: "ア", DeleteBefore: 3},
+		key.PreeditEvent{},
+	)
+
+	// Leave ends the composition.
+	s.PreeditString("x", -1, -1)
+	s.Done(c.serial)
+	assertEvents(t, s.Leave(), key.PreeditEvent{})
+	assertEvents(t, s.Leave())
+}
+
+func TestDoneWithStaleSerial(t *testing.T) {
+	c := new(compositor)
+	s := New(c)
+	s.Enter()
+	s.Show(true)
+	s.CommitString("a")
+	assertEvents(t, s.Done(c.serial-1), key.EditEvent{Text: "a"})
+}
+
+func TestSurroundingTextLimit(t *testing.T) {
+	c := new(compositor)
+	s := New(c)
+	text := strings.Repeat("é", 3000)
+	s.Update(iinput.TextInput{Text: text, Cursor: 4000, Anchor: 0})
+	s.Enter()
+	s.Show(true)
+	got := c.current.text
+	if len(got) > maxSurroundingText {
+		t.Fatalf("surrounding text length %d exceeds the maximum %d", len(got), maxSurroundingText)
+	}
+	if !strings.HasPrefix(got, "é") || !strings.HasSuffix(got, "é") {
+		t.Errorf("surrounding text not clamped at rune boundaries")
+	}
+	if start := 4000 - c.current.cursor; start < 0 || !strings.HasPrefix(text[start:], got) {
+		t.Errorf("cursor %d doesn't match the surrounding text", c.current.cursor)
+	}
+	if c.current.anchor != 0 {
+		t.Errorf("got anchor %d, expected 0", c.current.anchor)
+	}
+}
+
+func assertEvents(t *testing.T, got []input.Event, exp ...input.Event) {
+	t.Helper()
+	if len(got) == 0 && len(exp) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got events %v, expected %v", got, exp)
+	}
+}
@@ -72,12 +72,12 @@ JNIEXPORT jint JNI_OnLoad(JavaVM *vm, void *reserved) {
 		},
 		{
 			.name = "onTouchEvent",
-			.signature = "(JIIIFFJ)V",
+			.signature = "(JIIIIIFFJ)V",
 			.fnPtr = onTouchEvent
 		},
 		{
 			.name = "onKeyEvent",
-			.signature = "(JIIJ)V",
+			.signature = "(JIIIIIJ)V",
 			.fnPtr = onKeyEvent
 		},
 		{
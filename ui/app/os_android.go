@@ -25,6 +25,7 @@ import (
 	"unsafe"
 
 	"gioui.org/ui"
+	iinput "gioui.org/ui/app/internal/input"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -362,18 +363,28 @@ func convertKeyCode(code C.jint) (rune, bool) {
 }
 
 //export onKeyEvent
-func onKeyEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, keyCode, r C.jint, t C.jlong) {
+func onKeyEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, keyCode, r, action, repeat, meta C.jint, t C.jlong) {
 	w := views[handle]
+	ks := key.Press
+	if action == C.AKEY_EVENT_ACTION_UP {
+		ks = key.Release
+	}
 	if n, ok := convertKeyCode(keyCode); ok {
-		w.event(key.Event{Name: n})
+		w.event(key.Event{
+			Name:      n,
+			Modifiers: convertMeta(meta),
+			State:     ks,
+			Repeat:    repeat > 0,
+			Time:      time.Duration(t) * time.Millisecond,
+		})
 	}
-	if r != 0 {
+	if ks == key.Press && r != 0 {
 		w.event(key.EditEvent{Text: string(rune(r))})
 	}
 }
 
 //export onTouchEvent
-func onTouchEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, action, pointerID, tool C.jint, x, y C.jfloat, t C.jlong) {
+func onTouchEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, action, pointerID, tool, buttons, meta C.jint, x, y C.jfloat, t C.jlong) {
 	w := views[handle]
 	var typ pointer.Type
 	switch action {
@@ -397,15 +408,38 @@ func onTouchEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, action, pointer
 	default:
 		return
 	}
+	// The bits of MotionEvent.getButtonState match
+	// the order of pointer.Buttons.
 	w.event(pointer.Event{
 		Type:      typ,
 		Source:    src,
+		Buttons:   pointer.Buttons(buttons & 0x1f),
 		PointerID: pointer.ID(pointerID),
 		Time:      time.Duration(t) * time.Millisecond,
 		Position:  f32.Point{X: float32(x), Y: float32(y)},
+		Modifiers: convertMeta(meta),
 	})
 }
 
+// convertMeta converts an Android meta state to
+// key.Modifiers.
+func convertMeta(meta C.jint) key.Modifiers {
+	var mods key.Modifiers
+	if meta&C.AMETA_CTRL_ON != 0 {
+		mods |= key.ModCommand | key.ModCtrl
+	}
+	if meta&C.AMETA_SHIFT_ON != 0 {
+		mods |= key.ModShift
+	}
+	if meta&C.AMETA_ALT_ON != 0 {
+		mods |= key.ModAlt
+	}
+	if meta&C.AMETA_META_ON != 0 {
+		mods |= key.ModSuper
+	}
+	return mods
+}
+
 func (w *window) showTextInput(show bool) {
 	if w.view == 0 {
 		return
@@ -419,6 +453,8 @@ func (w *window) showTextInput(show bool) {
 	})
 }
 
+func (w *window) updateTextInput(ti iinput.TextInput) {}
+
 func main() {
 }
 
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"gioui.org/ui"
+	iinput "gioui.org/ui/app/internal/input"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -246,6 +247,8 @@ func (w *window) showTextInput(show bool) {
 	}
 }
 
+func (w *window) updateTextInput(ti iinput.TextInput) {}
+
 func createWindow(win *Window, opts *windowOptions) error {
 	mainWindow.in <- windowAndOptions{win, opts}
 	return <-mainWindow.errs
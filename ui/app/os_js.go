@@ -4,10 +4,12 @@ package app
 
 import (
 	"image"
+	"strconv"
 	"sync"
 	"syscall/js"
 	"time"
 
+	iinput "gioui.org/ui/app/internal/input"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -23,6 +25,8 @@ type window struct {
 	cleanfuncs            []func()
 	touches               []js.Value
 	composing             bool
+	// buttons are the pressed mouse buttons.
+	buttons pointer.Buttons
 
 	mu        sync.Mutex
 	scale     float32
@@ -122,6 +126,11 @@ func (w *window) addEventListeners() {
 		w.pointerEvent(pointer.Release, 0, 0, args[0])
 		return nil
 	})
+	w.addEventListener(w.cnv, "contextmenu", func(this js.Value, args []js.Value) interface{} {
+		// Deliver secondary button clicks to the program.
+		args[0].Call("preventDefault")
+		return nil
+	})
 	w.addEventListener(w.cnv, "wheel", func(this js.Value, args []js.Value) interface{} {
 		e := args[0]
 		dx, dy := e.Get("deltaX").Float(), e.Get("deltaY").Float()
@@ -170,7 +179,11 @@ func (w *window) addEventListeners() {
 		return nil
 	})
 	w.addEventListener(w.tarea, "keydown", func(this js.Value, args []js.Value) interface{} {
-		w.keyEvent(args[0])
+		w.keyEvent(args[0], key.Press)
+		return nil
+	})
+	w.addEventListener(w.tarea, "keyup", func(this js.Value, args []js.Value) interface{} {
+		w.keyEvent(args[0], key.Release)
 		return nil
 	})
 	w.addEventListener(w.tarea, "compositionstart", func(this js.Value, args []js.Value) interface{} {
@@ -205,23 +218,47 @@ func (w *window) focus() {
 	w.tarea.Call("focus")
 }
 
-func (w *window) keyEvent(e js.Value) {
+func (w *window) keyEvent(e js.Value, state key.State) {
 	k := e.Get("key").String()
 	if n, ok := translateKey(k); ok {
-		cmd := key.Event{Name: n}
-		if e.Call("getModifierState", "Control").Bool() {
-			cmd.Modifiers |= key.ModCommand
+		if n == key.NameTab {
+			// Move the focus within the program, not the page.
+			e.Call("preventDefault")
 		}
-		if e.Call("getModifierState", "Shift").Bool() {
-			cmd.Modifiers |= key.ModShift
+		cmd := key.Event{
+			Name:      n,
+			Modifiers: modifiersFor(e),
+			State:     state,
+			Repeat:    e.Get("repeat").Bool(),
+			Time:      time.Duration(e.Get("timeStamp").Int()) * time.Millisecond,
 		}
 		w.w.event(cmd)
 	}
 }
 
+// modifiersFor returns the modifiers of a keyboard,
+// mouse or touch event.
+func modifiersFor(e js.Value) key.Modifiers {
+	var mods key.Modifiers
+	if e.Get("ctrlKey").Bool() {
+		mods |= key.ModCommand | key.ModCtrl
+	}
+	if e.Get("shiftKey").Bool() {
+		mods |= key.ModShift
+	}
+	if e.Get("altKey").Bool() {
+		mods |= key.ModAlt
+	}
+	if e.Get("metaKey").Bool() {
+		mods |= key.ModSuper
+	}
+	return mods
+}
+
 func (w *window) touchEvent(typ pointer.Type, e js.Value) {
 	e.Call("preventDefault")
 	t := time.Duration(e.Get("timeStamp").Int()) * time.Millisecond
+	mods := modifiersFor(e)
 	changedTouches := e.Get("changedTouches")
 	n := changedTouches.Length()
 	rect := w.cnv.Call("getBoundingClientRect")
@@ -244,6 +281,7 @@ func (w *window) touchEvent(typ pointer.Type, e js.Value) {
 			Position:  pos,
 			PointerID: pid,
 			Time:      t,
+			Modifiers: mods,
 		})
 	}
 }
@@ -278,12 +316,25 @@ func (w *window) pointerEvent(typ pointer.Type, dx, dy float32, e js.Value) {
 		Y: dy * scale,
 	}
 	t := time.Duration(e.Get("timeStamp").Int()) * time.Millisecond
+	// The bits of MouseEvent.buttons match the order
+	// of pointer.Buttons.
+	btns := pointer.Buttons(e.Get("buttons").Int() & 0x1f)
+	// The pointer is pressed while any button is down.
+	switch {
+	case typ == pointer.Press && w.buttons != 0:
+		typ = pointer.Move
+	case typ == pointer.Release && btns != 0:
+		typ = pointer.Move
+	}
+	w.buttons = btns
 	w.w.event(pointer.Event{
-		Type:     typ,
-		Source:   pointer.Mouse,
-		Position: pos,
-		Scroll:   scroll,
-		Time:     t,
+		Type:      typ,
+		Source:    pointer.Mouse,
+		Buttons:   btns,
+		Position:  pos,
+		Scroll:    scroll,
+		Time:      t,
+		Modifiers: modifiersFor(e),
 	})
 }
 
@@ -336,6 +387,8 @@ func (w *window) showTextInput(show bool) {
 	}()
 }
 
+func (w *window) updateTextInput(ti iinput.TextInput) {}
+
 func (w *window) draw(sync bool) {
 	width, height, scale, cfg := w.config()
 	if cfg == (Config{}) {
@@ -414,6 +467,23 @@ func translateKey(k string) (rune, bool) {
 		n = key.NamePageUp
 	case "PageDown":
 		n = key.NamePageDown
+	case "Tab":
+		n = key.NameTab
+	case " ":
+		n = key.NameSpace
+	case "Insert":
+		n = key.NameInsert
+	case "Shift":
+		n = key.NameShift
+	case "Control":
+		n = key.NameCtrl
+	case "Alt":
+		n = key.NameAlt
+	case "Meta", "OS":
+		n = key.NameSuper
+	case "F1", "F2", "F3", "F4", "F5", "F6", "F7", "F8", "F9", "F10", "F11", "F12":
+		f, _ := strconv.Atoi(k[1:])
+		n = key.NameF1 + rune(f-1)
 	default:
 		return 0, false
 	}
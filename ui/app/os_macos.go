@@ -19,6 +19,7 @@ import (
 	"time"
 	"unsafe"
 
+	iinput "gioui.org/ui/app/internal/input"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -35,6 +36,8 @@ type window struct {
 	stage Stage
 	ppdp  float32
 	scale float32
+	// buttons are the pressed mouse buttons.
+	buttons pointer.Buttons
 }
 
 type viewCmd struct {
@@ -81,6 +84,8 @@ func (w *window) contextView() C.CFTypeRef {
 
 func (w *window) showTextInput(show bool) {}
 
+func (w *window) updateTextInput(ti iinput.TextInput) {}
+
 func (w *window) setAnimating(anim bool) {
 	var animb C.BOOL
 	if anim {
@@ -114,20 +119,25 @@ func gio_onFrameCallback(view C.CFTypeRef) {
 }
 
 //export gio_onKeys
-func gio_onKeys(view C.CFTypeRef, cstr *C.char, ti C.double, mods C.NSUInteger) {
+func gio_onKeys(view C.CFTypeRef, cstr *C.char, ti C.double, mods C.NSUInteger, keyDown, repeat C.BOOL) {
 	str := C.GoString(cstr)
-	var kmods key.Modifiers
-	if mods&C.NSEventModifierFlagCommand != 0 {
-		kmods |= key.ModCommand
-	}
-	if mods&C.NSEventModifierFlagShift != 0 {
-		kmods |= key.ModShift
+	kmods := convertMods(mods)
+	t := time.Duration(float64(ti)*float64(time.Second) + .5)
+	ks := key.Release
+	if keyDown == C.YES {
+		ks = key.Press
 	}
 	viewDo(view, func(views viewMap, view C.CFTypeRef) {
 		w := views[view]
 		for _, k := range str {
 			if n, ok := convertKey(k); ok {
-				w.w.event(key.Event{Name: n, Modifiers: kmods})
+				w.w.event(key.Event{
+					Name:      n,
+					Modifiers: kmods,
+					State:     ks,
+					Repeat:    repeat == C.YES,
+					Time:      t,
+				})
 			}
 		}
 	})
@@ -143,29 +153,40 @@ func gio_onText(view C.CFTypeRef, cstr *C.char) {
 }
 
 //export gio_onMouse
-func gio_onMouse(view C.CFTypeRef, cdir C.int, x, y, dx, dy C.CGFloat, ti C.double) {
-	var typ pointer.Type
-	switch cdir {
-	case C.GIO_MOUSE_MOVE:
-		typ = pointer.Move
-	case C.GIO_MOUSE_UP:
-		typ = pointer.Release
-	case C.GIO_MOUSE_DOWN:
-		typ = pointer.Press
-	default:
-		panic("invalid direction")
-	}
+func gio_onMouse(view C.CFTypeRef, cdir C.int, cbtns C.NSUInteger, x, y, dx, dy C.CGFloat, ti C.double, mods C.NSUInteger) {
+	// The bits of NSEvent.pressedMouseButtons match
+	// the order of pointer.Buttons.
+	btns := pointer.Buttons(cbtns & 0x1f)
+	kmods := convertMods(mods)
 	t := time.Duration(float64(ti)*float64(time.Second) + .5)
 	viewDo(view, func(views viewMap, view C.CFTypeRef) {
 		w := views[view]
+		// The pointer is pressed while any button is down.
+		typ := pointer.Move
+		switch cdir {
+		case C.GIO_MOUSE_MOVE:
+		case C.GIO_MOUSE_UP:
+			if btns == 0 {
+				typ = pointer.Release
+			}
+		case C.GIO_MOUSE_DOWN:
+			if w.buttons == 0 {
+				typ = pointer.Press
+			}
+		default:
+			panic("invalid direction")
+		}
+		w.buttons = btns
 		x, y := float32(x)*w.scale, float32(y)*w.scale
 		dx, dy := float32(dx)*w.scale, float32(dy)*w.scale
 		w.w.event(pointer.Event{
-			Type:     typ,
-			Source:   pointer.Mouse,
-			Time:     t,
-			Position: f32.Point{X: x, Y: y},
-			Scroll:   f32.Point{X: dx, Y: dy},
+			Type:      typ,
+			Source:    pointer.Mouse,
+			Buttons:   btns,
+			Time:      t,
+			Position:  f32.Point{X: x, Y: y},
+			Scroll:    f32.Point{X: dx, Y: dy},
+			Modifiers: kmods,
 		})
 	})
 }
@@ -187,6 +208,23 @@ func gio_onFocus(view C.CFTypeRef, focus C.BOOL) {
 	})
 }
 
+func convertMods(mods C.NSUInteger) key.Modifiers {
+	var kmods key.Modifiers
+	if mods&C.NSEventModifierFlagCommand != 0 {
+		kmods |= key.ModCommand | key.ModSuper
+	}
+	if mods&C.NSEventModifierFlagShift != 0 {
+		kmods |= key.ModShift
+	}
+	if mods&C.NSEventModifierFlagControl != 0 {
+		kmods |= key.ModCtrl
+	}
+	if mods&C.NSEventModifierFlagOption != 0 {
+		kmods |= key.ModAlt
+	}
+	return kmods
+}
+
 func (w *window) draw(sync bool) {
 	w.scale = float32(C.gio_getViewBackingScale(w.view))
 	wf, hf := float32(C.gio_viewWidth(w.view)), float32(C.gio_viewHeight(w.view))
@@ -299,6 +337,9 @@ func convertKey(k rune) (rune, bool) {
 	if 'a' <= k && k <= 'z' {
 		return k - 0x20, true
 	}
+	if C.NSF1FunctionKey <= k && k <= C.NSF12FunctionKey {
+		return key.NameF1 + k - C.NSF1FunctionKey, true
+	}
 	var n rune
 	switch k {
 	case 0x1b:
@@ -325,6 +366,12 @@ func convertKey(k rune) (rune, bool) {
 		n = key.NamePageUp
 	case C.NSPageDownFunctionKey:
 		n = key.NamePageDown
+	case 0x9, 0x19:
+		n = key.NameTab
+	case ' ':
+		n = key.NameSpace
+	case C.NSInsertFunctionKey:
+		n = key.NameInsert
 	default:
 		return 0, false
 	}
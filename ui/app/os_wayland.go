@@ -19,6 +19,8 @@ import (
 	"unicode/utf8"
 	"unsafe"
 
+	iinput "gioui.org/ui/app/internal/input"
+	"gioui.org/ui/app/internal/textinput"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -78,6 +80,10 @@ type wlConn struct {
 	utf8Buf      []byte
 
 	repeat repeatState
+
+	// imWindow is the window with the text input focus.
+	imWindow  *window
+	textInput *textinput.State
 }
 
 type repeatState struct {
@@ -111,6 +117,8 @@ type window struct {
 	scroll    f32.Point
 	lastPos   f32.Point
 	lastTouch f32.Point
+	// buttons are the pressed mouse buttons.
+	buttons pointer.Buttons
 
 	stage             Stage
 	dead              bool
@@ -125,6 +133,11 @@ type window struct {
 	height   int
 	newScale bool
 	scale    int
+	// The text input state waiting to be applied by
+	// the event loop.
+	textInput      iinput.TextInput
+	showInput      bool
+	textInputDirty bool
 }
 
 type wlOutput struct {
@@ -150,6 +163,8 @@ var (
 var (
 	_XKB_MOD_NAME_CTRL  = []byte("Control\x00")
 	_XKB_MOD_NAME_SHIFT = []byte("Shift\x00")
+	_XKB_MOD_NAME_ALT   = []byte("Mod1\x00")
+	_XKB_MOD_NAME_LOGO  = []byte("Mod4\x00")
 )
 
 func main() {
@@ -265,6 +280,7 @@ func gio_onSeatCapabilities(data unsafe.Pointer, seat *C.struct_wl_seat, caps C.
 	if conn.im == nil && conn.imm != nil {
 		conn.im = C.zwp_text_input_manager_v3_get_text_input(conn.imm, conn.seat)
 		C.gio_zwp_text_input_v3_add_listener(conn.im)
+		conn.textInput = textinput.New(wlTextInput{})
 	}
 	switch {
 	case conn.pointer == nil && caps&C.WL_SEAT_CAPABILITY_POINTER != 0:
@@ -409,9 +425,8 @@ func gio_onRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C
 		conn.wm = (*C.struct_xdg_wm_base)(C.wl_registry_bind(reg, name, &C.xdg_wm_base_interface, 1))
 	case "zxdg_decoration_manager_v1":
 		conn.decor = (*C.struct_zxdg_decoration_manager_v1)(C.wl_registry_bind(reg, name, &C.zxdg_decoration_manager_v1_interface, 1))
-		// TODO: Implement and test text-input support.
-		/*case "zwp_text_input_manager_v3":
-		conn.imm = (*C.struct_zwp_text_input_manager_v3)(C.wl_registry_bind(reg, name, &C.zwp_text_input_manager_v3_interface, 1))*/
+	case "zwp_text_input_manager_v3":
+		conn.imm = (*C.struct_zwp_text_input_manager_v3)(C.wl_registry_bind(reg, name, &C.zwp_text_input_manager_v3_interface, 1))
 	}
 }
 
@@ -421,6 +436,8 @@ func gio_onRegistryGlobalRemove(data unsafe.Pointer, reg *C.struct_wl_registry,
 		if conn.im != nil {
 			C.zwp_text_input_v3_destroy(conn.im)
 			conn.im = nil
+			conn.imWindow = nil
+			conn.textInput = nil
 		}
 		if conn.pointer != nil {
 			delete(winMap, conn.pointer)
@@ -452,6 +469,7 @@ func gio_onTouchDown(data unsafe.Pointer, touch *C.struct_wl_touch, serial, t C.
 		Position:  w.lastTouch,
 		PointerID: pointer.ID(id),
 		Time:      time.Duration(t) * time.Millisecond,
+		Modifiers: xkbModifiers(),
 	})
 }
 
@@ -464,6 +482,7 @@ func gio_onTouchUp(data unsafe.Pointer, touch *C.struct_wl_touch, serial, t C.ui
 		Position:  w.lastTouch,
 		PointerID: pointer.ID(id),
 		Time:      time.Duration(t) * time.Millisecond,
+		Modifiers: xkbModifiers(),
 	})
 }
 
@@ -477,6 +496,7 @@ func gio_onTouchMotion(data unsafe.Pointer, touch *C.struct_wl_touch, t C.uint32
 		Source:    pointer.Touch,
 		PointerID: pointer.ID(id),
 		Time:      time.Duration(t) * time.Millisecond,
+		Modifiers: xkbModifiers(),
 	})
 }
 
@@ -512,6 +532,16 @@ func gio_onPointerEnter(data unsafe.Pointer, pointer *C.struct_wl_pointer, seria
 
 //export gio_onPointerLeave
 func gio_onPointerLeave(data unsafe.Pointer, p *C.struct_wl_pointer, serial C.uint32_t, surface *C.struct_wl_surface) {
+	w, ok := winMap[p]
+	if !ok {
+		return
+	}
+	w.flushScroll()
+	w.buttons = 0
+	w.w.event(pointer.Event{
+		Type:   pointer.Cancel,
+		Source: pointer.Mouse,
+	})
 }
 
 //export gio_onPointerMotion
@@ -524,23 +554,50 @@ func gio_onPointerMotion(data unsafe.Pointer, p *C.struct_wl_pointer, t C.uint32
 func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t, button, state C.uint32_t) {
 	w := winMap[p]
 	// From linux-event-codes.h.
-	const BTN_LEFT = 0x110
-	if button != BTN_LEFT {
+	const (
+		BTN_LEFT   = 0x110
+		BTN_RIGHT  = 0x111
+		BTN_MIDDLE = 0x112
+		BTN_SIDE   = 0x113
+		BTN_EXTRA  = 0x114
+	)
+	var btn pointer.Buttons
+	switch button {
+	case BTN_LEFT:
+		btn = pointer.ButtonPrimary
+	case BTN_RIGHT:
+		btn = pointer.ButtonSecondary
+	case BTN_MIDDLE:
+		btn = pointer.ButtonTertiary
+	case BTN_SIDE:
+		btn = pointer.ButtonBack
+	case BTN_EXTRA:
+		btn = pointer.ButtonForward
+	default:
 		return
 	}
-	var typ pointer.Type
+	// The pointer is pressed while any button is down.
+	typ := pointer.Move
 	switch state {
 	case 0:
-		typ = pointer.Release
+		w.buttons &^= btn
+		if w.buttons == 0 {
+			typ = pointer.Release
+		}
 	case 1:
-		typ = pointer.Press
+		if w.buttons == 0 {
+			typ = pointer.Press
+		}
+		w.buttons |= btn
 	}
 	w.flushScroll()
 	w.w.event(pointer.Event{
-		Type:     typ,
-		Source:   pointer.Mouse,
-		Position: w.lastPos,
-		Time:     time.Duration(t) * time.Millisecond,
+		Type:      typ,
+		Source:    pointer.Mouse,
+		Buttons:   w.buttons,
+		Position:  w.lastPos,
+		Time:      time.Duration(t) * time.Millisecond,
+		Modifiers: xkbModifiers(),
 	})
 }
 
@@ -663,12 +720,16 @@ func gio_onKeyboardKey(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, seri
 	t := time.Duration(timestamp) * time.Millisecond
 	conn.repeat.Stop(t)
 	w := winMap[keyboard]
-	if state != C.WL_KEYBOARD_KEY_STATE_PRESSED || conn.xkbMap == nil || conn.xkbState == nil || conn.xkbCompState == nil {
+	if conn.xkbMap == nil || conn.xkbState == nil || conn.xkbCompState == nil {
 		return
 	}
 	// According to the xkb_v1 spec: "to determine the xkb keycode, clients must add 8 to the key event keycode."
 	keyCode += 8
-	w.dispatchKey(keyCode)
+	if state != C.WL_KEYBOARD_KEY_STATE_PRESSED {
+		w.dispatchKey(keyCode, key.Release, false, t)
+		return
+	}
+	w.dispatchKey(keyCode, key.Press, false, t)
 	if C.xkb_keymap_key_repeats(conn.xkbMap, C.xkb_keycode_t(keyCode)) == 1 {
 		conn.repeat.Start(w, keyCode, t)
 	}
@@ -739,8 +800,8 @@ func (r *repeatState) Repeat() {
 		if r.last+delay > now {
 			break
 		}
-		r.win.dispatchKey(r.key)
 		r.last += delay
+		r.win.dispatchKey(r.key, key.Press, true, r.start+r.last)
 	}
 }
 
@@ -791,6 +852,7 @@ loop:
 			}
 			redraw = true
 		}
+		w.flushTextInput()
 		// Handle events
 		switch {
 		case *dispEvents&syscall.POLLIN != 0:
@@ -850,21 +912,26 @@ func (w *window) destroy() {
 	}
 }
 
-func (w *window) dispatchKey(keyCode C.uint32_t) {
+// dispatchKey generates the key event and, for pressed keys,
+// the text input of a key.
+func (w *window) dispatchKey(keyCode C.uint32_t, state key.State, repeat bool, t time.Duration) {
 	if len(conn.utf8Buf) == 0 {
 		conn.utf8Buf = make([]byte, 1)
 	}
 	sym := C.xkb_state_key_get_one_sym(conn.xkbState, C.xkb_keycode_t(keyCode))
 	if n, ok := convertKeysym(sym); ok {
-		cmd := key.Event{Name: n}
-		if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_CTRL[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
-			cmd.Modifiers |= key.ModCommand
-		}
-		if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_SHIFT[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
-			cmd.Modifiers |= key.ModShift
+		cmd := key.Event{
+			Name:      n,
+			Modifiers: xkbModifiers(),
+			State:     state,
+			Repeat:    repeat,
+			Time:      t,
 		}
 		w.w.event(cmd)
 	}
+	if state != key.Press {
+		return
+	}
 	C.xkb_compose_state_feed(conn.xkbCompState, sym)
 	var size C.int
 	switch C.xkb_compose_state_get_status(conn.xkbCompState) {
@@ -918,28 +985,92 @@ func gio_onKeyboardRepeatInfo(data unsafe.Pointer, keyboard *C.struct_wl_keyboar
 	conn.repeat.delay = time.Duration(delay) * time.Millisecond
 }
 
+// The text-input-v3 callbacks below only forward to conn.textInput.
+// The event sequences are tested on textinput.State with a fake
+// compositor; the callbacks themselves need a running compositor
+// and are not tested.
+
 //export gio_onTextInputEnter
 func gio_onTextInputEnter(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, surf *C.struct_wl_surface) {
+	w, ok := winMap[surf]
+	if !ok {
+		return
+	}
+	conn.imWindow = w
+	conn.textInput.Enter()
 }
 
 //export gio_onTextInputLeave
 func gio_onTextInputLeave(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, surf *C.struct_wl_surface) {
+	w := conn.imWindow
+	if w == nil {
+		return
+	}
+	for _, e := range conn.textInput.Leave() {
+		w.w.event(e)
+	}
+	conn.imWindow = nil
 }
 
 //export gio_onTextInputPreeditString
 func gio_onTextInputPreeditString(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, ctxt *C.char, begin, end C.int32_t) {
+	conn.textInput.PreeditString(C.GoString(ctxt), int(begin), int(end))
 }
 
 //export gio_onTextInputCommitString
 func gio_onTextInputCommitString(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, ctxt *C.char) {
+	conn.textInput.CommitString(C.GoString(ctxt))
 }
 
 //export gio_onTextInputDeleteSurroundingText
 func gio_onTextInputDeleteSurroundingText(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, before, after C.uint32_t) {
+	conn.textInput.DeleteSurroundingText(int(before), int(after))
 }
 
 //export gio_onTextInputDone
 func gio_onTextInputDone(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, serial C.uint32_t) {
+	events := conn.textInput.Done(uint32(serial))
+	if w := conn.imWindow; w != nil {
+		for _, e := range events {
+			w.w.event(e)
+		}
+	}
+}
+
+// wlTextInput implements the text-input-v3 requests
+// for textinput.State. Like the State, it is only used
+// from the event loop.
+type wlTextInput struct{}
+
+func (wlTextInput) Enable() {
+	C.zwp_text_input_v3_enable(conn.im)
+}
+
+func (wlTextInput) Disable() {
+	C.zwp_text_input_v3_disable(conn.im)
+}
+
+func (wlTextInput) SetSurroundingText(text string, cursor, anchor int) {
+	ctext := C.CString(text)
+	defer C.free(unsafe.Pointer(ctext))
+	C.zwp_text_input_v3_set_surrounding_text(conn.im, ctext, C.int32_t(cursor), C.int32_t(anchor))
+}
+
+func (wlTextInput) SetCursorRectangle(r image.Rectangle) {
+	// Convert from pixels to surface local coordinates.
+	scale := 1
+	if w := conn.imWindow; w != nil {
+		w.mu.Lock()
+		scale = w.scale
+		w.mu.Unlock()
+	}
+	r.Min = r.Min.Div(scale)
+	r.Max = r.Max.Div(scale)
+	C.zwp_text_input_v3_set_cursor_rectangle(conn.im, C.int32_t(r.Min.X), C.int32_t(r.Min.Y), C.int32_t(r.Dx()), C.int32_t(r.Dy()))
+}
+
+func (wlTextInput) Commit() {
+	C.zwp_text_input_v3_commit(conn.im)
 }
 
 // ppmm returns the approximate pixels per millimeter for the output.
@@ -953,6 +1084,28 @@ func (c *wlOutput) ppmm() (float32, error) {
 	return density, nil
 }
 
+// xkbModifiers returns the active keyboard modifiers.
+func xkbModifiers() key.Modifiers {
+	var mods key.Modifiers
+	if conn.xkbState == nil {
+		return mods
+	}
+	for _, m := range []struct {
+		name []byte
+		mods key.Modifiers
+	}{
+		{_XKB_MOD_NAME_CTRL, key.ModCommand | key.ModCtrl},
+		{_XKB_MOD_NAME_SHIFT, key.ModShift},
+		{_XKB_MOD_NAME_ALT, key.ModAlt},
+		{_XKB_MOD_NAME_LOGO, key.ModSuper},
+	} {
+		if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&m.name[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
+			mods |= m.mods
+		}
+	}
+	return mods
+}
+
 func (w *window) flushScroll() {
 	if w.scroll == (f32.Point{}) {
 		return
@@ -968,11 +1121,13 @@ func (w *window) flushScroll() {
 		w.scroll.Y *= discreteScale
 	}
 	w.w.event(pointer.Event{
-		Type:     pointer.Move,
-		Source:   pointer.Mouse,
-		Position: w.lastPos,
-		Scroll:   w.scroll,
-		Time:     w.scrollTime,
+		Type:      pointer.Move,
+		Source:    pointer.Mouse,
+		Buttons:   w.buttons,
+		Position:  w.lastPos,
+		Scroll:    w.scroll,
+		Time:      w.scrollTime,
+		Modifiers: xkbModifiers(),
 	})
 	w.scroll = f32.Point{}
 	w.discScroll.x = 0
@@ -983,10 +1138,12 @@ func (w *window) onPointerMotion(x, y C.wl_fixed_t, t C.uint32_t) {
 	w.flushScroll()
 	w.lastPos = f32.Point{X: fromFixed(x), Y: fromFixed(y)}
 	w.w.event(pointer.Event{
-		Type:     pointer.Move,
-		Position: w.lastPos,
-		Source:   pointer.Mouse,
-		Time:     time.Duration(t) * time.Millisecond,
+		Type:      pointer.Move,
+		Position:  w.lastPos,
+		Buttons:   w.buttons,
+		Source:    pointer.Mouse,
+		Time:      time.Duration(t) * time.Millisecond,
+		Modifiers: xkbModifiers(),
 	})
 }
 
@@ -1087,7 +1244,37 @@ func (w *window) nativeWindow(visID int) (unsafe.Pointer, int, int) {
 	return unsafe.Pointer(w.surf), width * scale, height * scale
 }
 
-func (w *window) showTextInput(show bool) {}
+func (w *window) showTextInput(show bool) {
+	w.mu.Lock()
+	w.showInput = show
+	w.textInputDirty = true
+	w.mu.Unlock()
+	w.notify()
+}
+
+func (w *window) updateTextInput(ti iinput.TextInput) {
+	w.mu.Lock()
+	w.textInput = ti
+	w.textInputDirty = true
+	w.mu.Unlock()
+	w.notify()
+}
+
+// flushTextInput applies the text input state from
+// showTextInput and updateTextInput. It runs in the
+// event loop, because the text input state is shared
+// with the text input event handlers.
+func (w *window) flushTextInput() {
+	w.mu.Lock()
+	ti, show, dirty := w.textInput, w.showInput, w.textInputDirty
+	w.textInputDirty = false
+	w.mu.Unlock()
+	if !dirty || conn.textInput == nil {
+		return
+	}
+	conn.textInput.Update(ti)
+	conn.textInput.Show(show)
+}
 
 // detectFontScale reports current font scale, or 1.0
 // if it fails.
@@ -1194,6 +1381,8 @@ func (c *wlConn) destroy() {
 	}
 	if c.im != nil {
 		C.zwp_text_input_v3_destroy(c.im)
+		c.imWindow = nil
+		c.textInput = nil
 	}
 	if c.imm != nil {
 		C.zwp_text_input_manager_v3_destroy(c.imm)
@@ -1237,6 +1426,9 @@ func convertKeysym(s C.xkb_keysym_t) (rune, bool) {
 	if 'a' <= s && s <= 'z' {
 		return rune(s - 0x20), true
 	}
+	if C.XKB_KEY_F1 <= s && s <= C.XKB_KEY_F12 {
+		return key.NameF1 + rune(s-C.XKB_KEY_F1), true
+	}
 	var n rune
 	switch s {
 	case C.XKB_KEY_Escape:
@@ -1265,6 +1457,20 @@ func convertKeysym(s C.xkb_keysym_t) (rune, bool) {
 		n = key.NamePageUp
 	case C.XKB_KEY_Page_Down:
 		n = key.NamePageDown
+	case C.XKB_KEY_Tab, C.XKB_KEY_ISO_Left_Tab:
+		n = key.NameTab
+	case C.XKB_KEY_space:
+		n = key.NameSpace
+	case C.XKB_KEY_Insert:
+		n = key.NameInsert
+	case C.XKB_KEY_Shift_L, C.XKB_KEY_Shift_R:
+		n = key.NameShift
+	case C.XKB_KEY_Control_L, C.XKB_KEY_Control_R:
+		n = key.NameCtrl
+	case C.XKB_KEY_Alt_L, C.XKB_KEY_Alt_R:
+		n = key.NameAlt
+	case C.XKB_KEY_Super_L, C.XKB_KEY_Super_R:
+		n = key.NameSuper
 	default:
 		return 0, false
 	}
@@ -14,6 +14,7 @@ import (
 
 	syscall "golang.org/x/sys/windows"
 
+	iinput "gioui.org/ui/app/internal/input"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -62,6 +63,8 @@ type window struct {
 	height int
 	stage  Stage
 	dead   bool
+	// buttons are the pressed mouse buttons.
+	buttons pointer.Buttons
 
 	mu        sync.Mutex
 	animating bool
@@ -80,6 +83,14 @@ const (
 
 	_LOGPIXELSX = 88
 
+	_MK_LBUTTON  = 0x0001
+	_MK_RBUTTON  = 0x0002
+	_MK_SHIFT    = 0x0004
+	_MK_CONTROL  = 0x0008
+	_MK_MBUTTON  = 0x0010
+	_MK_XBUTTON1 = 0x0020
+	_MK_XBUTTON2 = 0x0040
+
 	_SIZE_MAXIMIZED = 2
 	_SIZE_MINIMIZED = 1
 	_SIZE_RESTORED  = 0
@@ -89,6 +100,9 @@ const (
 	_USER_TIMER_MINIMUM = 0x0000000A
 
 	_VK_CONTROL = 0x11
+	_VK_LWIN    = 0x5B
+	_VK_MENU    = 0x12
+	_VK_RWIN    = 0x5C
 	_VK_SHIFT   = 0x10
 
 	_VK_BACK   = 0x08
@@ -96,12 +110,17 @@ const (
 	_VK_DOWN   = 0x28
 	_VK_END    = 0x23
 	_VK_ESCAPE = 0x1b
+	_VK_F1     = 0x70
+	_VK_F12    = 0x7b
 	_VK_HOME   = 0x24
+	_VK_INSERT = 0x2d
 	_VK_LEFT   = 0x25
 	_VK_NEXT   = 0x22
 	_VK_PRIOR  = 0x21
 	_VK_RIGHT  = 0x27
 	_VK_RETURN = 0x0d
+	_VK_SPACE  = 0x20
+	_VK_TAB    = 0x09
 	_VK_UP     = 0x26
 
 	_UNICODE_NOCHAR = 65535
@@ -114,8 +133,14 @@ const (
 	_WM_KEYUP       = 0x0101
 	_WM_LBUTTONDOWN = 0x0201
 	_WM_LBUTTONUP   = 0x0202
+	_WM_MBUTTONDOWN = 0x0207
+	_WM_MBUTTONUP   = 0x0208
 	_WM_MOUSEMOVE   = 0x0200
 	_WM_MOUSEWHEEL  = 0x020A
+	_WM_RBUTTONDOWN = 0x0204
+	_WM_RBUTTONUP   = 0x0205
+	_WM_XBUTTONDOWN = 0x020B
+	_WM_XBUTTONUP   = 0x020C
 	_WM_PAINT       = 0x000F
 	_WM_QUIT        = 0x0012
 	_WM_SETFOCUS    = 0x0007
@@ -123,6 +148,7 @@ const (
 	_WM_SHOWWINDOW  = 0x0018
 	_WM_SIZE        = 0x0005
 	_WM_SYSKEYDOWN  = 0x0104
+	_WM_SYSKEYUP    = 0x0105
 	_WM_TIMER       = 0x0113
 	_WM_UNICHAR     = 0x0109
 	_WM_USER        = 0x0400
@@ -267,28 +293,25 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 		}
 		// The message is processed.
 		return 1
-	case _WM_KEYDOWN, _WM_SYSKEYDOWN:
+	case _WM_KEYDOWN, _WM_SYSKEYDOWN, _WM_KEYUP, _WM_SYSKEYUP:
 		if n, ok := convertKeyCode(wParam); ok {
-			cmd := key.Event{Name: n}
-			if getKeyState(_VK_CONTROL)&0x1000 != 0 {
-				cmd.Modifiers |= key.ModCommand
+			cmd := key.Event{
+				Name:      n,
+				Modifiers: getModifiers(),
+				Time:      getMessageTime(),
 			}
-			if getKeyState(_VK_SHIFT)&0x1000 != 0 {
-				cmd.Modifiers |= key.ModShift
+			if msg == _WM_KEYUP || msg == _WM_SYSKEYUP {
+				cmd.State = key.Release
+			} else {
+				// Bit 30 of lParam is the previous key state.
+				cmd.Repeat = lParam&(1<<30) != 0
 			}
 			w.w.event(cmd)
 		}
-	case _WM_LBUTTONDOWN:
-		setCapture(w.hwnd)
-		x, y := coordsFromlParam(lParam)
-		p := f32.Point{X: float32(x), Y: float32(y)}
-		w.w.event(pointer.Event{
-			Type:     pointer.Press,
-			Source:   pointer.Mouse,
-			Position: p,
-			Time:     getMessageTime(),
-		})
+	case _WM_LBUTTONDOWN, _WM_RBUTTONDOWN, _WM_MBUTTONDOWN, _WM_XBUTTONDOWN:
+		w.pointerButton(true, wParam, lParam)
 	case _WM_CANCELMODE:
+		w.buttons = 0
 		w.w.event(pointer.Event{
 			Type: pointer.Cancel,
 		})
@@ -296,24 +319,19 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 		w.w.event(key.FocusEvent{Focus: true})
 	case _WM_KILLFOCUS:
 		w.w.event(key.FocusEvent{Focus: false})
-	case _WM_LBUTTONUP:
-		releaseCapture()
-		x, y := coordsFromlParam(lParam)
-		p := f32.Point{X: float32(x), Y: float32(y)}
-		w.w.event(pointer.Event{
-			Type:     pointer.Release,
-			Source:   pointer.Mouse,
-			Position: p,
-			Time:     getMessageTime(),
-		})
+	case _WM_LBUTTONUP, _WM_RBUTTONUP, _WM_MBUTTONUP, _WM_XBUTTONUP:
+		w.pointerButton(false, wParam, lParam)
 	case _WM_MOUSEMOVE:
 		x, y := coordsFromlParam(lParam)
 		p := f32.Point{X: float32(x), Y: float32(y)}
+		btns, mods := mouseState(wParam)
 		w.w.event(pointer.Event{
-			Type:     pointer.Move,
-			Source:   pointer.Mouse,
-			Position: p,
-			Time:     getMessageTime(),
+			Type:      pointer.Move,
+			Source:    pointer.Mouse,
+			Buttons:   btns,
+			Position:  p,
+			Time:      getMessageTime(),
+			Modifiers: mods,
 		})
 	case _WM_MOUSEWHEEL:
 		w.scrollEvent(wParam, lParam)
@@ -343,6 +361,63 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 	return defWindowProc(hwnd, msg, wParam, lParam)
 }
 
+// pointerButton generates the pointer event for a mouse button
+// press or release. The pointer is pressed while any button is
+// down.
+func (w *window) pointerButton(press bool, wParam, lParam uintptr) {
+	x, y := coordsFromlParam(lParam)
+	p := f32.Point{X: float32(x), Y: float32(y)}
+	// The button state in wParam is the state after the event.
+	btns, mods := mouseState(wParam)
+	typ := pointer.Move
+	switch {
+	case press && w.buttons == 0:
+		setCapture(w.hwnd)
+		typ = pointer.Press
+	case !press && btns == 0:
+		releaseCapture()
+		typ = pointer.Release
+	}
+	w.buttons = btns
+	w.w.event(pointer.Event{
+		Type:      typ,
+		Source:    pointer.Mouse,
+		Buttons:   btns,
+		Position:  p,
+		Time:      getMessageTime(),
+		Modifiers: mods,
+	})
+}
+
+// mouseState returns the pressed buttons and modifiers
+// from the wParam of a mouse message.
+func mouseState(wParam uintptr) (pointer.Buttons, key.Modifiers) {
+	var btns pointer.Buttons
+	var mods key.Modifiers
+	if wParam&_MK_LBUTTON != 0 {
+		btns |= pointer.ButtonPrimary
+	}
+	if wParam&_MK_RBUTTON != 0 {
+		btns |= pointer.ButtonSecondary
+	}
+	if wParam&_MK_MBUTTON != 0 {
+		btns |= pointer.ButtonTertiary
+	}
+	if wParam&_MK_XBUTTON1 != 0 {
+		btns |= pointer.ButtonBack
+	}
+	if wParam&_MK_XBUTTON2 != 0 {
+		btns |= pointer.ButtonForward
+	}
+	if wParam&_MK_CONTROL != 0 {
+		mods |= key.ModCommand | key.ModCtrl
+	}
+	if wParam&_MK_SHIFT != 0 {
+		mods |= key.ModShift
+	}
+	return btns, mods
+}
+
 func coordsFromlParam(lParam uintptr) (int, int) {
 	x := int(int16(lParam & 0xffff))
 	y := int(int16((lParam >> 16) & 0xffff))
@@ -357,12 +432,15 @@ func (w *window) scrollEvent(wParam, lParam uintptr) {
 	screenToClient(w.hwnd, &np)
 	p := f32.Point{X: float32(np.x), Y: float32(np.y)}
 	dist := float32(int16(wParam >> 16))
+	btns, mods := mouseState(wParam & 0xffff)
 	w.w.event(pointer.Event{
-		Type:     pointer.Move,
-		Source:   pointer.Mouse,
-		Position: p,
-		Scroll:   f32.Point{Y: -dist},
-		Time:     getMessageTime(),
+		Type:      pointer.Move,
+		Source:    pointer.Mouse,
+		Buttons:   btns,
+		Position:  p,
+		Scroll:    f32.Point{Y: -dist},
+		Time:      getMessageTime(),
+		Modifiers: mods,
 	})
 }
 
@@ -443,6 +521,8 @@ func (w *window) destroy() {
 
 func (w *window) showTextInput(show bool) {}
 
+func (w *window) updateTextInput(ti iinput.TextInput) {}
+
 func (w *window) display() uintptr {
 	return uintptr(w.hdc)
 }
@@ -451,10 +531,36 @@ func (w *window) nativeWindow(visID int) (uintptr, int, int) {
 	return uintptr(w.hwnd), w.width, w.height
 }
 
+// getModifiers returns the current state of the
+// keyboard modifiers.
+func getModifiers() key.Modifiers {
+	// The high-order bit is set for pressed keys.
+	pressed := func(vk int32) bool {
+		return uint16(getKeyState(vk))&0x8000 != 0
+	}
+	var kmods key.Modifiers
+	if pressed(_VK_CONTROL) {
+		kmods |= key.ModCommand | key.ModCtrl
+	}
+	if pressed(_VK_SHIFT) {
+		kmods |= key.ModShift
+	}
+	if pressed(_VK_MENU) {
+		kmods |= key.ModAlt
+	}
+	if pressed(_VK_LWIN) || pressed(_VK_RWIN) {
+		kmods |= key.ModSuper
+	}
+	return kmods
+}
+
 func convertKeyCode(code uintptr) (rune, bool) {
 	if '0' <= code && code <= '9' || 'A' <= code && code <= 'Z' {
 		return rune(code), true
 	}
+	if _VK_F1 <= code && code <= _VK_F12 {
+		return key.NameF1 + rune(code-_VK_F1), true
+	}
 	var r rune
 	switch code {
 	case _VK_ESCAPE:
@@ -481,6 +587,20 @@ func convertKeyCode(code uintptr) (rune, bool) {
 		r = key.NamePageUp
 	case _VK_NEXT:
 		r = key.NamePageDown
+	case _VK_TAB:
+		r = key.NameTab
+	case _VK_SPACE:
+		r = key.NameSpace
+	case _VK_INSERT:
+		r = key.NameInsert
+	case _VK_SHIFT:
+		r = key.NameShift
+	case _VK_CONTROL:
+		r = key.NameCtrl
+	case _VK_MENU:
+		r = key.NameAlt
+	case _VK_LWIN, _VK_RWIN:
+		r = key.NameSuper
 	default:
 		return 0, false
 	}
@@ -3,9 +3,11 @@
 package app
 
 import (
+	"encoding/json"
 	"errors"
-	"fmt"
 	"image"
+	"io"
+	"os"
 	"time"
 
 	"gioui.org/ui"
@@ -23,26 +25,44 @@ type WindowOption struct {
 type windowOptions struct {
 	Width, Height ui.Value
 	Title         string
+	Headless      *Headless
+	CacheBudget   gpu.CacheBudget
+	ProfileOutput io.Writer
+	GLDebug       bool
 }
 
 // Window represents an operating system window.
 type Window struct {
 	driver    *window
+	headless  *Headless
 	lastFrame time.Time
 	drawStart time.Time
 	gpu       *gpu.GPU
+	// eventsDur is the time spent routing events
+	// since the last frame.
+	eventsDur time.Duration
+	// layoutDur is the time from the UpdateEvent
+	// to the frame.
+	layoutDur time.Duration
+	// profileOut is the encoder of the profiles written
+	// to the profile output.
+	profileOut *json.Encoder
 
 	out         chan input.Event
 	in          chan input.Event
 	ack         chan struct{}
 	invalidates chan struct{}
 	frames      chan *ui.Ops
+	// traces receives requests for frame traces.
+	traces chan struct{}
 
 	stage        Stage
 	animating    bool
 	hasNextFrame bool
 	nextFrame    time.Time
 	delayedDraw  *time.Timer
+	// textInput is the last TextInput sent to the driver.
+	textInput iinput.TextInput
 
 	queue Queue
 }
@@ -68,6 +88,9 @@ var _ interface {
 	setAnimating(anim bool)
 	// showTextInput updates the virtual keyboard state.
 	showTextInput(show bool)
+	// updateTextInput updates the caret and surrounding
+	// text reported to input methods.
+	updateTextInput(ti iinput.TextInput)
 } = (*window)(nil)
 
 // Pre-allocate the ack event to avoid garbage.
@@ -101,6 +124,17 @@ func NewWindow(options ...WindowOption) *Window {
 		ack:         make(chan struct{}),
 		invalidates: make(chan struct{}, 1),
 		frames:      make(chan *ui.Ops),
+		traces:      make(chan struct{}, 1),
+	}
+	if os.Getenv("GIO_GL_DEBUG") != "" {
+		opts.GLDebug = true
+	}
+	if out := opts.ProfileOutput; out != nil {
+		w.profileOut = json.NewEncoder(out)
+	}
+	if h := opts.Headless; h != nil {
+		w.headless = h
+		h.attach(w)
 	}
 	go w.run(opts)
 	return w
@@ -126,35 +160,78 @@ func (w *Window) Update(frame *ui.Ops) {
 }
 
 func (w *Window) draw(size image.Point, frame *ui.Ops) {
-	var drawDur time.Duration
-	if !w.drawStart.IsZero() {
-		drawDur = time.Since(w.drawStart)
-		w.drawStart = time.Time{}
+	profiling := w.queue.q.Profiling() || w.profileOut != nil
+	var stats gpu.FrameStats
+	if w.headless != nil {
+		w.headless.draw(size, frame)
+	} else {
+		select {
+		case <-w.traces:
+			w.gpu.TraceFrame()
+		default:
+		}
+		w.gpu.Draw(profiling, size, frame)
+		stats = w.gpu.Stats()
 	}
-	w.gpu.Draw(w.queue.q.Profiling(), size, frame)
 	w.queue.q.Frame(frame)
 	now := time.Now()
 	switch w.queue.q.TextInputState() {
 	case iinput.TextInputOpen:
-		w.driver.showTextInput(true)
+		w.showTextInput(true)
 	case iinput.TextInputClose:
-		w.driver.showTextInput(false)
+		w.showTextInput(false)
+	}
+	if ti, ok := w.queue.q.TextInput(); ok && ti != w.textInput {
+		w.textInput = ti
+		w.updateTextInput(ti)
 	}
 	frameDur := now.Sub(w.lastFrame)
-	frameDur = frameDur.Truncate(100 * time.Microsecond)
 	w.lastFrame = now
-	if w.queue.q.Profiling() {
-		q := 100 * time.Microsecond
-		timings := fmt.Sprintf("tot:%7s cpu:%7s %s", frameDur.Round(q), drawDur.Round(q), w.gpu.Timings())
-		w.queue.q.AddProfile(system.ProfileEvent{Timings: timings})
-		w.setNextFrame(time.Time{})
+	if profiling {
+		e := system.ProfileEvent{
+			Frame:          frameDur,
+			Events:         w.eventsDur,
+			Layout:         w.layoutDur,
+			Collect:        stats.Collect,
+			Blit:           stats.Blit,
+			Stencil:        stats.Stencil,
+			Cover:          stats.Cover,
+			GPU:            stats.GPU,
+			DrawCalls:      stats.DrawCalls,
+			Paths:          stats.Paths,
+			UploadBytes:    stats.UploadBytes,
+			TextureHitRate: hitRate(stats.Textures),
+			PathHitRate:    hitRate(stats.PathCache),
+		}
+		if w.queue.q.Profiling() {
+			w.queue.q.AddProfile(e)
+			w.setNextFrame(time.Time{})
+		}
+		if w.profileOut != nil {
+			if err := w.profileOut.Encode(e); err != nil {
+				// Stop writing to a broken output.
+				w.profileOut = nil
+			}
+		}
 	}
+	w.eventsDur = 0
 	if t, ok := w.queue.q.WakeupTime(); ok {
 		w.setNextFrame(t)
 	}
 	w.updateAnimation()
 }
 
+// TraceFrame logs the GL calls of the next frame. TraceFrame has
+// no effect unless GL debugging is enabled with WithGLDebug or by
+// setting the GIO_GL_DEBUG environment variable.
+func (w *Window) TraceFrame() {
+	select {
+	case w.traces <- struct{}{}:
+	default:
+	}
+	w.Invalidate()
+}
+
 // Invalidate the window such that a UpdateEvent will be generated
 // immediately. If the window is inactive, the event is sent when the
 // window becomes active.
@@ -180,7 +257,25 @@ func (w *Window) updateAnimation() {
 	}
 	if animate != w.animating {
 		w.animating = animate
-		w.driver.setAnimating(animate)
+		if w.headless != nil {
+			w.headless.setAnimating(animate)
+		} else {
+			w.driver.setAnimating(animate)
+		}
+	}
+}
+
+func (w *Window) showTextInput(show bool) {
+	if w.headless != nil {
+		w.headless.showTextInput(show)
+	} else {
+		w.driver.showTextInput(show)
+	}
+}
+
+func (w *Window) updateTextInput(ti iinput.TextInput) {
+	if w.headless == nil {
+		w.driver.updateTextInput(ti)
 	}
 }
 
@@ -223,7 +318,11 @@ func (w *Window) destroy(err error) {
 func (w *Window) run(opts *windowOptions) {
 	defer close(w.in)
 	defer close(w.out)
-	if err := createWindow(w, opts); err != nil {
+	if w.headless != nil {
+		w.stage = StageRunning
+		w.out <- StageEvent{Stage: StageRunning}
+		w.waitAck()
+	} else if err := createWindow(w, opts); err != nil {
 		w.out <- DestroyEvent{err}
 		return
 	}
@@ -272,6 +371,7 @@ func (w *Window) run(opts *windowOptions) {
 				case frame = <-w.frames:
 				case w.out <- ackEvent:
 				}
+				w.layoutDur = time.Since(w.drawStart)
 				if w.gpu != nil {
 					if e2.sync {
 						w.gpu.Refresh()
@@ -282,13 +382,13 @@ func (w *Window) run(opts *windowOptions) {
 						w.destroy(err)
 						return
 					}
-				} else {
+				} else if w.headless == nil {
 					ctx, err := newContext(w.driver)
 					if err != nil {
 						w.destroy(err)
 						return
 					}
-					w.gpu, err = gpu.NewGPU(ctx)
+					w.gpu, err = gpu.NewGPU(ctx, opts.CacheBudget, opts.GLDebug)
 					if err != nil {
 						w.destroy(err)
 						return
@@ -313,11 +413,13 @@ func (w *Window) run(opts *windowOptions) {
 				w.ack <- struct{}{}
 				return
 			case input.Event:
+				start := time.Now()
 				if w.queue.q.Add(e2) {
 					w.setNextFrame(time.Time{})
 					w.updateAnimation()
 				}
 				w.out <- e
+				w.eventsDur += time.Since(start)
 			}
 			w.ack <- struct{}{}
 		}
@@ -328,6 +430,16 @@ func (q *Queue) Next(k input.Key) (input.Event, bool) {
 	return q.q.Next(k)
 }
 
+// WithHeadless returns an option that runs the window with
+// the headless driver h instead of the platform driver.
+func WithHeadless(h *Headless) WindowOption {
+	return WindowOption{
+		apply: func(opts *windowOptions) {
+			opts.Headless = h
+		},
+	}
+}
+
 // WithTitle returns an option that sets the window title.
 func WithTitle(t string) WindowOption {
 	return WindowOption{
@@ -361,4 +473,59 @@ func WithHeight(h ui.Value) WindowOption {
 	}
 }
 
+// WithCacheBudget returns an option that limits the memory of
+// the GPU resources kept across frames. The budget for image
+// textures is textureBytes and the budget for path vertices
+// is vertexBytes. Zero means the default budget. The least
+// recently used resources are released first.
+func WithCacheBudget(textureBytes, vertexBytes int) WindowOption {
+	if textureBytes < 0 || vertexBytes < 0 {
+		panic("cache budgets must be larger than or equal to 0")
+	}
+	return WindowOption{
+		apply: func(opts *windowOptions) {
+			opts.CacheBudget = gpu.CacheBudget{
+				TextureBytes: textureBytes,
+				VertexBytes:  vertexBytes,
+			}
+		},
+	}
+}
+
 func (driverEvent) ImplementsEvent() {}
+
+// WithProfileOutput returns an option that profiles every frame
+// and writes the profiles to out as JSON encoded
+// system.ProfileEvents, one per line. Durations are in
+// nanoseconds. Writing stops at the first error.
+func WithProfileOutput(out io.Writer) WindowOption {
+	return WindowOption{
+		apply: func(opts *windowOptions) {
+			opts.ProfileOutput = out
+		},
+	}
+}
+
+// WithGLDebug returns an option that checks every GL call for
+// errors. Errors are logged along with the Go stack of the call,
+// and so are the messages of the KHR_debug extension, if
+// supported. The GIO_GL_DEBUG environment variable enables GL
+// debugging as well. Use Window.TraceFrame to log the calls of
+// a frame.
+func WithGLDebug() WindowOption {
+	return WindowOption{
+		apply: func(opts *windowOptions) {
+			opts.GLDebug = true
+		},
+	}
+}
+
+// hitRate returns the fraction of the lookups in a cache that
+// were hits.
+func hitRate(s gpu.CacheStats) float32 {
+	n := s.Hits + s.Misses
+	if n == 0 {
+		return 0
+	}
+	return float32(s.Hits) / float32(n)
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package f32
+
+import (
+	"fmt"
+	"math"
+)
+
+// Affine2D represents an affine 2D transformation. The zero value of
+// Affine2D represents the identity transform.
+type Affine2D struct {
+	// The transformation matrix is
+	//
+	//	[sx hx ox]
+	//	[hy sy oy]
+	//	[ 0  0  1]
+	//
+	// To make the zero value the identity transform,
+	// a and e store sx-1 and sy-1.
+	a, b, c float32
+	d, e, f float32
+}
+
+// NewAffine2D creates a new Affine2D transform from the matrix elements
+// in row major order. The rows are: [sx, hx, ox], [hy, sy, oy], [0, 0, 1].
+func NewAffine2D(sx, hx, ox, hy, sy, oy float32) Affine2D {
+	return Affine2D{
+		a: sx - 1, b: hx, c: ox,
+		d: hy, e: sy - 1, f: oy,
+	}
+}
+
+// Offset the transformation.
+func (a Affine2D) Offset(offset Point) Affine2D {
+	return Affine2D{
+		a.a, a.b, a.c + offset.X,
+		a.d, a.e, a.f + offset.Y,
+	}
+}
+
+// Scale the transformation around the given origin.
+func (a Affine2D) Scale(origin, factor Point) Affine2D {
+	if origin == (Point{}) {
+		return a.scale(factor)
+	}
+	a = a.Offset(origin.Mul(-1))
+	a = a.scale(factor)
+	return a.Offset(origin)
+}
+
+// Rotate the transformation by the given angle (in radians) around
+// the given origin. Because the y axis extends downwards, positive
+// angles rotate clockwise.
+func (a Affine2D) Rotate(origin Point, radians float32) Affine2D {
+	if origin == (Point{}) {
+		return a.rotate(radians)
+	}
+	a = a.Offset(origin.Mul(-1))
+	a = a.rotate(radians)
+	return a.Offset(origin)
+}
+
+// Shear the transformation by the given angles (in radians) around
+// the given origin.
+func (a Affine2D) Shear(origin Point, radiansX, radiansY float32) Affine2D {
+	if origin == (Point{}) {
+		return a.shear(radiansX, radiansY)
+	}
+	a = a.Offset(origin.Mul(-1))
+	a = a.shear(radiansX, radiansY)
+	return a.Offset(origin)
+}
+
+// Mul returns a*b, the transformation that applies b
+// followed by a.
+func (a Affine2D) Mul(b Affine2D) Affine2D {
+	a0, b0, c0, d0, e0, f0 := a.Elems()
+	a1, b1, c1, d1, e1, f1 := b.Elems()
+	return NewAffine2D(
+		a0*a1+b0*d1, a0*b1+b0*e1, a0*c1+b0*f1+c0,
+		d0*a1+e0*d1, d0*b1+e0*e1, d0*c1+e0*f1+f0,
+	)
+}
+
+// Invert the transformation. Note that if the matrix is close to
+// singular numerical errors may become large or infinity.
+func (a Affine2D) Invert() Affine2D {
+	if a.IsOffset() {
+		return Affine2D{c: -a.c, f: -a.f}
+	}
+	sx, hx, ox, hy, sy, oy := a.Elems()
+	det := sx*sy - hx*hy
+	isx, ihx := sy/det, -hx/det
+	ihy, isy := -hy/det, sx/det
+	return NewAffine2D(
+		isx, ihx, -(isx*ox + ihx*oy),
+		ihy, isy, -(ihy*ox + isy*oy),
+	)
+}
+
+// Transform p by returning a*p.
+func (a Affine2D) Transform(p Point) Point {
+	return Point{
+		X: p.X*(a.a+1) + p.Y*a.b + a.c,
+		Y: p.X*a.d + p.Y*(a.e+1) + a.f,
+	}
+}
+
+// Elems returns the matrix elements of the transform in row-major order. The
+// rows are: [sx, hx, ox], [hy, sy, oy], [0, 0, 1].
+func (a Affine2D) Elems() (sx, hx, ox, hy, sy, oy float32) {
+	return a.a + 1, a.b, a.c, a.d, a.e + 1, a.f
+}
+
+// Split a transform into two parts, one which is pure offset and the
+// other representing the scaling, shearing and rotation part.
+func (a Affine2D) Split() (srs Affine2D, offset Point) {
+	return Affine2D{
+		a: a.a, b: a.b, c: 0,
+		d: a.d, e: a.e, f: 0,
+	}, Point{X: a.c, Y: a.f}
+}
+
+// IsOffset reports whether the transform is a pure
+// offset (translation).
+func (a Affine2D) IsOffset() bool {
+	return a.a == 0 && a.b == 0 && a.d == 0 && a.e == 0
+}
+
+// IsAxisAligned reports whether the transform maps axis aligned
+// rectangles to axis aligned rectangles. That is the case if
+// the transform has no rotation and no shear components.
+func (a Affine2D) IsAxisAligned() bool {
+	return a.b == 0 && a.d == 0
+}
+
+func (a Affine2D) scale(factor Point) Affine2D {
+	return Affine2D{
+		(a.a+1)*factor.X - 1, a.b * factor.X, a.c * factor.X,
+		a.d * factor.Y, (a.e+1)*factor.Y - 1, a.f * factor.Y,
+	}
+}
+
+func (a Affine2D) rotate(radians float32) Affine2D {
+	sin, cos := math.Sincos(float64(radians))
+	s, c := float32(sin), float32(cos)
+	return Affine2D{
+		(a.a+1)*c - a.d*s - 1, a.b*c - (a.e+1)*s, a.c*c - a.f*s,
+		(a.a+1)*s + a.d*c, a.b*s + (a.e+1)*c - 1, a.c*s + a.f*c,
+	}
+}
+
+func (a Affine2D) shear(radiansX, radiansY float32) Affine2D {
+	tx := float32(math.Tan(float64(radiansX)))
+	ty := float32(math.Tan(float64(radiansY)))
+	return Affine2D{
+		(a.a + 1) + a.d*tx - 1, a.b + (a.e+1)*tx, a.c + a.f*tx,
+		(a.a+1)*ty + a.d, a.b*ty + (a.e + 1) - 1, a.c*ty + a.f,
+	}
+}
+
+func (a Affine2D) String() string {
+	sx, hx, ox, hy, sy, oy := a.Elems()
+	return fmt.Sprintf("[[%g %g %g] [%g %g %g]]", sx, hx, ox, hy, sy, oy)
+}
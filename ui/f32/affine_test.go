@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package f32
+
+import (
+	"math"
+	"testing"
+)
+
+func eq(p1, p2 Point) bool {
+	tol := 1e-5
+	dx, dy := p2.X-p1.X, p2.Y-p1.Y
+	return math.Abs(math.Sqrt(float64(dx*dx+dy*dy))) < tol
+}
+
+func TestTransformOffset(t *testing.T) {
+	p := Point{X: 1, Y: 2}
+	o := Point{X: 2, Y: -3}
+	r := Affine2D{}.Offset(o).Transform(p)
+	if !eq(r, Point{X: 3, Y: -1}) {
+		t.Errorf("offset failed: got %v", r)
+	}
+	if !eq(Affine2D{}.Offset(o).Invert().Transform(r), p) {
+		t.Errorf("offset inverse failed")
+	}
+}
+
+func TestTransformScale(t *testing.T) {
+	p := Point{X: 1, Y: 2}
+	s := Point{X: -1, Y: 2}
+	r := Affine2D{}.Scale(Point{}, s).Transform(p)
+	if !eq(r, Point{X: -1, Y: 4}) {
+		t.Errorf("scale failed: got %v", r)
+	}
+	r = Affine2D{}.Scale(Point{X: 1, Y: 1}, s).Transform(p)
+	if !eq(r, Point{X: 1, Y: 3}) {
+		t.Errorf("scale around origin failed: got %v", r)
+	}
+}
+
+func TestTransformRotate(t *testing.T) {
+	p := Point{X: 1, Y: 0}
+	a := float32(math.Pi / 2)
+	r := Affine2D{}.Rotate(Point{}, a).Transform(p)
+	if !eq(r, Point{X: 0, Y: 1}) {
+		t.Errorf("rotate failed: got %v", r)
+	}
+	r = Affine2D{}.Rotate(Point{X: 1, Y: 1}, a).Transform(p)
+	if !eq(r, Point{X: 2, Y: 1}) {
+		t.Errorf("rotate around origin failed: got %v", r)
+	}
+}
+
+func TestTransformShear(t *testing.T) {
+	p := Point{X: 1, Y: 1}
+	r := Affine2D{}.Shear(Point{}, math.Pi/4, 0).Transform(p)
+	if !eq(r, Point{X: 2, Y: 1}) {
+		t.Errorf("shear failed: got %v", r)
+	}
+}
+
+func TestTransformMulInvert(t *testing.T) {
+	p := Point{X: 3, Y: -2}
+	A := Affine2D{}.Rotate(Point{X: 1, Y: 2}, 0.7).Scale(Point{}, Point{X: 2, Y: 3})
+	B := Affine2D{}.Offset(Point{X: 5, Y: 1}).Shear(Point{X: -1, Y: 0}, 0.2, 0.3)
+	r := A.Mul(B).Transform(p)
+	if !eq(r, A.Transform(B.Transform(p))) {
+		t.Errorf("multiplication failed: got %v", r)
+	}
+	if !eq(A.Mul(B).Invert().Transform(r), p) {
+		t.Errorf("inverse failed")
+	}
+}
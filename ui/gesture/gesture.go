@@ -144,6 +144,11 @@ func (c *Click) Next(q input.Queue) (ClickEvent, bool) {
 			if c.state == StatePressed || !e.Hit {
 				break
 			}
+			// Ignore presses of other mouse buttons. Events
+			// without Buttons are treated as primary presses.
+			if e.Source == pointer.Mouse && e.Buttons != 0 && !e.Buttons.Contain(pointer.ButtonPrimary) {
+				break
+			}
 			c.state = StatePressed
 			return ClickEvent{Type: TypePress, Position: e.Position, Source: e.Source}, true
 		case pointer.Move:
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gesture
+
+import (
+	"testing"
+
+	"gioui.org/ui/input"
+	"gioui.org/ui/pointer"
+)
+
+type eventQueue []input.Event
+
+func (q *eventQueue) Next(k input.Key) (input.Event, bool) {
+	if len(*q) == 0 {
+		return nil, false
+	}
+	e := (*q)[0]
+	*q = (*q)[1:]
+	return e, true
+}
+
+func TestClickButtons(t *testing.T) {
+	tests := []struct {
+		name    string
+		buttons pointer.Buttons
+		press   bool
+	}{
+		{"no buttons", 0, true},
+		{"primary", pointer.ButtonPrimary, true},
+		{"primary and secondary", pointer.ButtonPrimary | pointer.ButtonSecondary, true},
+		{"secondary", pointer.ButtonSecondary, false},
+		{"tertiary", pointer.ButtonTertiary, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c Click
+			q := &eventQueue{
+				pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: test.buttons, Hit: true},
+				pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Hit: true},
+			}
+			var types []ClickType
+			for e, ok := c.Next(q); ok; e, ok = c.Next(q) {
+				types = append(types, e.Type)
+			}
+			if test.press {
+				if len(types) != 2 || types[0] != TypePress || types[1] != TypeClick {
+					t.Errorf("got click events %v, expected a press and a click", types)
+				}
+			} else if len(types) != 0 {
+				t.Errorf("got click events %v, expected none", types)
+			}
+		})
+	}
+}
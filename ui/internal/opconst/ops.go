@@ -4,6 +4,10 @@ package opconst
 
 type OpType byte
 
+// MaxGradientStops is the maximum number of
+// color stops in a gradient.
+const MaxGradientStops = 8
+
 // Start at a high number for easier debugging.
 const firstOpIndex = 200
 
@@ -26,27 +30,47 @@ const (
 	TypeAux
 	TypeClip
 	TypeProfile
+	TypeLinearGradient
+	TypeRadialGradient
+	TypeShadow
+	TypeBlur
+	TypeGlyph
+	TypeFocus
+	TypeMoveFocus
+	TypeShortcut
+	TypeTextInput
 )
 
 const (
 	TypeMacroDefLen     = 1 + 4 + 4
 	TypeMacroLen        = 1 + 4 + 4 + 4
-	TypeTransformLen    = 1 + 4*2
-	TypeLayerLen        = 1
+	TypeTransformLen    = 1 + 4*6
+	TypeLayerLen        = 1 + 4
 	TypeRedrawLen       = 1 + 8
-	TypeImageLen        = 1 + 4*4
+	TypeImageLen        = 1 + 4*4 + 4 + 4*4
 	TypePaintLen        = 1 + 4*4
 	TypeColorLen        = 1 + 4
 	TypeAreaLen         = 1 + 1 + 4*4
 	TypePointerInputLen = 1 + 1
 	TypePassLen         = 1 + 1
-	TypeKeyInputLen     = 1 + 1
+	TypeKeyInputLen     = 1 + 1 + 4 + 1
 	TypeHideInputLen    = 1
 	TypePushLen         = 1
 	TypePopLen          = 1
 	TypeAuxLen          = 1 + 4
-	TypeClipLen         = 1 + 4*4
+	TypeClipLen         = 1 + 4*4 + 1
 	TypeProfileLen      = 1
+	// Gradients are followed by the number of stops and
+	// MaxGradientStops (offset, color) pairs.
+	TypeLinearGradientLen = 1 + 4*4 + 1 + MaxGradientStops*(4+4)
+	TypeRadialGradientLen = 1 + 4*3 + 1 + MaxGradientStops*(4+4)
+	TypeShadowLen         = 1 + 4*4 + 4 + 4*2 + 4 + 4 + 4
+	TypeBlurLen           = 1 + 4*4 + 4
+	TypeGlyphLen          = 1 + 4*2
+	TypeFocusLen          = 1
+	TypeMoveFocusLen      = 1 + 1
+	TypeShortcutLen       = 1 + 4 + 4 + 1
+	TypeTextInputLen      = 1 + 4*4 + 4 + 4
 )
 
 func (t OpType) Size() int {
@@ -69,13 +93,24 @@ func (t OpType) Size() int {
 		TypeAuxLen,
 		TypeClipLen,
 		TypeProfileLen,
+		TypeLinearGradientLen,
+		TypeRadialGradientLen,
+		TypeShadowLen,
+		TypeBlurLen,
+		TypeGlyphLen,
+		TypeFocusLen,
+		TypeMoveFocusLen,
+		TypeShortcutLen,
+		TypeTextInputLen,
 	}[t-firstOpIndex]
 }
 
 func (t OpType) NumRefs() int {
 	switch t {
-	case TypeMacro, TypeImage, TypeKeyInput, TypePointerInput, TypeProfile:
+	case TypeMacro, TypeImage, TypeKeyInput, TypePointerInput, TypeProfile, TypeGlyph, TypeFocus, TypeShortcut:
 		return 1
+	case TypeTextInput:
+		return 2
 	default:
 		return 0
 	}
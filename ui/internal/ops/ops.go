@@ -4,6 +4,7 @@ package ops
 
 import (
 	"encoding/binary"
+	"image/color"
 	"math"
 
 	"gioui.org/ui"
@@ -16,8 +17,161 @@ func DecodeTransformOp(d []byte) ui.TransformOp {
 	if opconst.OpType(d[0]) != opconst.TypeTransform {
 		panic("invalid op")
 	}
-	return ui.TransformOp{}.Offset(f32.Point{
-		X: math.Float32frombits(bo.Uint32(d[1:])),
-		Y: math.Float32frombits(bo.Uint32(d[5:])),
-	})
+	f := func(off int) float32 {
+		return math.Float32frombits(bo.Uint32(d[off:]))
+	}
+	sx, hx, ox, hy, sy, oy := f(1), f(5), f(9), f(13), f(17), f(21)
+	return ui.Affine(f32.NewAffine2D(sx, hx, ox, hy, sy, oy))
+}
+
+// DecodeOpacityOp returns the opacity of a
+// paint.OpacityOp, clamped to [0;1].
+func DecodeOpacityOp(d []byte) float32 {
+	bo := binary.LittleEndian
+	if opconst.OpType(d[0]) != opconst.TypeLayer {
+		panic("invalid op")
+	}
+	o := math.Float32frombits(bo.Uint32(d[1:]))
+	switch {
+	case o > 1:
+		return 1
+	case o > 0:
+		return o
+	default:
+		// Includes NaN.
+		return 0
+	}
+}
+
+// Shadow is a decoded paint.ShadowOp.
+type Shadow struct {
+	// Rect is the shape of the shadow before blurring,
+	// with the offset and spread applied.
+	Rect   f32.Rectangle
+	Radius float32
+	// Sigma is the standard deviation of the blur.
+	Sigma float32
+	Color color.RGBA
+}
+
+func DecodeShadowOp(d []byte) Shadow {
+	bo := binary.LittleEndian
+	if opconst.OpType(d[0]) != opconst.TypeShadow {
+		panic("invalid op")
+	}
+	f := func(off int) float32 {
+		return math.Float32frombits(bo.Uint32(d[off:]))
+	}
+	r := f32.Rectangle{
+		Min: f32.Point{X: f(1), Y: f(5)},
+		Max: f32.Point{X: f(9), Y: f(13)},
+	}
+	radius := f(17)
+	off := f32.Point{X: f(21), Y: f(25)}
+	blur, spread := f(29), f(33)
+	r = r.Add(off)
+	r.Min = r.Min.Sub(f32.Point{X: spread, Y: spread})
+	r.Max = r.Max.Add(f32.Point{X: spread, Y: spread})
+	if r.Empty() {
+		r = f32.Rectangle{}
+	}
+	// Like CSS, rounded corners grow and shrink
+	// with the spread.
+	if radius > 0 {
+		radius += spread
+	}
+	if max := float32(math.Min(float64(r.Dx()), float64(r.Dy()))) / 2; radius > max {
+		radius = max
+	}
+	if radius < 0 {
+		radius = 0
+	}
+	if blur < 0 {
+		blur = 0
+	}
+	return Shadow{
+		Rect:   r,
+		Radius: radius,
+		Sigma:  blur / 2,
+		Color:  color.RGBA{R: d[37], G: d[38], B: d[39], A: d[40]},
+	}
+}
+
+// DecodeBlurOp returns the area of a paint.BlurOp and the
+// standard deviation of its blur.
+func DecodeBlurOp(d []byte) (f32.Rectangle, float32) {
+	bo := binary.LittleEndian
+	if opconst.OpType(d[0]) != opconst.TypeBlur {
+		panic("invalid op")
+	}
+	f := func(off int) float32 {
+		return math.Float32frombits(bo.Uint32(d[off:]))
+	}
+	r := f32.Rectangle{
+		Min: f32.Point{X: f(1), Y: f(5)},
+		Max: f32.Point{X: f(9), Y: f(13)},
+	}
+	radius := f(17)
+	if radius < 0 {
+		radius = 0
+	}
+	return r, radius / 2
+}
+
+// Gradient is a decoded paint.LinearGradientOp or
+// paint.RadialGradientOp.
+type Gradient struct {
+	Radial bool
+	// Trans maps points to gradient space. The offset along
+	// a linear gradient is the x coordinate of the mapped point,
+	// and the offset along a radial gradient is its distance
+	// to the origin.
+	Trans   f32.Affine2D
+	NStops  int
+	Offsets [opconst.MaxGradientStops]float32
+	Colors  [opconst.MaxGradientStops]color.RGBA
+}
+
+func DecodeGradientOp(d []byte) Gradient {
+	bo := binary.LittleEndian
+	f := func(off int) float32 {
+		return math.Float32frombits(bo.Uint32(d[off:]))
+	}
+	var g Gradient
+	var stops []byte
+	switch opconst.OpType(d[0]) {
+	case opconst.TypeLinearGradient:
+		start := f32.Point{X: f(1), Y: f(5)}
+		dir := f32.Point{X: f(9), Y: f(13)}.Sub(start)
+		if l := dir.X*dir.X + dir.Y*dir.Y; l > 0 {
+			// Project onto the gradient direction.
+			dir = dir.Mul(1 / l)
+			g.Trans = f32.NewAffine2D(
+				dir.X, dir.Y, -(start.X*dir.X + start.Y*dir.Y),
+				-dir.Y, dir.X, start.X*dir.Y-start.Y*dir.X,
+			)
+		} else {
+			g.Trans = f32.NewAffine2D(0, 0, 1, 0, 0, 0)
+		}
+		stops = d[17:]
+	case opconst.TypeRadialGradient:
+		c, r := f32.Point{X: f(1), Y: f(5)}, f(9)
+		if r > 0 {
+			g.Radial = true
+			g.Trans = f32.NewAffine2D(1/r, 0, -c.X/r, 0, 1/r, -c.Y/r)
+		} else {
+			g.Trans = f32.NewAffine2D(0, 0, 1, 0, 0, 0)
+		}
+		stops = d[13:]
+	default:
+		panic("invalid op")
+	}
+	g.NStops = int(stops[0])
+	stops = stops[1:]
+	for i := 0; i < g.NStops; i++ {
+		g.Offsets[i] = math.Float32frombits(bo.Uint32(stops))
+		g.Colors[i] = color.RGBA{R: stops[4], G: stops[5], B: stops[6], A: stops[7]}
+		stops = stops[8:]
+	}
+	return g
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 
 	"gioui.org/ui"
+	"gioui.org/ui/f32"
 	"gioui.org/ui/internal/opconst"
 )
 
@@ -29,6 +30,9 @@ type Key struct {
 	ops     *ui.Ops
 	pc      int
 	version int
+	// The linear part of a transformation applied to
+	// the op, if any.
+	sx, hx, hy, sy float32
 }
 
 // Shadow of ui.MacroOp.
@@ -57,6 +61,21 @@ type opAux struct {
 	len int
 }
 
+// SetTransform returns a key for the op transformed by the
+// scaling, shearing and rotation part of t.
+func (k Key) SetTransform(t f32.Affine2D) Key {
+	sx, hx, _, hy, sy, _ := t.Elems()
+	k.sx, k.hx, k.hy, k.sy = sx, hx, hy, sy
+	return k
+}
+
+// Stale reports whether the op list of the key was reset
+// after the key was created. Stale keys are never returned
+// by a Reader again.
+func (k Key) Stale() bool {
+	return k.ops != nil && k.ops.Version() != k.version
+}
+
 // Reset start reading from the op list.
 func (r *Reader) Reset(ops *ui.Ops) {
 	r.stack = r.stack[:0]
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
+)
+
+// shadow draws the blurred shape of s.
+func (r *Renderer) shadow(state drawState, s ops.Shadow) {
+	t := state.t.Affine2D()
+	// Scale the shadow shape and blur with the transformation.
+	scale := transformScale(t)
+	shape := transformBounds(t, s.Rect)
+	radius, sigma := s.Radius*scale, s.Sigma*scale
+	ext := blurExtent(sigma)
+	area := boundRectF(shape).Inset(-ext)
+	clip := boundRectF(state.clip).Intersect(area).Intersect(image.Rectangle{Max: r.bounds.Size()})
+	if clip.Empty() || shape.Empty() {
+		return
+	}
+	// Rasterize the shape with a margin for the blur.
+	w, h := area.Dx(), area.Dy()
+	buf := make([]linearColor, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := f32.Point{X: float32(area.Min.X+x) + .5, Y: float32(area.Min.Y+y) + .5}
+			c := clamp1(.5 - roundRectDist(shape, radius, p))
+			buf[y*w+x] = linearColor{c, c, c, c}
+		}
+	}
+	blur(buf, w, h, sigma)
+	col := gamma(s.Color.RGBA())
+	stride := r.bounds.Dx()
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		for x := clip.Min.X; x < clip.Max.X; x++ {
+			cov := buf[(y-area.Min.Y)*w+x-area.Min.X][3]
+			if state.mask != nil {
+				cov *= state.mask.at(x, y)
+			}
+			dst := &r.buf[y*stride+x]
+			a := 1 - col[3]*cov
+			for i := range dst {
+				dst[i] = col[i]*cov + dst[i]*a
+			}
+		}
+	}
+}
+
+// blurBackdrop blurs the pixels in bounds with the standard
+// deviation sigma, respecting the clip mask.
+func (r *Renderer) blurBackdrop(state drawState, sigma float32, bounds image.Rectangle) {
+	img := image.Rectangle{Max: r.bounds.Size()}
+	bounds = bounds.Intersect(img)
+	src := bounds.Inset(-blurExtent(sigma)).Intersect(img)
+	if bounds.Empty() {
+		return
+	}
+	w, h := src.Dx(), src.Dy()
+	stride := r.bounds.Dx()
+	buf := make([]linearColor, w*h)
+	for y := 0; y < h; y++ {
+		copy(buf[y*w:(y+1)*w], r.buf[(src.Min.Y+y)*stride+src.Min.X:])
+	}
+	blur(buf, w, h, sigma)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cov := float32(1)
+			if state.mask != nil {
+				cov = state.mask.at(x, y)
+			}
+			b := buf[(y-src.Min.Y)*w+x-src.Min.X]
+			dst := &r.buf[y*stride+x]
+			a := 1 - b[3]*cov
+			for i := range dst {
+				dst[i] = b[i]*cov + dst[i]*a
+			}
+		}
+	}
+}
+
+// blur the w×h image in buf with a separable Gaussian
+// blur. Pixels outside buf repeat the edge pixels.
+func blur(buf []linearColor, w, h int, sigma float32) {
+	weights := gaussian(sigma)
+	if len(weights) <= 1 {
+		return
+	}
+	line := make([]linearColor, w+h)
+	at := func(i, n int) linearColor {
+		switch {
+		case i < 0:
+			i = 0
+		case i >= n:
+			i = n - 1
+		}
+		return line[i]
+	}
+	pass := func(n, count, stride, step int) {
+		for l := 0; l < count; l++ {
+			start := l * stride
+			for i := 0; i < n; i++ {
+				line[i] = buf[start+i*step]
+			}
+			for i := 0; i < n; i++ {
+				var sum linearColor
+				for ch, c := range line[i] {
+					sum[ch] = c * weights[0]
+				}
+				for k := 1; k < len(weights); k++ {
+					a, b := at(i-k, n), at(i+k, n)
+					for ch := range sum {
+						sum[ch] += (a[ch] + b[ch]) * weights[k]
+					}
+				}
+				buf[start+i*step] = sum
+			}
+		}
+	}
+	// Horizontal pass over rows, then vertical
+	// pass over columns.
+	pass(w, h, w, 1)
+	pass(h, w, 1, w)
+}
+
+// gaussian returns the normalized weights of a Gaussian kernel with
+// standard deviation sigma, from the center tap outwards.
+func gaussian(sigma float32) []float32 {
+	n := blurExtent(sigma)
+	if n == 0 {
+		return []float32{1}
+	}
+	w := make([]float32, n+1)
+	var sum float32
+	for i := range w {
+		x := float64(i) / float64(sigma)
+		w[i] = float32(math.Exp(-x * x / 2))
+		sum += w[i]
+		if i > 0 {
+			sum += w[i]
+		}
+	}
+	for i := range w {
+		w[i] /= sum
+	}
+	return w
+}
+
+// transformScale returns the average scale factor of t.
+func transformScale(t f32.Affine2D) float32 {
+	sx, hx, _, hy, sy, _ := t.Elems()
+	return float32(math.Sqrt(math.Abs(float64(sx*sy - hx*hy))))
+}
+
+// blurExtent returns the number of pixels affected by a
+// Gaussian blur in each direction.
+func blurExtent(sigma float32) int {
+	return int(math.Ceil(float64(3 * sigma)))
+}
+
+// roundRectDist returns the signed distance from p to the
+// rectangle r with rounded corners of the given radius.
+func roundRectDist(r f32.Rectangle, radius float32, p f32.Point) float32 {
+	cx, cy := (r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2
+	qx := absf(p.X-cx) - r.Dx()/2 + radius
+	qy := absf(p.Y-cy) - r.Dy()/2 + radius
+	outside := float32(math.Hypot(float64(maxf(qx, 0)), float64(maxf(qy, 0))))
+	return outside + minf(maxf(qx, qy), 0) - radius
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import (
+	"image"
+	"math"
+	"sync"
+
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/opconst"
+	"gioui.org/ui/internal/ops"
+)
+
+// texture is a linearized copy of an image, sampled
+// like a GL_LINEAR sRGB texture.
+type texture struct {
+	// src is the source rectangle of the ImageOp.
+	src    image.Rectangle
+	bounds image.Rectangle
+	pix    []linearColor
+}
+
+var (
+	srgbOnce  sync.Once
+	srgbTable [256]float32
+)
+
+const linearTableSize = 4096
+
+var linearTable [linearTableSize + 1]uint8
+
+func initTables() {
+	for i := range srgbTable {
+		srgbTable[i] = srgbToLinear(float32(i) / 255)
+	}
+	for i := range linearTable {
+		c := float64(i) / linearTableSize
+		// Use the formula from EXT_sRGB.
+		if c <= 0.0031308 {
+			c = c * 12.92
+		} else {
+			c = 1.055*math.Pow(c, 1/2.4) - 0.055
+		}
+		linearTable[i] = uint8(c*255 + .5)
+	}
+}
+
+func newTexture(img image.Image, src image.Rectangle) *texture {
+	srgbOnce.Do(initTables)
+	b := img.Bounds()
+	t := &texture{
+		src:    src,
+		bounds: b,
+		pix:    make([]linearColor, b.Dx()*b.Dy()),
+	}
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			// Like sRGB textures, only the color
+			// components are linearized.
+			t.pix[i] = linearColor{
+				srgbTable[r>>8],
+				srgbTable[g>>8],
+				srgbTable[bl>>8],
+				float32(a) / 0xffff,
+			}
+			i++
+		}
+	}
+	return t
+}
+
+// sample the texture at p, where the texture source rectangle
+// is mapped to dr.
+func (t *texture) sample(dr f32.Rectangle, p f32.Point) linearColor {
+	u := (p.X-dr.Min.X)/dr.Dx()*float32(t.src.Dx()) + float32(t.src.Min.X) - .5
+	v := (p.Y-dr.Min.Y)/dr.Dy()*float32(t.src.Dy()) + float32(t.src.Min.Y) - .5
+	x0, y0 := float32(math.Floor(float64(u))), float32(math.Floor(float64(v)))
+	fx, fy := u-x0, v-y0
+	ix, iy := int(x0), int(y0)
+	c00 := t.at(ix, iy)
+	c10 := t.at(ix+1, iy)
+	c01 := t.at(ix, iy+1)
+	c11 := t.at(ix+1, iy+1)
+	var c linearColor
+	for i := range c {
+		top := c00[i]*(1-fx) + c10[i]*fx
+		bottom := c01[i]*(1-fx) + c11[i]*fx
+		c[i] = top*(1-fy) + bottom*fy
+	}
+	return c
+}
+
+// at returns the texel at (x, y), clamped to the edges
+// of the texture.
+func (t *texture) at(x, y int) linearColor {
+	b := t.bounds
+	if x < b.Min.X {
+		x = b.Min.X
+	}
+	if x >= b.Max.X {
+		x = b.Max.X - 1
+	}
+	if y < b.Min.Y {
+		y = b.Min.Y
+	}
+	if y >= b.Max.Y {
+		y = b.Max.Y - 1
+	}
+	return t.pix[(y-b.Min.Y)*b.Dx()+x-b.Min.X]
+}
+
+// gamma linearizes a color the same way as the
+// GPU renderer.
+func gamma(r, g, b, a uint32) linearColor {
+	c := linearColor{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+	for i, v := range c {
+		c[i] = srgbToLinear(v)
+	}
+	return c
+}
+
+func srgbToLinear(c float32) float32 {
+	// Use the formula from EXT_sRGB.
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return float32(math.Pow(float64((c+0.055)/1.055), 2.4))
+}
+
+func linearToSRGB(c float32) uint8 {
+	srgbOnce.Do(initTables)
+	return linearTable[int(clamp1(c)*linearTableSize+.5)]
+}
+
+// gradient is a linearized gradient material.
+type gradient struct {
+	radial  bool
+	trans   f32.Affine2D
+	n       int
+	offsets [opconst.MaxGradientStops]float32
+	colors  [opconst.MaxGradientStops]linearColor
+}
+
+func newGradient(g *ops.Gradient) *gradient {
+	grad := &gradient{
+		radial:  g.Radial,
+		trans:   g.Trans,
+		n:       g.NStops,
+		offsets: g.Offsets,
+	}
+	for i := 0; i < g.NStops; i++ {
+		grad.colors[i] = gamma(g.Colors[i].RGBA())
+	}
+	return grad
+}
+
+// at evaluates the gradient at p, in the same way as the
+// gradient programs of the GPU renderer.
+func (g *gradient) at(p f32.Point) linearColor {
+	if g.n == 0 {
+		return linearColor{}
+	}
+	p = g.trans.Transform(p)
+	t := p.X
+	if g.radial {
+		t = float32(math.Hypot(float64(p.X), float64(p.Y)))
+	}
+	c := g.colors[0]
+	for i := 1; i < g.n; i++ {
+		o0, o1 := g.offsets[i-1], g.offsets[i]
+		f := clamp1((t - o0) / maxf(o1-o0, 1e-4))
+		for j := range c {
+			c[j] += (g.colors[i][j] - c[j]) * f
+		}
+	}
+	return c
+}
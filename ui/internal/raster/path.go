@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/ui/f32"
+)
+
+// accumulator computes anti-aliased path coverage by accumulating
+// signed areas, in the style of font-rs and golang.org/x/image/vector.
+//
+// Like the stencil passes of the GPU renderer, coverage is integrated
+// vertically. The accumulator therefore works in a transposed space
+// where the roles of the x and y axes are swapped: every row of acc
+// corresponds to a column of the mask and areas are accumulated
+// downwards.
+type accumulator struct {
+	size image.Point
+	// stride is the length of a transposed row, with
+	// room for contributions right of the last pixel.
+	stride int
+	acc    []float32
+}
+
+func (a *accumulator) reset(size image.Point) {
+	a.size = size
+	a.stride = size.Y + 2
+	n := size.X * a.stride
+	if cap(a.acc) < n {
+		a.acc = make([]float32, n)
+	}
+	a.acc = a.acc[:n]
+	for i := range a.acc {
+		a.acc[i] = 0
+	}
+}
+
+// quadTo adds a quadratic Bézier curve from, ctrl, to by
+// flattening it to lines.
+func (a *accumulator) quadTo(from, ctrl, to f32.Point) {
+	// The maximum deviation of a quadratic Bézier from its
+	// chord is a quarter of the length of from - 2ctrl + to.
+	dev := from.Sub(ctrl.Mul(2)).Add(to)
+	devsq := dev.X*dev.X + dev.Y*dev.Y
+	if devsq < 0.333 {
+		a.lineTo(from, to)
+		return
+	}
+	const tol = 3
+	n := 1 + int(math.Sqrt(math.Sqrt(float64(tol*devsq))))
+	t, dt := float32(0), 1/float32(n)
+	pen := from
+	for i := 1; i < n; i++ {
+		t += dt
+		// De Casteljau evaluation.
+		c0 := from.Add(ctrl.Sub(from).Mul(t))
+		c1 := ctrl.Add(to.Sub(ctrl).Mul(t))
+		p := c0.Add(c1.Sub(c0).Mul(t))
+		a.lineTo(pen, p)
+		pen = p
+	}
+	a.lineTo(pen, to)
+}
+
+// lineTo accumulates the line from p0 to p1.
+func (a *accumulator) lineTo(p0, p1 f32.Point) {
+	// Transpose.
+	p0.X, p0.Y = p0.Y, p0.X
+	p1.X, p1.Y = p1.Y, p1.X
+	if p0.Y == p1.Y {
+		return
+	}
+	dir := float32(1)
+	if p0.Y > p1.Y {
+		dir, p0, p1 = -1, p1, p0
+	}
+	width, height := float32(a.size.Y), a.size.X
+	dxdy := (p1.X - p0.X) / (p1.Y - p0.Y)
+	y0 := int(math.Floor(float64(p0.Y)))
+	if y0 < 0 {
+		y0 = 0
+	}
+	y1 := int(math.Ceil(float64(p1.Y)))
+	if y1 > height {
+		y1 = height
+	}
+	for y := y0; y < y1; y++ {
+		row := a.acc[y*a.stride : (y+1)*a.stride]
+		ystart := maxf(float32(y), p0.Y)
+		yend := minf(float32(y+1), p1.Y)
+		dy := yend - ystart
+		if dy <= 0 {
+			continue
+		}
+		x := p0.X + dxdy*(ystart-p0.Y)
+		xnext := p0.X + dxdy*(yend-p0.Y)
+		d := dy * dir
+		// Contributions left of the area belong to the first
+		// pixel, and contributions to the right are dropped.
+		x0, x1 := clampf(x, 0, width), clampf(xnext, 0, width)
+		if x0 > x1 {
+			x0, x1 = x1, x0
+		}
+		x0floor := float32(math.Floor(float64(x0)))
+		x0i := int(x0floor)
+		x1ceil := float32(math.Ceil(float64(x1)))
+		x1i := int(x1ceil)
+		if x1i <= x0i+1 {
+			xmf := 0.5*(x0+x1) - x0floor
+			row[x0i] += d - d*xmf
+			row[x0i+1] += d * xmf
+			continue
+		}
+		s := 1 / (x1 - x0)
+		x0f := x0 - x0floor
+		oneMinusX0f := 1 - x0f
+		a0 := 0.5 * s * oneMinusX0f * oneMinusX0f
+		x1f := x1 - x1ceil + 1
+		am := 0.5 * s * x1f * x1f
+		row[x0i] += d * a0
+		if x1i == x0i+2 {
+			row[x0i+1] += d * (1 - a0 - am)
+		} else {
+			a1 := s * (1.5 - x0f)
+			row[x0i+1] += d * (a1 - a0)
+			for xi := x0i + 2; xi < x1i-1; xi++ {
+				row[xi] += d * s
+			}
+			a2 := a1 + s*float32(x1i-x0i-3)
+			row[x1i-1] += d * (1 - a2 - am)
+		}
+		row[x1i] += d * am
+	}
+}
+
+// coverage resolves the accumulated areas into cov, which
+// is laid out in row-major order in the untransposed space.
+// The winding numbers are resolved with the even-odd rule if
+// evenOdd is set, the nonzero rule otherwise.
+func (a *accumulator) coverage(cov []float32, evenOdd bool) {
+	w := a.size.X
+	for x := 0; x < a.size.X; x++ {
+		row := a.acc[x*a.stride : (x+1)*a.stride]
+		var sum float32
+		for y := 0; y < a.size.Y; y++ {
+			sum += row[y]
+			// Resolve like the GPU cover programs.
+			if evenOdd {
+				cov[y*w+x] = 1 - absf(1-float32(math.Mod(float64(absf(sum)), 2)))
+			} else {
+				cov[y*w+x] = clamp1(absf(sum))
+			}
+		}
+	}
+}
+
+func absf(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func minf(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxf(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func clampf(v, min, max float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,446 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package raster implements a software renderer for operation lists.
+
+The renderer walks an operation list the same way as the GPU renderer
+in gioui.org/ui/app/internal/gpu and produces an anti-aliased image.
+Blending is done in linear color space and the result is stored as sRGB,
+matching the output of the GPU renderer within a small tolerance.
+
+The renderer is intended for headless screenshots, tests and
+platforms without OpenGL support. It is not optimized for speed.
+*/
+package raster
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/opconst"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/internal/path"
+	"gioui.org/ui/paint"
+)
+
+// Renderer renders operation lists to images. The zero
+// Renderer is ready to use.
+type Renderer struct {
+	reader ops.Reader
+	// Linear, premultiplied color of the
+	// image being rendered.
+	buf    []linearColor
+	bounds image.Rectangle
+	acc    accumulator
+}
+
+type drawState struct {
+	clip f32.Rectangle
+	t    ui.TransformOp
+	// mask is the coverage of the current clip
+	// path, or nil if the clip is rectangular.
+	mask *mask
+
+	// Current ImageOp image and rect, if any.
+	img     image.Image
+	imgRect image.Rectangle
+	// Current ColorOp, if any.
+	color color.RGBA
+	// Current gradient, if any.
+	grad *ops.Gradient
+}
+
+// mask is a coverage mask for a rectangular area.
+type mask struct {
+	rect image.Rectangle
+	cov  []float32
+}
+
+type linearColor [4]float32
+
+// Render is a convenience function for rendering an operation
+// list to a new image of the given size.
+func Render(root *ui.Ops, size image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	new(Renderer).Draw(img, root)
+	return img
+}
+
+// Draw renders the operation list to dst. Like the GPU renderer,
+// the image is cleared to opaque white before drawing. The origin
+// of the operation list coordinate space is mapped to the top left
+// corner of dst.
+func (r *Renderer) Draw(dst *image.RGBA, root *ui.Ops) {
+	r.bounds = dst.Bounds()
+	size := r.bounds.Size()
+	n := size.X * size.Y
+	if cap(r.buf) < n {
+		r.buf = make([]linearColor, n)
+	}
+	r.buf = r.buf[:n]
+	white := linearColor{1, 1, 1, 1}
+	for i := range r.buf {
+		r.buf[i] = white
+	}
+	r.reader.Reset(root)
+	state := drawState{
+		clip: f32.Rectangle{
+			Max: f32.Point{X: float32(size.X), Y: float32(size.Y)},
+		},
+		color: color.RGBA{A: 0xff},
+	}
+	r.collectOps(&r.reader, state)
+	r.store(dst)
+}
+
+func (r *Renderer) collectOps(rd *ops.Reader, state drawState) {
+	var aux []byte
+loop:
+	for encOp, ok := rd.Decode(); ok; encOp, ok = rd.Decode() {
+		switch opconst.OpType(encOp.Data[0]) {
+		case opconst.TypeTransform:
+			op := ops.DecodeTransformOp(encOp.Data)
+			state.t = state.t.Multiply(ui.TransformOp(op))
+		case opconst.TypeAux:
+			aux = encOp.Data[opconst.TypeAuxLen:]
+		case opconst.TypeClip:
+			bounds, evenOdd := decodeClipOp(encOp.Data)
+			t := state.t.Affine2D()
+			state.clip = state.clip.Intersect(transformBounds(t, bounds))
+			if !state.clip.Empty() {
+				switch {
+				case len(aux) > 0:
+					state.mask = r.pathMask(state, aux, evenOdd)
+				case !t.IsAxisAligned():
+					state.mask = r.rectMask(state, bounds)
+				}
+			}
+			aux = nil
+		case opconst.TypeColor:
+			state.img = nil
+			state.grad = nil
+			state.color = decodeColorOp(encOp.Data)
+		case opconst.TypeLinearGradient, opconst.TypeRadialGradient:
+			g := ops.DecodeGradientOp(encOp.Data)
+			state.img = nil
+			state.grad = &g
+		case opconst.TypeImage:
+			state.grad = nil
+			state.img, state.imgRect = decodeImageOp(encOp.Data, encOp.Refs)
+		case opconst.TypePaint:
+			rect := decodePaintOp(encOp.Data)
+			t := state.t.Affine2D()
+			clip := state.clip.Intersect(transformBounds(t, rect))
+			if clip.Empty() {
+				continue
+			}
+			pstate := state
+			if !t.IsAxisAligned() {
+				pstate.clip = clip
+				pstate.mask = r.rectMask(pstate, rect)
+			}
+			r.paint(pstate, rect, boundRectF(clip))
+		case opconst.TypeShadow:
+			r.shadow(state, ops.DecodeShadowOp(encOp.Data))
+		case opconst.TypeBlur:
+			rect, sigma := ops.DecodeBlurOp(encOp.Data)
+			t := state.t.Affine2D()
+			clip := state.clip.Intersect(transformBounds(t, rect))
+			if clip.Empty() {
+				continue
+			}
+			pstate := state
+			if !t.IsAxisAligned() {
+				pstate.clip = clip
+				pstate.mask = r.rectMask(pstate, rect)
+			}
+			r.blurBackdrop(pstate, sigma*transformScale(t), boundRectF(clip))
+		case opconst.TypeLayer:
+			switch o := ops.DecodeOpacityOp(encOp.Data); {
+			case o == 0:
+				// Skip the invisible operations.
+				state.clip = f32.Rectangle{}
+			case o < 1:
+				r.layer(rd, state, o)
+				break loop
+			}
+		case opconst.TypePush:
+			r.collectOps(rd, state)
+		case opconst.TypePop:
+			break loop
+		}
+	}
+}
+
+// layer draws the remaining operations of the current stack
+// level into a transparent layer and blends the layer with
+// the given opacity.
+func (r *Renderer) layer(rd *ops.Reader, state drawState, opacity float32) {
+	parent := r.buf
+	r.buf = make([]linearColor, len(parent))
+	r.collectOps(rd, state)
+	layer := r.buf
+	r.buf = parent
+	bounds := boundRectF(state.clip).Intersect(image.Rectangle{Max: r.bounds.Size()})
+	w := r.bounds.Dx()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			src := layer[y*w+x]
+			dst := &r.buf[y*w+x]
+			a := 1 - src[3]*opacity
+			for i := range dst {
+				dst[i] = src[i]*opacity + dst[i]*a
+			}
+		}
+	}
+}
+
+// pathMask rasterizes the path described by the encoded
+// vertices in aux with the given fill rule and intersects it
+// with the current clip.
+func (r *Renderer) pathMask(state drawState, aux []byte, evenOdd bool) *mask {
+	m, origin := r.beginMask(state)
+	bo := binary.LittleEndian
+	// Each quadratic curve is encoded as 4 vertices, one for each
+	// corner of its bounding quad. Only the first is needed.
+	for len(aux) >= path.VertStride {
+		from := f32.Point{
+			X: math.Float32frombits(bo.Uint32(aux[8:])),
+			Y: math.Float32frombits(bo.Uint32(aux[12:])),
+		}
+		ctrl := f32.Point{
+			X: math.Float32frombits(bo.Uint32(aux[16:])),
+			Y: math.Float32frombits(bo.Uint32(aux[20:])),
+		}
+		to := f32.Point{
+			X: math.Float32frombits(bo.Uint32(aux[24:])),
+			Y: math.Float32frombits(bo.Uint32(aux[28:])),
+		}
+		from = state.t.Transform(from).Sub(origin)
+		ctrl = state.t.Transform(ctrl).Sub(origin)
+		to = state.t.Transform(to).Sub(origin)
+		r.acc.quadTo(from, ctrl, to)
+		aux = aux[path.VertStride*4:]
+	}
+	return r.endMask(state, m, evenOdd)
+}
+
+// rectMask rasterizes rect transformed by the current
+// transformation and intersects it with the current clip.
+func (r *Renderer) rectMask(state drawState, rect f32.Rectangle) *mask {
+	m, origin := r.beginMask(state)
+	corners := [...]f32.Point{
+		rect.Min,
+		{X: rect.Max.X, Y: rect.Min.Y},
+		rect.Max,
+		{X: rect.Min.X, Y: rect.Max.Y},
+	}
+	for i, c := range corners {
+		from := state.t.Transform(c).Sub(origin)
+		to := state.t.Transform(corners[(i+1)%len(corners)]).Sub(origin)
+		r.acc.lineTo(from, to)
+	}
+	return r.endMask(state, m, false)
+}
+
+// beginMask prepares the accumulator for a mask covering
+// the current clip. The accumulator works in a space relative
+// to the returned origin.
+func (r *Renderer) beginMask(state drawState) (*mask, f32.Point) {
+	rect := boundRectF(state.clip)
+	m := &mask{
+		rect: rect,
+		cov:  make([]float32, rect.Dx()*rect.Dy()),
+	}
+	r.acc.reset(rect.Size())
+	return m, f32.Point{X: float32(rect.Min.X), Y: float32(rect.Min.Y)}
+}
+
+// endMask resolves the accumulated coverage into m and
+// intersects it with the mask of the current clip, if any.
+func (r *Renderer) endMask(state drawState, m *mask, evenOdd bool) *mask {
+	rect := m.rect
+	r.acc.coverage(m.cov, evenOdd)
+	if p := state.mask; p != nil {
+		w := rect.Dx()
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				i := (y-rect.Min.Y)*w + x - rect.Min.X
+				m.cov[i] *= p.at(x, y)
+			}
+		}
+	}
+	return m
+}
+
+func (m *mask) at(x, y int) float32 {
+	if !(image.Point{X: x, Y: y}).In(m.rect) {
+		return 0
+	}
+	return m.cov[(y-m.rect.Min.Y)*m.rect.Dx()+x-m.rect.Min.X]
+}
+
+// paint fills the pixels in bounds with the current material. The
+// destination rectangle of the material is dr, in the coordinate
+// space of the current transformation.
+func (r *Renderer) paint(state drawState, dr f32.Rectangle, bounds image.Rectangle) {
+	inv := state.t.Invert()
+	bounds = bounds.Intersect(image.Rectangle{Max: r.bounds.Size()})
+	var col linearColor
+	var tex *texture
+	var grad *gradient
+	switch img := state.img.(type) {
+	case nil:
+		if state.grad != nil {
+			grad = newGradient(state.grad)
+			break
+		}
+		col = gamma(state.color.RGBA())
+	case *image.Uniform:
+		col = gamma(img.RGBA())
+	default:
+		tex = newTexture(img, state.imgRect)
+	}
+	w := r.bounds.Dx()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cov := float32(1)
+			if state.mask != nil {
+				cov = state.mask.at(x, y)
+				if cov == 0 {
+					continue
+				}
+			}
+			src := col
+			if tex != nil || grad != nil {
+				// Map the pixel center to the material.
+				p := f32.Point{X: float32(x) + .5, Y: float32(y) + .5}
+				p = inv.Transform(p)
+				if tex != nil {
+					src = tex.sample(dr, p)
+				} else {
+					src = grad.at(p)
+				}
+			}
+			dst := &r.buf[y*w+x]
+			a := 1 - src[3]*cov
+			for i := range dst {
+				dst[i] = src[i]*cov + dst[i]*a
+			}
+		}
+	}
+}
+
+// store converts the linear buffer to sRGB and copies it to dst.
+func (r *Renderer) store(dst *image.RGBA) {
+	w, h := r.bounds.Dx(), r.bounds.Dy()
+	for y := 0; y < h; y++ {
+		row := dst.Pix[dst.PixOffset(r.bounds.Min.X, r.bounds.Min.Y+y):]
+		for x := 0; x < w; x++ {
+			c := r.buf[y*w+x]
+			p := row[x*4 : x*4+4]
+			p[0] = linearToSRGB(c[0])
+			p[1] = linearToSRGB(c[1])
+			p[2] = linearToSRGB(c[2])
+			p[3] = uint8(clamp1(c[3])*255 + .5)
+		}
+	}
+}
+
+// decodeClipOp returns the bounds of a clip and whether
+// it uses the even-odd fill rule.
+func decodeClipOp(data []byte) (f32.Rectangle, bool) {
+	if opconst.OpType(data[0]) != opconst.TypeClip {
+		panic("invalid op")
+	}
+	return decodeRect(data[1:]), data[17] == byte(paint.EvenOdd)
+}
+
+func decodePaintOp(data []byte) f32.Rectangle {
+	if opconst.OpType(data[0]) != opconst.TypePaint {
+		panic("invalid op")
+	}
+	return decodeRect(data[1:])
+}
+
+func decodeColorOp(data []byte) color.RGBA {
+	if opconst.OpType(data[0]) != opconst.TypeColor {
+		panic("invalid op")
+	}
+	return color.RGBA{
+		R: data[1],
+		G: data[2],
+		B: data[3],
+		A: data[4],
+	}
+}
+
+func decodeImageOp(data []byte, refs []interface{}) (image.Image, image.Rectangle) {
+	bo := binary.LittleEndian
+	if opconst.OpType(data[0]) != opconst.TypeImage {
+		panic("invalid op")
+	}
+	sr := image.Rectangle{
+		Min: image.Point{
+			X: int(int32(bo.Uint32(data[1:]))),
+			Y: int(int32(bo.Uint32(data[5:]))),
+		},
+		Max: image.Point{
+			X: int(int32(bo.Uint32(data[9:]))),
+			Y: int(int32(bo.Uint32(data[13:]))),
+		},
+	}
+	return refs[0].(image.Image), sr
+}
+
+func decodeRect(data []byte) f32.Rectangle {
+	bo := binary.LittleEndian
+	return f32.Rectangle{
+		Min: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[0:])),
+			Y: math.Float32frombits(bo.Uint32(data[4:])),
+		},
+		Max: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[8:])),
+			Y: math.Float32frombits(bo.Uint32(data[12:])),
+		},
+	}
+}
+
+// transformBounds returns the bounds of the
+// rectangle r transformed by t.
+func transformBounds(t f32.Affine2D, r f32.Rectangle) f32.Rectangle {
+	b := f32.Rectangle{Min: t.Transform(r.Min), Max: t.Transform(r.Max)}.Canon()
+	return b.Union(f32.Rectangle{
+		Min: t.Transform(f32.Point{X: r.Max.X, Y: r.Min.Y}),
+		Max: t.Transform(f32.Point{X: r.Min.X, Y: r.Max.Y}),
+	}.Canon())
+}
+
+// boundRectF returns a bounding image.Rectangle for a f32.Rectangle.
+func boundRectF(r f32.Rectangle) image.Rectangle {
+	return image.Rectangle{
+		Min: image.Point{
+			X: int(math.Floor(float64(r.Min.X))),
+			Y: int(math.Floor(float64(r.Min.Y))),
+		},
+		Max: image.Point{
+			X: int(math.Ceil(float64(r.Max.X))),
+			Y: int(math.Ceil(float64(r.Max.Y))),
+		},
+	}
+}
+
+func clamp1(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/paint"
+)
+
+func TestPaintColor(t *testing.T) {
+	ops := new(ui.Ops)
+	paint.ColorOp{Color: color.RGBA{R: 0xff, A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 5, Y: 5}}}.Add(ops)
+	img := Render(ops, image.Point{X: 10, Y: 10})
+	if got, exp := img.RGBAAt(2, 2), (color.RGBA{R: 0xff, A: 0xff}); got != exp {
+		t.Errorf("inside: got %v, expected %v", got, exp)
+	}
+	if got, exp := img.RGBAAt(7, 7), (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}); got != exp {
+		t.Errorf("outside: got %v, expected %v", got, exp)
+	}
+}
+
+func TestTransformAndClip(t *testing.T) {
+	ops := new(ui.Ops)
+	var stack ui.StackOp
+	stack.Push(ops)
+	ui.TransformOp{}.Offset(f32.Point{X: 4, Y: 4}).Add(ops)
+	paint.RectClip(image.Rectangle{Max: image.Point{X: 2, Y: 2}}).Add(ops)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(ops)
+	stack.Pop()
+	img := Render(ops, image.Point{X: 10, Y: 10})
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			inside := x >= 4 && x < 6 && y >= 4 && y < 6
+			black := img.RGBAAt(x, y).R == 0
+			if inside != black {
+				t.Errorf("(%d,%d): inside %v, painted %v", x, y, inside, black)
+			}
+		}
+	}
+}
+
+func TestPathCoverage(t *testing.T) {
+	ops := new(ui.Ops)
+	// A triangle covering the lower left half of a 10x10 square.
+	var p paint.PathBuilder
+	p.Init(ops)
+	p.Move(f32.Point{X: 0, Y: 0})
+	p.Line(f32.Point{X: 10, Y: 10})
+	p.Line(f32.Point{X: -10, Y: 0})
+	p.Line(f32.Point{X: 0, Y: -10})
+	p.End()
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(ops)
+	img := Render(ops, image.Point{X: 10, Y: 10})
+	if r := img.RGBAAt(1, 8).R; r != 0 {
+		t.Errorf("inside pixel not covered, got %d", r)
+	}
+	if r := img.RGBAAt(8, 1).R; r != 0xff {
+		t.Errorf("outside pixel covered, got %d", r)
+	}
+	// Pixels on the diagonal are half covered. Half coverage
+	// of black on white is linear 0.5, or about 188 in sRGB.
+	if r := img.RGBAAt(5, 5).R; r < 180 || r > 196 {
+		t.Errorf("diagonal pixel not anti-aliased, got %d", r)
+	}
+}
+
+func TestImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	src.Set(1, 0, color.RGBA{B: 0xff, A: 0xff})
+	ops := new(ui.Ops)
+	paint.ImageOp{Src: src, Rect: src.Bounds()}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 10}}}.Add(ops)
+	img := Render(ops, image.Point{X: 20, Y: 10})
+	if got := img.RGBAAt(1, 5); got.R != 0xff || got.B != 0 {
+		t.Errorf("left: got %v", got)
+	}
+	if got := img.RGBAAt(18, 5); got.R != 0 || got.B != 0xff {
+		t.Errorf("right: got %v", got)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ops := new(ui.Ops)
+	// Rotate a 10x2 bar by 90 degrees around its center,
+	// resulting in a 2x10 bar.
+	center := f32.Point{X: 10, Y: 10}
+	rot := f32.Affine2D{}.Rotate(center, math.Pi/2)
+	ui.Affine(rot).Add(ops)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{
+		Min: f32.Point{X: 5, Y: 9},
+		Max: f32.Point{X: 15, Y: 11},
+	}}.Add(ops)
+	img := Render(ops, image.Point{X: 20, Y: 20})
+	if r := img.RGBAAt(10, 6).R; r != 0 {
+		t.Errorf("inside pixel not covered, got %d", r)
+	}
+	if r := img.RGBAAt(6, 10).R; r != 0xff {
+		t.Errorf("outside pixel covered, got %d", r)
+	}
+}
+
+func TestLinearGradient(t *testing.T) {
+	ops := new(ui.Ops)
+	paint.LinearGradientOp{
+		Start: f32.Point{X: 0},
+		End:   f32.Point{X: 10},
+		Stops: []paint.GradientStop{
+			{Offset: 0, Color: color.RGBA{A: 0xff}},
+			{Offset: 1, Color: color.RGBA{R: 0xff, A: 0xff}},
+		},
+	}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 10}}}.Add(ops)
+	img := Render(ops, image.Point{X: 20, Y: 10})
+	if r := img.RGBAAt(0, 5).R; r > 0x40 {
+		t.Errorf("start: got %d", r)
+	}
+	// The middle is linear 0.5, or about 188 in sRGB.
+	if r := img.RGBAAt(5, 5).R; r < 180 || r > 196 {
+		t.Errorf("middle not interpolated in linear space, got %d", r)
+	}
+	if r := img.RGBAAt(15, 5).R; r != 0xff {
+		t.Errorf("past end: got %d", r)
+	}
+}
+
+func TestRadialGradient(t *testing.T) {
+	ops := new(ui.Ops)
+	paint.RadialGradientOp{
+		Center: f32.Point{X: 10, Y: 10},
+		Radius: 5,
+		Stops: []paint.GradientStop{
+			{Offset: 0, Color: color.RGBA{A: 0xff}},
+			{Offset: 1, Color: color.RGBA{B: 0xff, A: 0xff}},
+		},
+	}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	img := Render(ops, image.Point{X: 20, Y: 20})
+	if b := img.RGBAAt(10, 10).B; b > 0x80 {
+		t.Errorf("center: got %d", b)
+	}
+	if b := img.RGBAAt(1, 1).B; b != 0xff {
+		t.Errorf("outside: got %d", b)
+	}
+}
+
+func TestOpacity(t *testing.T) {
+	ops := new(ui.Ops)
+	square := func(x float32) {
+		paint.PaintOp{Rect: f32.Rectangle{
+			Min: f32.Point{X: x, Y: 0},
+			Max: f32.Point{X: x + 6, Y: 10},
+		}}.Add(ops)
+	}
+	var stack ui.StackOp
+	stack.Push(ops)
+	paint.OpacityOp{Opacity: .5}.Add(ops)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	// Overlapping squares.
+	square(0)
+	square(4)
+	stack.Pop()
+	// Operations after the layer are opaque.
+	square(14)
+	img := Render(ops, image.Point{X: 20, Y: 10})
+	single, overlap := img.RGBAAt(2, 5), img.RGBAAt(5, 5)
+	if single != overlap {
+		t.Errorf("overlapping squares blended twice: %v, %v", single, overlap)
+	}
+	if single.R < 0xa0 || single.R > 0xd0 {
+		t.Errorf("got %v, expected a 50%% gray", single)
+	}
+	if got := img.RGBAAt(16, 5); got.R != 0 {
+		t.Errorf("got %v after layer, expected black", got)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	ops := new(ui.Ops)
+	paint.ShadowOp{
+		Rect:   f32.Rectangle{Min: f32.Point{X: 10, Y: 10}, Max: f32.Point{X: 30, Y: 30}},
+		Offset: f32.Point{X: 4, Y: 0},
+		Blur:   4,
+		Color:  color.RGBA{A: 0xff},
+	}.Add(ops)
+	img := Render(ops, image.Point{X: 40, Y: 40})
+	if got := img.RGBAAt(24, 20); got.R != 0 {
+		t.Errorf("got %v inside shadow, expected black", got)
+	}
+	if got := img.RGBAAt(2, 20); got.R != 0xff {
+		t.Errorf("got %v outside shadow, expected white", got)
+	}
+	// The offset edge is blurred.
+	edge := img.RGBAAt(34, 20)
+	if edge.R < 0x40 || edge.R > 0xe0 {
+		t.Errorf("got %v at shadow edge, expected gray", edge)
+	}
+	if left, right := img.RGBAAt(10, 20), img.RGBAAt(30, 20); left.R <= right.R {
+		t.Errorf("got %v and %v, expected shadow to be offset", left, right)
+	}
+}
+
+func TestBlur(t *testing.T) {
+	ops := new(ui.Ops)
+	// Black left half, white right half.
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 10}}}.Add(ops)
+	paint.BlurOp{
+		Rect:   f32.Rectangle{Min: f32.Point{X: 10}, Max: f32.Point{X: 30, Y: 10}},
+		Radius: 4,
+	}.Add(ops)
+	img := Render(ops, image.Point{X: 40, Y: 10})
+	if got := img.RGBAAt(19, 5); got.R == 0 || got.R == 0xff {
+		t.Errorf("got %v at blurred edge, expected gray", got)
+	}
+	// Outside the blur rectangle the edge is sharp.
+	if got := img.RGBAAt(5, 5); got.R != 0 {
+		t.Errorf("got %v outside blur, expected black", got)
+	}
+	if got := img.RGBAAt(35, 5); got.R != 0xff {
+		t.Errorf("got %v outside blur, expected white", got)
+	}
+}
@@ -11,6 +11,11 @@ input to receive events.
 package key
 
 import (
+	"encoding/binary"
+	"image"
+	"strings"
+	"time"
+
 	"gioui.org/ui"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/opconst"
@@ -20,11 +25,59 @@ import (
 // Key events are in general only delivered to the
 // focused key handler. Set the Focus flag to request
 // the focus.
+//
+// The Tab and Shift-Tab keys move the focus along the
+// focus order, unless the focused handler sets Tab.
+// Handlers with a positive TabIndex come first, in
+// increasing TabIndex order. Handlers with a zero
+// TabIndex follow in the order of their InputOps.
+// Handlers with a negative TabIndex are skipped.
 type InputOp struct {
-	Key   input.Key
-	Focus bool
+	Key      input.Key
+	Focus    bool
+	TabIndex int
+	// Tab, if set, delivers Tab and Shift-Tab to the handler
+	// when it is focused, instead of moving the focus.
+	Tab bool
+}
+
+// FocusOp moves the focus to a handler. Like the
+// Focus flag of InputOp, FocusOp should only be added
+// in the frame where the focus is to be moved.
+type FocusOp struct {
+	// Key is the handler to focus. Keys without an
+	// InputOp in the same frame are ignored.
+	Key input.Key
+}
+
+// MoveFocusOp moves the focus along the focus order,
+// wrapping around at the ends.
+type MoveFocusOp struct {
+	Dir FocusDirection
 }
 
+// ShortcutOp registers a key combination for a handler.
+// Key press events matching Name and Modifiers are delivered
+// to the handler instead of the focused handler. The release
+// of the key is delivered to the same handler.
+//
+// A ShortcutOp is in scope when it is part of a StackOp that
+// contains the focused handler, or when it is outside every
+// StackOp. Without a focused handler, every ShortcutOp is in
+// scope. Of the matching shortcuts in scope, the innermost
+// wins; ties go to the last ShortcutOp.
+type ShortcutOp struct {
+	Key       input.Key
+	Name      rune
+	Modifiers Modifiers
+	// Pass, if set, delivers the matching events to the
+	// focused handler as well.
+	Pass bool
+}
+
+// FocusDirection is the direction of a focus move.
+type FocusDirection uint8
+
 // HideInputOp request that any on screen text input
 // be hidden.
 type HideInputOp struct{}
@@ -35,8 +88,8 @@ type FocusEvent struct {
 	Focus bool
 }
 
-// An Event is generated when a key is pressed. For text input
-// use EditEvent.
+// An Event is generated when a key is pressed or released.
+// For text input use EditEvent.
 type Event struct {
 	// Name is the rune character that most closely
 	// match the key. For letters, the upper case form
@@ -45,16 +98,62 @@ type Event struct {
 	// Modifiers is the set of active modifiers when
 	// the key was pressed.
 	Modifiers Modifiers
+	// State is the state of the key when the event
+	// was generated.
+	State State
+	// Repeat is set for Press events generated by
+	// the key repeat of a held key.
+	Repeat bool
+	// Time is when the event was received. The
+	// timestamp is relative to an undefined base.
+	Time time.Duration
 }
 
 // An EditEvent is generated when text is input.
 type EditEvent struct {
 	Text string
+	// DeleteBefore and DeleteAfter are the number of
+	// bytes to delete before and after the caret before
+	// Text is inserted.
+	DeleteBefore, DeleteAfter int
+}
+
+// A PreeditEvent is generated when the text composed by an
+// input method changes. The preedit text is not part of the
+// content until an EditEvent commits it. Handlers usually
+// display the preedit text at the caret, underlined.
+type PreeditEvent struct {
+	// Text is the text being composed. An empty Text ends
+	// the composition.
+	Text string
+	// Begin and End is the cursor range in Text, in bytes.
+	// A negative Begin or End hides the cursor.
+	Begin, End int
+}
+
+// TextInputOp describes the caret and surrounding text of a
+// handler to input methods. Only the TextInputOp of the
+// focused handler is used.
+type TextInputOp struct {
+	Key input.Key
+	// Caret is the caret rectangle. The current transform
+	// is applied to it.
+	Caret image.Rectangle
+	// Text is the text around the caret, excluding any
+	// preedit text.
+	Text string
+	// Cursor and Anchor are the byte offsets in Text of the
+	// caret and of the other end of the selection. Anchor
+	// equals Cursor when there is no selection.
+	Cursor, Anchor int
 }
 
 // Modifiers
 type Modifiers uint32
 
+// State is the state of a key during an event.
+type State uint8
+
 const (
 	// ModCommand is the command modifier. On macOS
 	// it is the Cmd key, on other platforms the Ctrl
@@ -62,6 +161,30 @@ const (
 	ModCommand Modifiers = 1 << iota
 	// THe shift key.
 	ModShift
+	// ModCtrl is the Ctrl key.
+	ModCtrl
+	// ModAlt is the Alt key, or the Option key
+	// on macOS.
+	ModAlt
+	// ModSuper is the "logo" key, such as the Windows
+	// key. On macOS it is the Cmd key.
+	ModSuper
+)
+
+const (
+	// FocusNext moves the focus to the next handler.
+	FocusNext FocusDirection = iota
+	// FocusPrevious moves the focus to the previous
+	// handler.
+	FocusPrevious
+)
+
+const (
+	// Press is the state of a pressed key.
+	Press State = iota
+	// Release is the state of a key that has been
+	// released.
+	Release
 )
 
 const (
@@ -79,6 +202,30 @@ const (
 	NameDeleteForward  = '⌦'
 	NamePageUp         = '⇞'
 	NamePageDown       = '⇟'
+	NameTab            = '⇥'
+	NameSpace          = '␣'
+	NameInsert         = '⎀'
+	NameShift          = '⇧'
+	NameCtrl           = '⌃'
+	NameAlt            = '⌥'
+	NameSuper          = '❖'
+)
+
+// Runes for the function keys. The values match
+// the private use runes of NSEvent on macOS.
+const (
+	NameF1 rune = 0xf704 + iota
+	NameF2
+	NameF3
+	NameF4
+	NameF5
+	NameF6
+	NameF7
+	NameF8
+	NameF9
+	NameF10
+	NameF11
+	NameF12
 )
 
 // Contain reports whether m contains all modifiers
@@ -87,21 +234,95 @@ func (m Modifiers) Contain(m2 Modifiers) bool {
 	return m&m2 == m2
 }
 
+func (m Modifiers) String() string {
+	var strs []string
+	for _, mod := range []struct {
+		m    Modifiers
+		name string
+	}{
+		{ModCommand, "ModCommand"},
+		{ModShift, "ModShift"},
+		{ModCtrl, "ModCtrl"},
+		{ModAlt, "ModAlt"},
+		{ModSuper, "ModSuper"},
+	} {
+		if m.Contain(mod.m) {
+			strs = append(strs, mod.name)
+		}
+	}
+	return strings.Join(strs, "|")
+}
+
+func (s State) String() string {
+	switch s {
+	case Press:
+		return "Press"
+	case Release:
+		return "Release"
+	default:
+		panic("invalid State")
+	}
+}
+
 func (h InputOp) Add(o *ui.Ops) {
 	data := make([]byte, opconst.TypeKeyInputLen)
 	data[0] = byte(opconst.TypeKeyInput)
 	if h.Focus {
 		data[1] = 1
 	}
+	bo := binary.LittleEndian
+	bo.PutUint32(data[2:], uint32(h.TabIndex))
+	if h.Tab {
+		data[6] = 1
+	}
 	o.Write(data, h.Key)
 }
 
+func (op FocusOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeFocusLen)
+	data[0] = byte(opconst.TypeFocus)
+	o.Write(data, op.Key)
+}
+
+func (op ShortcutOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeShortcutLen)
+	data[0] = byte(opconst.TypeShortcut)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], uint32(op.Name))
+	bo.PutUint32(data[5:], uint32(op.Modifiers))
+	if op.Pass {
+		data[9] = 1
+	}
+	o.Write(data, op.Key)
+}
+
+func (op TextInputOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeTextInputLen)
+	data[0] = byte(opconst.TypeTextInput)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], uint32(op.Caret.Min.X))
+	bo.PutUint32(data[5:], uint32(op.Caret.Min.Y))
+	bo.PutUint32(data[9:], uint32(op.Caret.Max.X))
+	bo.PutUint32(data[13:], uint32(op.Caret.Max.Y))
+	bo.PutUint32(data[17:], uint32(op.Cursor))
+	bo.PutUint32(data[21:], uint32(op.Anchor))
+	o.Write(data, op.Key, op.Text)
+}
+
+func (op MoveFocusOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeMoveFocusLen)
+	data[0] = byte(opconst.TypeMoveFocus)
+	data[1] = byte(op.Dir)
+	o.Write(data)
+}
+
 func (h HideInputOp) Add(o *ui.Ops) {
 	data := make([]byte, opconst.TypeHideInputLen)
 	data[0] = byte(opconst.TypeHideInput)
 	o.Write(data)
 }
 
-func (EditEvent) ImplementsEvent()  {}
-func (Event) ImplementsEvent()      {}
-func (FocusEvent) ImplementsEvent() {}
+func (EditEvent) ImplementsEvent()    {}
+func (Event) ImplementsEvent()        {}
+func (FocusEvent) ImplementsEvent()   {}
+func (PreeditEvent) ImplementsEvent() {}
@@ -3,19 +3,14 @@ package layout_test
 import (
 	"fmt"
 	"image"
-	"time"
 
 	"gioui.org/ui"
-	"gioui.org/ui/input"
 	"gioui.org/ui/layout"
+	"gioui.org/ui/uitest"
 )
 
-type queue struct{}
-
-type config struct{}
-
-var q queue
-var cfg = new(config)
+var q = new(uitest.Queue)
+var cfg = new(uitest.Config)
 
 func ExampleInset() {
 	ops := new(ui.Ops)
@@ -148,15 +143,3 @@ func layoutWidget(width, height int, cs layout.Constraints) layout.Dimens {
 		},
 	}
 }
-
-func (config) Now() time.Time {
-	return time.Now()
-}
-
-func (config) Px(v ui.Value) int {
-	return int(v.V + .5)
-}
-
-func (queue) Next(k input.Key) (input.Event, bool) {
-	return nil, false
-}
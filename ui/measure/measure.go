@@ -6,6 +6,7 @@ Package measure implements text layout and shaping.
 package measure
 
 import (
+	"image"
 	"math"
 	"unicode"
 	"unicode/utf8"
@@ -19,12 +20,13 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
-// Faces is a cache of text layouts and paths.
+// Faces is a cache of text layouts, paths and glyph masks.
 type Faces struct {
 	config      ui.Config
 	faceCache   map[faceKey]*Face
 	layoutCache map[layoutKey]cachedLayout
 	pathCache   map[pathKey]cachedPath
+	glyphCache  map[glyphKey]cachedGlyph
 }
 
 type cachedLayout struct {
@@ -35,6 +37,14 @@ type cachedLayout struct {
 type cachedPath struct {
 	active bool
 	path   ui.MacroOp
+	// glyphs are the keys of the glyph
+	// masks of the path.
+	glyphs []glyphKey
+}
+
+type cachedGlyph struct {
+	active bool
+	mask   *image.Alpha
 }
 
 type layoutKey struct {
@@ -50,6 +60,12 @@ type pathKey struct {
 	str  string
 }
 
+type glyphKey struct {
+	f    *sfnt.Font
+	ppem fixed.Int26_6
+	r    rune
+}
+
 type faceKey struct {
 	font *sfnt.Font
 	size ui.Value
@@ -62,11 +78,23 @@ type Face struct {
 	font  *opentype
 }
 
-// Reset the cache, discarding any measures or paths that
-// haven't been used since the last call to Reset.
+// maxGlyphPPEM is the largest text size, in pixels per em, that
+// is drawn with glyph masks. Larger text is drawn with paths.
+const maxGlyphPPEM = 64
+
+// Reset the cache, discarding any measures, paths or glyphs
+// that haven't been used since the last call to Reset.
 func (f *Faces) Reset(c ui.Config) {
 	f.config = c
 	f.init()
+	for gk, g := range f.glyphCache {
+		if !g.active {
+			delete(f.glyphCache, gk)
+			continue
+		}
+		g.active = false
+		f.glyphCache[gk] = g
+	}
 	for pk, p := range f.pathCache {
 		if !p.active {
 			delete(f.pathCache, pk)
@@ -108,6 +136,7 @@ func (f *Faces) init() {
 	f.faceCache = make(map[faceKey]*Face)
 	f.pathCache = make(map[pathKey]cachedPath)
 	f.layoutCache = make(map[layoutKey]cachedLayout)
+	f.glyphCache = make(map[glyphKey]cachedGlyph)
 }
 
 func (f *Face) Layout(str string, opts text.LayoutOptions) *text.Layout {
@@ -128,6 +157,11 @@ func (f *Face) Layout(str string, opts text.LayoutOptions) *text.Layout {
 	return l
 }
 
+// Path returns the clip path of str. Text up to 64
+// pixels per em also adds a paint.GlyphOp for every glyph,
+// rasterized once per size. The glyph masks are grid-fitted
+// vertically only; the hinting instructions of the font are
+// not run.
 func (f *Face) Path(str text.String) ui.MacroOp {
 	ppem := fixed.Int26_6(f.faces.config.Px(f.size) * 64)
 	pk := pathKey{
@@ -138,11 +172,57 @@ func (f *Face) Path(str text.String) ui.MacroOp {
 	if p, ok := f.faces.pathCache[pk]; ok {
 		p.active = true
 		f.faces.pathCache[pk] = p
+		// Keep the glyph masks of the path.
+		for _, gk := range p.glyphs {
+			if g, ok := f.faces.glyphCache[gk]; ok {
+				g.active = true
+				f.faces.glyphCache[gk] = g
+			}
+		}
 		return p.path
 	}
-	p := textPath(ppem, f.font, str)
-	f.faces.pathCache[pk] = cachedPath{active: true, path: p}
-	return p
+	var glyphs []glyphKey
+	ops := new(ui.Ops)
+	var m ui.MacroOp
+	m.Record(ops)
+	if ppem <= fixed.I(maxGlyphPPEM) {
+		glyphs = f.glyphs(ops, ppem, str)
+	}
+	textPath(ops, ppem, f.font, str)
+	m.Stop()
+	f.faces.pathCache[pk] = cachedPath{active: true, path: m, glyphs: glyphs}
+	return m
+}
+
+// glyphs adds a GlyphOp for every glyph of str and returns the
+// keys of their masks.
+func (f *Face) glyphs(ops *ui.Ops, ppem fixed.Int26_6, str text.String) []glyphKey {
+	var keys []glyphKey
+	var x fixed.Int26_6
+	var advIdx int
+	for _, r := range str.String {
+		orig := x
+		x += str.Advances[advIdx]
+		advIdx++
+		if unicode.IsSpace(r) {
+			continue
+		}
+		gk := glyphKey{f: f.font.Font, ppem: ppem, r: r}
+		g, ok := f.faces.glyphCache[gk]
+		if !ok {
+			mask, ok := f.font.GlyphMask(ppem, r)
+			if !ok {
+				continue
+			}
+			g.mask = mask
+		}
+		g.active = true
+		f.faces.glyphCache[gk] = g
+		keys = append(keys, gk)
+		// Masks are drawn at whole pixels.
+		paint.GlyphOp{Mask: g.mask, Origin: image.Point{X: orig.Round()}}.Add(ops)
+	}
+	return keys
 }
 
 func layoutText(ppem fixed.Int26_6, str string, f *opentype, opts text.LayoutOptions) *text.Layout {
@@ -234,15 +314,13 @@ func layoutText(ppem fixed.Int26_6, str string, f *opentype, opts text.LayoutOpt
 	return &text.Layout{Lines: lines}
 }
 
-func textPath(ppem fixed.Int26_6, f *opentype, str text.String) ui.MacroOp {
+// textPath adds the ClipOp outline of str to ops.
+func textPath(ops *ui.Ops, ppem fixed.Int26_6, f *opentype, str text.String) {
 	var lastPos f32.Point
 	var builder paint.PathBuilder
-	ops := new(ui.Ops)
 	builder.Init(ops)
 	var x fixed.Int26_6
 	var advIdx int
-	var m ui.MacroOp
-	m.Record(ops)
 	for _, r := range str.String {
 		if !unicode.IsSpace(r) {
 			segs, ok := f.LoadGlyph(ppem, r)
@@ -295,6 +373,4 @@ func textPath(ppem fixed.Int26_6, f *opentype, str text.String) ui.MacroOp {
 		advIdx++
 	}
 	builder.End()
-	m.Stop()
-	return m
 }
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package measure
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/internal/opconst"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/text"
+	"gioui.org/ui/uitest"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestGlyphs(t *testing.T) {
+	fnt, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var faces Faces
+	faces.Reset(&uitest.Config{})
+	tests := []struct {
+		size   ui.Value
+		glyphs int
+	}{
+		// Small text has a glyph for every
+		// non-space rune.
+		{ui.Px(12), 4},
+		// Large text is drawn with paths only.
+		{ui.Px(maxGlyphPPEM + 1), 0},
+	}
+	for _, test := range tests {
+		face := faces.For(fnt, test.size)
+		l := face.Layout("Go go", text.LayoutOptions{MaxWidth: 1e6})
+		o := new(ui.Ops)
+		face.Path(l.Lines[0].Text).Add(o)
+		var r ops.Reader
+		r.Reset(o)
+		var masks []*image.Alpha
+		clips := 0
+		for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+			switch opconst.OpType(encOp.Data[0]) {
+			case opconst.TypeGlyph:
+				if clips > 0 {
+					t.Errorf("%v: glyph after the clip", test.size)
+				}
+				m := encOp.Refs[0].(*image.Alpha)
+				if !covers(m) {
+					t.Errorf("%v: empty glyph mask", test.size)
+				}
+				masks = append(masks, m)
+			case opconst.TypeClip:
+				clips++
+			}
+		}
+		if len(masks) != test.glyphs {
+			t.Errorf("%v: got %d glyphs, expected %d", test.size, len(masks), test.glyphs)
+		}
+		// Glyphs share masks.
+		if len(masks) == 4 && masks[1] != masks[3] {
+			t.Errorf("%v: the glyphs of 'o' have different masks", test.size)
+		}
+		if clips != 1 {
+			t.Errorf("%v: got %d clips, expected 1", test.size, clips)
+		}
+	}
+}
+
+func covers(m *image.Alpha) bool {
+	for _, a := range m.Pix {
+		if a > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGridFit(t *testing.T) {
+	fnt, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &opentype{Font: fnt, Hinting: font.HintingFull}
+	for _, ppem := range []int{9, 11, 12, 13, 15} {
+		for _, r := range "EHgx_" {
+			segs, ok := f.LoadGlyph(fixed.I(ppem), r)
+			if !ok {
+				t.Fatalf("%d ppem: no outline for %c", ppem, r)
+			}
+			orig := outlineBounds(segs)
+			var baseline int
+			for _, seg := range segs {
+				for _, p := range seg.Args[:segmentArgs(seg)] {
+					if p.Y == 0 {
+						baseline++
+					}
+				}
+			}
+			gridFit(segs, orig)
+			b := outlineBounds(segs)
+			// The top and bottom are on pixel boundaries.
+			if orig.Min.Y < 0 && b.Min.Y != fixed.I(orig.Min.Y.Round()) {
+				t.Errorf("%d ppem: %c: top %v is not fitted to %v", ppem, r, b.Min.Y, orig.Min.Y)
+			}
+			if orig.Max.Y > 0 && b.Max.Y != fixed.I(orig.Max.Y.Round()) {
+				t.Errorf("%d ppem: %c: bottom %v is not fitted to %v", ppem, r, b.Max.Y, orig.Max.Y)
+			}
+			for _, seg := range segs {
+				for _, p := range seg.Args[:segmentArgs(seg)] {
+					if p.Y == 0 {
+						baseline--
+					}
+				}
+			}
+			if baseline != 0 {
+				t.Errorf("%d ppem: %c: points moved off the baseline", ppem, r)
+			}
+		}
+	}
+	// Masks of fitted glyphs start at whole pixels.
+	m, ok := f.GlyphMask(fixed.I(12), 'E')
+	if !ok {
+		t.Fatal("no mask for E")
+	}
+	if m.Rect.Max.Y != 0 {
+		t.Errorf("got mask bounds %v, expected the bottom at the baseline", m.Rect)
+	}
+}
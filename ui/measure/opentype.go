@@ -3,16 +3,20 @@
 package measure
 
 import (
+	"image"
+
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
 )
 
 type opentype struct {
 	Font    *sfnt.Font
 	Hinting font.Hinting
 
-	buf sfnt.Buffer
+	buf  sfnt.Buffer
+	rast vector.Rasterizer
 }
 
 func (f *opentype) GlyphAdvance(ppem fixed.Int26_6, r rune) (advance fixed.Int26_6, ok bool) {
@@ -61,3 +65,127 @@ func (f *opentype) LoadGlyph(ppem fixed.Int26_6, r rune) ([]sfnt.Segment, bool)
 	}
 	return segs, true
 }
+
+// GlyphMask rasterizes the glyph for r with its origin at
+// a pixel corner. The mask bounds are relative to the origin.
+//
+// The outline is grid-fitted vertically: the parts above and
+// below the baseline are scaled such that the top and bottom
+// of the glyph fall on pixel boundaries. The font hinting
+// instructions are not run, and the horizontal shape is not
+// hinted.
+func (f *opentype) GlyphMask(ppem fixed.Int26_6, r rune) (*image.Alpha, bool) {
+	segs, ok := f.LoadGlyph(ppem, r)
+	if !ok || len(segs) == 0 {
+		return nil, false
+	}
+	gridFit(segs, outlineBounds(segs))
+	b := outlineBounds(segs)
+	bounds := image.Rectangle{
+		Min: image.Point{X: b.Min.X.Floor(), Y: b.Min.Y.Floor()},
+		Max: image.Point{X: b.Max.X.Ceil(), Y: b.Max.Y.Ceil()},
+	}
+	sz := bounds.Size()
+	f.rast.Reset(sz.X, sz.Y)
+	// Convert to mask coordinates.
+	off := fixed.Point26_6{X: fixed.I(bounds.Min.X), Y: fixed.I(bounds.Min.Y)}
+	pt := func(p fixed.Point26_6) (float32, float32) {
+		p = p.Sub(off)
+		return float32(p.X) / 64, float32(p.Y) / 64
+	}
+	for i, seg := range segs {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i > 0 {
+				f.rast.ClosePath()
+			}
+			f.rast.MoveTo(pt(seg.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			f.rast.LineTo(pt(seg.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := pt(seg.Args[0])
+			x2, y2 := pt(seg.Args[1])
+			f.rast.QuadTo(x1, y1, x2, y2)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := pt(seg.Args[0])
+			x2, y2 := pt(seg.Args[1])
+			x3, y3 := pt(seg.Args[2])
+			f.rast.CubeTo(x1, y1, x2, y2, x3, y3)
+		}
+	}
+	f.rast.ClosePath()
+	mask := image.NewAlpha(image.Rectangle{Max: sz})
+	f.rast.Draw(mask, mask.Rect, image.Opaque, image.Point{})
+	mask.Rect = bounds
+	return mask, true
+}
+
+// outlineBounds returns the bounds of the control points of
+// segs, which bound the outline.
+func outlineBounds(segs []sfnt.Segment) fixed.Rectangle26_6 {
+	var b fixed.Rectangle26_6
+	for i, seg := range segs {
+		for j, p := range seg.Args[:segmentArgs(seg)] {
+			if i == 0 && j == 0 {
+				b.Min, b.Max = p, p
+				continue
+			}
+			b.Min.X, b.Max.X = minFixed(b.Min.X, p.X), maxFixed(b.Max.X, p.X)
+			b.Min.Y, b.Max.Y = minFixed(b.Min.Y, p.Y), maxFixed(b.Max.Y, p.Y)
+		}
+	}
+	return b
+}
+
+// gridFit scales the points of segs above the baseline such
+// that the top of b is at a whole pixel, and the points below
+// the baseline such that the bottom of b is at a whole pixel.
+// The baseline is not moved.
+func gridFit(segs []sfnt.Segment, b fixed.Rectangle26_6) {
+	// The glyph coordinates grow downwards.
+	top, bottom := b.Min.Y, b.Max.Y
+	scale := func(y, edge fixed.Int26_6) fixed.Int26_6 {
+		fitted := fixed.I(edge.Round())
+		if fitted == 0 {
+			return y
+		}
+		return fixed.Int26_6(int64(y) * int64(fitted) / int64(edge))
+	}
+	for i := range segs {
+		seg := &segs[i]
+		for j := range seg.Args[:segmentArgs(*seg)] {
+			p := &seg.Args[j]
+			switch {
+			case p.Y < 0 && top < 0:
+				p.Y = scale(p.Y, top)
+			case p.Y > 0 && bottom > 0:
+				p.Y = scale(p.Y, bottom)
+			}
+		}
+	}
+}
+
+func segmentArgs(seg sfnt.Segment) int {
+	switch seg.Op {
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	default:
+		return 1
+	}
+}
+
+func minFixed(a, b fixed.Int26_6) fixed.Int26_6 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFixed(a, b fixed.Int26_6) fixed.Int26_6 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -6,10 +6,23 @@ Package paint provides operations for 2D graphics.
 The PaintOp operation draws the current material into a rectangular
 area, taking the current clip path and transformation into account.
 
-The material is set by either a ColorOp for a constant color, or
-ImageOp for an image.
+The material is set by either a ColorOp for a constant color,
+ImageOp for an image, or LinearGradientOp and RadialGradientOp for
+color gradients.
 
 The ClipOp operation sets the clip path. Drawing outside the clip
-path is ignored. A path is a closed shape of lines or curves.
+path is ignored. A path is a closed shape of lines or curves built
+with PathBuilder, or the outline of a stroke built with Stroker.
+RectClip, RoundRect and Ellipse are shortcuts for common shapes.
+Paths are filled according to their FillRule, NonZero by default.
+
+The OpacityOp operation fades the operations that follow it up to
+the end of the current ui.StackOp as a single layer.
+
+ShadowOp draws the soft shadow of a rounded rectangle, and BlurOp
+blurs what is already drawn behind it.
+
+Text renderers may precede the ClipOp of a text outline with GlyphOp
+operations that supply rasterized glyphs for drawing small text.
 */
 package paint
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint
+
+import (
+	"encoding/binary"
+	"image"
+
+	"gioui.org/ui"
+	"gioui.org/ui/internal/opconst"
+)
+
+// GlyphOp supplies a pre-rasterized glyph of the text outlined by
+// the next ClipOp. The union of the glyph masks before a ClipOp is
+// an alternative to its path: renderers may clip to the masks
+// instead, for example when the current transformation is an
+// offset. Masks are drawn at whole pixel positions and are not
+// scaled.
+//
+// Renderers cache masks by identity, so the contents of a Mask
+// must not change after the GlyphOp is added.
+type GlyphOp struct {
+	// Mask is the coverage of the glyph, relative to
+	// the glyph origin.
+	Mask *image.Alpha
+	// Origin is the position of the glyph origin.
+	Origin image.Point
+}
+
+func (g GlyphOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeGlyphLen)
+	data[0] = byte(opconst.TypeGlyph)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], uint32(g.Origin.X))
+	bo.PutUint32(data[5:], uint32(g.Origin.Y))
+	o.Write(data, g.Mask)
+}
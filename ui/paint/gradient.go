@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint
+
+import (
+	"encoding/binary"
+	"image/color"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/opconst"
+)
+
+// MaxGradientStops is the maximum number of color stops
+// of a gradient.
+const MaxGradientStops = opconst.MaxGradientStops
+
+// GradientStop is a color at a position along a gradient.
+type GradientStop struct {
+	// Offset is the position of the stop, from 0 at
+	// the start of the gradient to 1 at the end.
+	Offset float32
+	Color  color.RGBA
+}
+
+// LinearGradientOp sets the material to a linear gradient
+// from Start to End. Colors are interpolated in linear
+// color space. Points before Start have the color of the first
+// stop and points after End have the color of the last stop.
+type LinearGradientOp struct {
+	Start, End f32.Point
+	// Stops are the color stops, sorted by offset.
+	// There may be at most MaxGradientStops stops.
+	Stops []GradientStop
+}
+
+// RadialGradientOp sets the material to a radial gradient
+// from Center to the circle around Center with radius Radius.
+// Colors are interpolated in linear color space. Points outside
+// the circle have the color of the last stop.
+type RadialGradientOp struct {
+	Center f32.Point
+	Radius float32
+	// Stops are the color stops, sorted by offset.
+	// There may be at most MaxGradientStops stops.
+	Stops []GradientStop
+}
+
+func (g LinearGradientOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeLinearGradientLen)
+	data[0] = byte(opconst.TypeLinearGradient)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(g.Start.X))
+	bo.PutUint32(data[5:], math.Float32bits(g.Start.Y))
+	bo.PutUint32(data[9:], math.Float32bits(g.End.X))
+	bo.PutUint32(data[13:], math.Float32bits(g.End.Y))
+	encodeStops(data[17:], g.Stops)
+	o.Write(data)
+}
+
+func (g RadialGradientOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeRadialGradientLen)
+	data[0] = byte(opconst.TypeRadialGradient)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(g.Center.X))
+	bo.PutUint32(data[5:], math.Float32bits(g.Center.Y))
+	bo.PutUint32(data[9:], math.Float32bits(g.Radius))
+	encodeStops(data[13:], g.Stops)
+	o.Write(data)
+}
+
+func encodeStops(data []byte, stops []GradientStop) {
+	if len(stops) > MaxGradientStops {
+		panic("too many gradient stops")
+	}
+	bo := binary.LittleEndian
+	data[0] = byte(len(stops))
+	data = data[1:]
+	for _, s := range stops {
+		bo.PutUint32(data, math.Float32bits(s.Offset))
+		data[4] = s.Color.R
+		data[5] = s.Color.G
+		data[6] = s.Color.B
+		data[7] = s.Color.A
+		data = data[8:]
+	}
+}
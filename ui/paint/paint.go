@@ -15,11 +15,21 @@ import (
 
 // ImageOp sets the material to a section of an
 // image.
+//
+// Renderers may cache the contents of Src across frames. To draw
+// an image whose pixels are modified in place, increment Version
+// for every modification and set Dirty to the modified area.
 type ImageOp struct {
 	// Src is the image.
 	Src image.Image
 	// Rect defines the section of Src to use.
 	Rect image.Rectangle
+	// Version identifies the contents of Src.
+	Version int
+	// Dirty is the area of Src modified since the previous
+	// Version. If Dirty is empty, or if the previous Version
+	// wasn't drawn, all of Src is considered modified.
+	Dirty image.Rectangle
 }
 
 // ColorOp sets the material to a constant color.
@@ -33,6 +43,16 @@ type PaintOp struct {
 	Rect f32.Rectangle
 }
 
+// OpacityOp fades the drawing operations that follow it,
+// up to the end of the current StackOp. The operations are
+// drawn into a separate layer that is blended as a whole, so
+// overlapping shapes are not blended with each other.
+type OpacityOp struct {
+	// Opacity ranges from 0 for an invisible layer
+	// to 1 for an opaque layer.
+	Opacity float32
+}
+
 func (i ImageOp) Add(o *ui.Ops) {
 	data := make([]byte, opconst.TypeImageLen)
 	data[0] = byte(opconst.TypeImage)
@@ -41,6 +61,11 @@ func (i ImageOp) Add(o *ui.Ops) {
 	bo.PutUint32(data[5:], uint32(i.Rect.Min.Y))
 	bo.PutUint32(data[9:], uint32(i.Rect.Max.X))
 	bo.PutUint32(data[13:], uint32(i.Rect.Max.Y))
+	bo.PutUint32(data[17:], uint32(i.Version))
+	bo.PutUint32(data[21:], uint32(i.Dirty.Min.X))
+	bo.PutUint32(data[25:], uint32(i.Dirty.Min.Y))
+	bo.PutUint32(data[29:], uint32(i.Dirty.Max.X))
+	bo.PutUint32(data[33:], uint32(i.Dirty.Max.Y))
 	o.Write(data, i.Src)
 }
 
@@ -65,6 +90,14 @@ func (d PaintOp) Add(o *ui.Ops) {
 	o.Write(data)
 }
 
+func (op OpacityOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeLayerLen)
+	data[0] = byte(opconst.TypeLayer)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(op.Opacity))
+	o.Write(data)
+}
+
 // RectClip returns a ClipOp corresponding to a pixel aligned
 // rectangular area.
 func RectClip(r image.Rectangle) ClipOp {
@@ -26,13 +26,28 @@ type PathBuilder struct {
 	pen       f32.Point
 	bounds    f32.Rectangle
 	hasBounds bool
+	rule      FillRule
 }
 
 // ClipOp sets the current clip path.
 type ClipOp struct {
 	bounds f32.Rectangle
+	rule   FillRule
 }
 
+// FillRule determines which areas are inside a path
+// with intersecting or nested contours.
+type FillRule uint8
+
+const (
+	// NonZero includes areas where the contours around
+	// them wind a nonzero number of times.
+	NonZero FillRule = iota
+	// EvenOdd includes areas surrounded by an odd number
+	// of contours, regardless of their direction.
+	EvenOdd
+)
+
 func (p ClipOp) Add(o *ui.Ops) {
 	data := make([]byte, opconst.TypeClipLen)
 	data[0] = byte(opconst.TypeClip)
@@ -41,6 +56,7 @@ func (p ClipOp) Add(o *ui.Ops) {
 	bo.PutUint32(data[5:], math.Float32bits(p.bounds.Min.Y))
 	bo.PutUint32(data[9:], math.Float32bits(p.bounds.Max.X))
 	bo.PutUint32(data[13:], math.Float32bits(p.bounds.Max.Y))
+	data[17] = byte(p.rule)
 	o.Write(data)
 }
 
@@ -50,6 +66,12 @@ func (p *PathBuilder) Init(ops *ui.Ops) {
 	p.ops = ops
 }
 
+// SetFillRule sets the fill rule of the path. The default
+// is NonZero.
+func (p *PathBuilder) SetFillRule(rule FillRule) {
+	p.rule = rule
+}
+
 // MoveTo moves the pen to the given position.
 func (p *PathBuilder) Move(to f32.Point) {
 	p.end()
@@ -90,9 +112,10 @@ func (p *PathBuilder) Quad(ctrl, to f32.Point) {
 }
 
 func (p *PathBuilder) quadTo(ctrl, to f32.Point) {
-	// Zero width curves don't contribute to stenciling.
-	if p.pen.X == to.X && p.pen.X == ctrl.X {
-		p.pen = to
+	// Zero width curves don't contribute to stenciling, but
+	// are kept for paths that are later rotated or sheared.
+	// Drop only empty curves.
+	if p.pen == to && p.pen == ctrl {
 		return
 	}
 
@@ -148,22 +171,88 @@ func (p *PathBuilder) Cube(ctrl0, ctrl1, to f32.Point) {
 	ctrl0 = ctrl0.Add(p.pen)
 	ctrl1 = ctrl1.Add(p.pen)
 	to = to.Add(p.pen)
+	approxCube(p, p.pen, ctrl0, ctrl1, to)
+}
+
+// Arc records a line from the pen to the start of a circular arc,
+// followed by the arc itself. The arc is centered at center and
+// starts at the angle start, in radians. It extends sweep radians,
+// clockwise for positive sweeps. The pen ends at the end of the arc.
+func (p *PathBuilder) Arc(center f32.Point, radius, start, sweep float32) {
+	center = center.Add(p.pen)
+	p.arcTo(center, f32.Point{X: radius, Y: radius}, start, sweep)
+}
+
+// arcTo is like Arc for elliptical arcs with the given radii
+// and absolute center.
+func (p *PathBuilder) arcTo(center, radii f32.Point, start, sweep float32) {
+	at := func(angle float64) f32.Point {
+		sin, cos := math.Sincos(angle)
+		return f32.Point{
+			X: center.X + radii.X*float32(cos),
+			Y: center.Y + radii.Y*float32(sin),
+		}
+	}
+	p.lineTo(at(float64(start)))
+	// Approximate the arc with quadratic Béziers of the unit circle,
+	// scaled by the radii. The midpoint of a quadratic Bézier with
+	// the control point at the intersection of the tangents of a
+	// circle at ±φ deviates approximately
+	//
+	// d = r(cos(φ) + 1/cos(φ))/2 - r ≈ rφ⁴/8
+	//
+	// from the circle. Like approxCubeTo, keep d below 0.001 times the
+	// size of the bounding rectangle.
+	r := radii.X
+	if radii.Y > r {
+		r = radii.Y
+	}
+	if r <= 0 {
+		return
+	}
+	const maxDist = 0.001 * 2
+	phi := math.Sqrt(math.Sqrt(8 * maxDist))
+	n := int(math.Ceil(math.Abs(float64(sweep)) / (2 * phi)))
+	if n == 0 {
+		return
+	}
+	step := float64(sweep) / float64(n)
+	ctrlDist := 1 / math.Cos(step/2)
+	for i := 1; i <= n; i++ {
+		a := float64(start) + step*float64(i)
+		sin, cos := math.Sincos(a - step/2)
+		ctrl := f32.Point{
+			X: center.X + radii.X*float32(cos*ctrlDist),
+			Y: center.Y + radii.Y*float32(sin*ctrlDist),
+		}
+		p.quadTo(ctrl, at(a))
+	}
+}
+
+// quadSink records quadratic Béziers in absolute
+// coordinates, continuing from the end of the previous
+// curve.
+type quadSink interface {
+	quadTo(ctrl, to f32.Point)
+}
+
+// approxCube approximates the cubic Bézier from, ctrl0, ctrl1, to
+// by a series of quadratic curves recorded to q.
+func approxCube(q quadSink, from, ctrl0, ctrl1, to f32.Point) {
 	// Set the maximum distance proportionally to the longest side
 	// of the bounding rectangle.
 	hull := f32.Rectangle{
-		Min: p.pen,
+		Min: from,
 		Max: ctrl0,
 	}.Canon().Add(ctrl1).Add(to)
 	l := hull.Dx()
 	if h := hull.Dy(); h > l {
 		l = h
 	}
-	p.approxCubeTo(0, l*0.001, ctrl0, ctrl1, to)
+	approxCubeTo(q, 0, l*0.001, from, ctrl0, ctrl1, to)
 }
 
-// approxCube approximates a cubic Bézier by a series of quadratic
-// curves.
-func (p *PathBuilder) approxCubeTo(splits int, maxDist float32, ctrl0, ctrl1, to f32.Point) int {
+func approxCubeTo(q quadSink, splits int, maxDist float32, from, ctrl0, ctrl1, to f32.Point) int {
 	// The idea is from
 	// https://caffeineowl.com/graphics/2d/vectorial/cubic2quad01.html
 	// where a quadratic approximates a cubic by eliminating its t³ term
@@ -188,10 +277,10 @@ func (p *PathBuilder) approxCubeTo(splits int, maxDist float32, ctrl0, ctrl1, to
 	// and use the midpoint between the two curves Q1 and Q2 as control point:
 	//
 	// C = (3ctrl0 - pen + 3ctrl1 - to)/4
-	c := ctrl0.Mul(3).Sub(p.pen).Add(ctrl1.Mul(3)).Sub(to).Mul(1.0 / 4.0)
+	c := ctrl0.Mul(3).Sub(from).Add(ctrl1.Mul(3)).Sub(to).Mul(1.0 / 4.0)
 	const maxSplits = 32
 	if splits >= maxSplits {
-		p.quadTo(c, to)
+		q.quadTo(c, to)
 		return splits
 	}
 	// The maximum distance between the cubic P and its approximation Q given t
@@ -200,23 +289,23 @@ func (p *PathBuilder) approxCubeTo(splits int, maxDist float32, ctrl0, ctrl1, to
 	// d = sqrt(3)/36*|to - 3ctrl1 + 3ctrl0 - pen|
 	//
 	// To save a square root, compare d² with the squared tolerance.
-	v := to.Sub(ctrl1.Mul(3)).Add(ctrl0.Mul(3)).Sub(p.pen)
+	v := to.Sub(ctrl1.Mul(3)).Add(ctrl0.Mul(3)).Sub(from)
 	d2 := (v.X*v.X + v.Y*v.Y) * 3 / (36 * 36)
 	if d2 <= maxDist*maxDist {
-		p.quadTo(c, to)
+		q.quadTo(c, to)
 		return splits
 	}
 	// De Casteljau split the curve and approximate the halves.
 	t := float32(0.5)
-	c0 := p.pen.Add(ctrl0.Sub(p.pen).Mul(t))
+	c0 := from.Add(ctrl0.Sub(from).Mul(t))
 	c1 := ctrl0.Add(ctrl1.Sub(ctrl0).Mul(t))
 	c2 := ctrl1.Add(to.Sub(ctrl1).Mul(t))
 	c01 := c0.Add(c1.Sub(c0).Mul(t))
 	c12 := c1.Add(c2.Sub(c1).Mul(t))
 	c0112 := c01.Add(c12.Sub(c01).Mul(t))
 	splits++
-	splits = p.approxCubeTo(splits, maxDist, c0, c01, c0112)
-	splits = p.approxCubeTo(splits, maxDist, c12, c2, to)
+	splits = approxCubeTo(q, splits, maxDist, from, c0, c01, c0112)
+	splits = approxCubeTo(q, splits, maxDist, c0112, c12, c2, to)
 	return splits
 }
 
@@ -288,5 +377,6 @@ func (p *PathBuilder) End() {
 	p.end()
 	ClipOp{
 		bounds: p.bounds,
+		rule:   p.rule,
 	}.Add(p.ops)
 }
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint
+
+import (
+	"encoding/binary"
+	"image/color"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/opconst"
+)
+
+// ShadowOp draws the soft shadow of a rectangle with rounded
+// corners, respecting the clip path and transformation. Rotations
+// and shears are approximated by the bounds of the transformed
+// rectangle.
+type ShadowOp struct {
+	// Rect is the rectangle casting the shadow.
+	Rect f32.Rectangle
+	// Radius is the corner radius of Rect.
+	Radius float32
+	// Offset moves the shadow relative to Rect.
+	Offset f32.Point
+	// Blur is the blur radius of the shadow edges. Like
+	// CSS box shadows, the shadow is blurred by a Gaussian
+	// with a standard deviation of half the blur radius.
+	Blur float32
+	// Spread expands the shadow before blurring. Negative
+	// values shrink the shadow.
+	Spread float32
+	Color  color.RGBA
+}
+
+// BlurOp blurs the content drawn before it in Rect, respecting
+// the clip path and transformation. Clip the blurred area to a
+// path for frosted glass effects behind translucent surfaces.
+type BlurOp struct {
+	Rect f32.Rectangle
+	// Radius is the blur radius. The content is blurred by a
+	// Gaussian with a standard deviation of half the radius.
+	Radius float32
+}
+
+func (s ShadowOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeShadowLen)
+	data[0] = byte(opconst.TypeShadow)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(s.Rect.Min.X))
+	bo.PutUint32(data[5:], math.Float32bits(s.Rect.Min.Y))
+	bo.PutUint32(data[9:], math.Float32bits(s.Rect.Max.X))
+	bo.PutUint32(data[13:], math.Float32bits(s.Rect.Max.Y))
+	bo.PutUint32(data[17:], math.Float32bits(s.Radius))
+	bo.PutUint32(data[21:], math.Float32bits(s.Offset.X))
+	bo.PutUint32(data[25:], math.Float32bits(s.Offset.Y))
+	bo.PutUint32(data[29:], math.Float32bits(s.Blur))
+	bo.PutUint32(data[33:], math.Float32bits(s.Spread))
+	data[37] = s.Color.R
+	data[38] = s.Color.G
+	data[39] = s.Color.B
+	data[40] = s.Color.A
+	o.Write(data)
+}
+
+func (b BlurOp) Add(o *ui.Ops) {
+	data := make([]byte, opconst.TypeBlurLen)
+	data[0] = byte(opconst.TypeBlur)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(b.Rect.Min.X))
+	bo.PutUint32(data[5:], math.Float32bits(b.Rect.Min.Y))
+	bo.PutUint32(data[9:], math.Float32bits(b.Rect.Max.X))
+	bo.PutUint32(data[13:], math.Float32bits(b.Rect.Max.Y))
+	bo.PutUint32(data[17:], math.Float32bits(b.Radius))
+	o.Write(data)
+}
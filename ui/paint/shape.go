@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint
+
+import (
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+)
+
+// RoundRect adds a ClipOp for the rectangle r with rounded
+// corners. The corner radii are given in clockwise order from
+// the south east corner. Like in CSS, radii are scaled down
+// proportionally if adjacent corners would overlap.
+func RoundRect(ops *ui.Ops, r f32.Rectangle, se, sw, nw, ne float32) {
+	w, h := r.Dx(), r.Dy()
+	scale := float32(1)
+	for _, s := range [...]struct{ side, radii float32 }{
+		{w, nw + ne},
+		{h, ne + se},
+		{w, se + sw},
+		{h, sw + nw},
+	} {
+		if s.radii > s.side && s.radii > 0 {
+			if f := s.side / s.radii; f < scale {
+				scale = f
+			}
+		}
+	}
+	se, sw, nw, ne = se*scale, sw*scale, nw*scale, ne*scale
+	var b PathBuilder
+	b.Init(ops)
+	start := f32.Point{X: r.Min.X + nw, Y: r.Min.Y}
+	b.Move(start)
+	b.lineTo(f32.Point{X: r.Max.X - ne, Y: r.Min.Y})
+	b.arcTo(f32.Point{X: r.Max.X - ne, Y: r.Min.Y + ne}, f32.Point{X: ne, Y: ne}, -math.Pi/2, math.Pi/2)
+	b.lineTo(f32.Point{X: r.Max.X, Y: r.Max.Y - se})
+	b.arcTo(f32.Point{X: r.Max.X - se, Y: r.Max.Y - se}, f32.Point{X: se, Y: se}, 0, math.Pi/2)
+	b.lineTo(f32.Point{X: r.Min.X + sw, Y: r.Max.Y})
+	b.arcTo(f32.Point{X: r.Min.X + sw, Y: r.Max.Y - sw}, f32.Point{X: sw, Y: sw}, math.Pi/2, math.Pi/2)
+	b.lineTo(f32.Point{X: r.Min.X, Y: r.Min.Y + nw})
+	b.arcTo(f32.Point{X: r.Min.X + nw, Y: r.Min.Y + nw}, f32.Point{X: nw, Y: nw}, math.Pi, math.Pi/2)
+	b.lineTo(start)
+	b.End()
+}
+
+// Ellipse adds a ClipOp for the ellipse inscribed in r. Use a
+// square rectangle for a circle.
+func Ellipse(ops *ui.Ops, r f32.Rectangle) {
+	center := r.Min.Add(r.Max).Mul(.5)
+	radii := r.Max.Sub(r.Min).Mul(.5)
+	var b PathBuilder
+	b.Init(ops)
+	start := f32.Point{X: r.Max.X, Y: center.Y}
+	b.Move(start)
+	b.arcTo(center, radii, 0, 2*math.Pi)
+	b.lineTo(start)
+	b.End()
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/paint"
+	"gioui.org/ui/uitest"
+)
+
+func fill(ops *ui.Ops) *image.RGBA {
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 40, Y: 40}}}.Add(ops)
+	return uitest.Render(ops, image.Point{X: 40, Y: 40})
+}
+
+func TestRoundRect(t *testing.T) {
+	ops := new(ui.Ops)
+	r := f32.Rectangle{Min: f32.Point{X: 5, Y: 5}, Max: f32.Point{X: 35, Y: 35}}
+	// Rounded south east corner only.
+	paint.RoundRect(ops, r, 10, 0, 0, 0)
+	img := fill(ops)
+	for _, p := range []image.Point{{5, 5}, {34, 5}, {5, 34}, {20, 20}, {30, 30}} {
+		if !covered(img, p.X, p.Y) {
+			t.Errorf("%v not covered", p)
+		}
+	}
+	for _, p := range []image.Point{{34, 34}, {4, 20}, {20, 35}} {
+		if covered(img, p.X, p.Y) {
+			t.Errorf("%v covered", p)
+		}
+	}
+}
+
+func TestEllipse(t *testing.T) {
+	ops := new(ui.Ops)
+	paint.Ellipse(ops, f32.Rectangle{Min: f32.Point{X: 0, Y: 10}, Max: f32.Point{X: 40, Y: 30}})
+	img := fill(ops)
+	for _, p := range []image.Point{{1, 20}, {38, 20}, {20, 11}, {20, 28}} {
+		if !covered(img, p.X, p.Y) {
+			t.Errorf("%v not covered", p)
+		}
+	}
+	for _, p := range []image.Point{{1, 11}, {38, 28}, {20, 9}} {
+		if covered(img, p.X, p.Y) {
+			t.Errorf("%v covered", p)
+		}
+	}
+}
+
+func TestArc(t *testing.T) {
+	ops := new(ui.Ops)
+	// A half circle below the center.
+	var b paint.PathBuilder
+	b.Init(ops)
+	b.Move(f32.Point{X: 20, Y: 20})
+	b.Arc(f32.Point{}, 15, 0, math.Pi)
+	b.Line(f32.Point{X: 15})
+	b.End()
+	img := fill(ops)
+	if !covered(img, 20, 30) {
+		t.Error("lower half not covered")
+	}
+	if covered(img, 20, 10) {
+		t.Error("upper half covered")
+	}
+}
+
+func TestFillRule(t *testing.T) {
+	for _, rule := range []paint.FillRule{paint.NonZero, paint.EvenOdd} {
+		ops := new(ui.Ops)
+		var b paint.PathBuilder
+		b.Init(ops)
+		b.SetFillRule(rule)
+		// Two nested squares in the same direction.
+		square := func(size float32) {
+			b.Line(f32.Point{X: size})
+			b.Line(f32.Point{Y: size})
+			b.Line(f32.Point{X: -size})
+			b.Line(f32.Point{Y: -size})
+		}
+		b.Move(f32.Point{X: 5, Y: 5})
+		square(30)
+		b.Move(f32.Point{X: 10, Y: 10})
+		square(10)
+		b.End()
+		img := fill(ops)
+		if !covered(img, 10, 10) {
+			t.Errorf("rule %d: outer square not covered", rule)
+		}
+		if got, want := covered(img, 20, 20), rule == paint.NonZero; got != want {
+			t.Errorf("rule %d: inner square covered: %v, expected %v", rule, got, want)
+		}
+	}
+}
@@ -0,0 +1,553 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint
+
+import (
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+)
+
+// Stroker builds a ClipOp for the outline of stroked lines
+// and curves. Like PathBuilder, Stroker generates no garbage
+// once its internal buffers have grown to fit the paths it
+// is used for.
+//
+// Coordinates are relative to the pen, exactly like
+// PathBuilder.
+type Stroker struct {
+	style StrokeStyle
+	b     PathBuilder
+	// pen is the stroker pen; out is the pen of the outline
+	// recorded in b and contour is the start of its current
+	// contour.
+	pen, out, contour f32.Point
+	// start is the start of the current subpath.
+	start f32.Point
+	// open reports whether a subpath is in progress.
+	open bool
+	segs []strokeSeg
+	// dash is the scratch space for dashed subpaths.
+	dash []strokeSeg
+}
+
+// StrokeStyle describes the shape of stroked lines.
+type StrokeStyle struct {
+	// Width of the stroke.
+	Width float32
+	Cap   StrokeCap
+	Join  StrokeJoin
+	// Miter is the maximum ratio of the miter length to
+	// the stroke width of a MiterJoin. Longer miters are
+	// drawn as BevelJoins. Zero means 4.
+	Miter float32
+	// Dashes is an optional dash pattern of alternating
+	// lengths of drawn and skipped stroke. Patterns with an
+	// odd number of lengths are repeated to make them even.
+	Dashes []float32
+	// DashOffset is the distance into the dash pattern where
+	// each subpath starts.
+	DashOffset float32
+}
+
+// StrokeCap is the shape of the ends of a stroke.
+type StrokeCap uint8
+
+// StrokeJoin is the shape of the corners of a stroke.
+type StrokeJoin uint8
+
+const (
+	// ButtCap ends strokes exactly at their end points.
+	ButtCap StrokeCap = iota
+	// RoundCap ends strokes with a half circle.
+	RoundCap
+	// SquareCap ends strokes with a half square.
+	SquareCap
+)
+
+const (
+	// MiterJoin extends the outer edges of a corner until
+	// they meet, subject to the StrokeStyle Miter limit.
+	MiterJoin StrokeJoin = iota
+	// RoundJoin rounds corners with a circular arc.
+	RoundJoin
+	// BevelJoin cuts corners with a straight line.
+	BevelJoin
+)
+
+// strokeSeg is a quadratic Bézier segment in absolute
+// coordinates.
+type strokeSeg struct {
+	from, ctrl, to f32.Point
+}
+
+const (
+	// strokeTolerance is the maximum distance between
+	// the approximated and exact outline of a stroke.
+	strokeTolerance = 0.05
+	// maxStrokeSplits limits the number of times a segment
+	// is split to approximate its offset curves.
+	maxStrokeSplits = 8
+)
+
+// Init the stroker with the operations list for storing the
+// outline and final ClipOp, and the style of the stroke.
+func (s *Stroker) Init(ops *ui.Ops, style StrokeStyle) {
+	s.style = style
+	if s.style.Miter == 0 {
+		s.style.Miter = 4
+	}
+	s.b = PathBuilder{}
+	s.b.Init(ops)
+	s.pen = f32.Point{}
+	s.out = f32.Point{}
+	s.open = false
+	s.segs = s.segs[:0]
+}
+
+// Move ends the current subpath and moves the pen
+// to start a new one.
+func (s *Stroker) Move(to f32.Point) {
+	s.flush(false)
+	s.pen = s.pen.Add(to)
+	s.start = s.pen
+}
+
+// Line records a line from the pen to end.
+func (s *Stroker) Line(to f32.Point) {
+	to = to.Add(s.pen)
+	s.quadTo(to.Add(s.pen).Mul(.5), to)
+}
+
+// Quad records a quadratic Bézier from the pen to end
+// with the control point ctrl.
+func (s *Stroker) Quad(ctrl, to f32.Point) {
+	s.quadTo(ctrl.Add(s.pen), to.Add(s.pen))
+}
+
+// Cube records a cubic Bézier from the pen through
+// two control points ending in to.
+func (s *Stroker) Cube(ctrl0, ctrl1, to f32.Point) {
+	approxCube(s, s.pen, ctrl0.Add(s.pen), ctrl1.Add(s.pen), to.Add(s.pen))
+}
+
+// Close the current subpath with a line to its start
+// and join its ends.
+func (s *Stroker) Close() {
+	if s.pen != s.start {
+		s.Line(s.start.Sub(s.pen))
+	}
+	s.open = true
+	s.flush(true)
+	s.pen = s.start
+}
+
+// End the stroke and add the resulting ClipOp to
+// the operation list passed to Init.
+func (s *Stroker) End() {
+	s.flush(false)
+	s.b.End()
+}
+
+func (s *Stroker) quadTo(ctrl, to f32.Point) {
+	s.open = true
+	if s.pen != to || s.pen != ctrl {
+		s.segs = append(s.segs, strokeSeg{from: s.pen, ctrl: ctrl, to: to})
+	}
+	s.pen = to
+}
+
+// flush strokes the current subpath.
+func (s *Stroker) flush(closed bool) {
+	if !s.open {
+		return
+	}
+	s.open = false
+	segs := s.segs
+	s.segs = s.segs[:0]
+	if len(segs) == 0 {
+		s.dot(s.start)
+		return
+	}
+	if !s.dashed() {
+		s.stroke(segs, closed)
+		return
+	}
+	s.dashSegs(segs, closed)
+}
+
+// dashed reports whether the style has a valid dash pattern.
+func (s *Stroker) dashed() bool {
+	var total float32
+	for _, d := range s.style.Dashes {
+		if d < 0 {
+			return false
+		}
+		total += d
+	}
+	return total > 0
+}
+
+// dashSegs splits segs according to the dash pattern and
+// strokes the dashes.
+func (s *Stroker) dashSegs(segs []strokeSeg, closed bool) {
+	dashes := s.style.Dashes
+	// Find the starting position in the pattern.
+	var period float32
+	for _, d := range dashes {
+		period += d
+	}
+	if len(dashes)%2 == 1 {
+		period *= 2
+	}
+	off := float32(math.Mod(float64(s.style.DashOffset), float64(period)))
+	if off < 0 {
+		off += period
+	}
+	idx, on := 0, true
+	for off >= dashes[idx] {
+		off -= dashes[idx]
+		idx = (idx + 1) % len(dashes)
+		on = !on
+	}
+	rem := dashes[idx] - off
+	s.dash = s.dash[:0]
+	for _, seg := range segs {
+		l := seg.length()
+		t0, pos := float32(0), float32(0)
+		for pos+rem < l {
+			pos += rem
+			t1 := seg.paramAt(pos)
+			if on && t1 > t0 {
+				s.dash = append(s.dash, seg.sub(t0, t1))
+				s.stroke(s.dash, false)
+				s.dash = s.dash[:0]
+			}
+			t0 = t1
+			idx = (idx + 1) % len(dashes)
+			on = !on
+			rem = dashes[idx]
+		}
+		rem -= l - pos
+		if on && t0 < 1 {
+			s.dash = append(s.dash, seg.sub(t0, 1))
+		}
+	}
+	if len(s.dash) > 0 {
+		s.stroke(s.dash, false)
+	}
+}
+
+// dot strokes a subpath without extent.
+func (s *Stroker) dot(p f32.Point) {
+	hw := s.style.Width / 2
+	switch s.style.Cap {
+	case RoundCap:
+		s.moveTo(p.Add(f32.Point{X: hw}))
+		s.arcTo(p, 2*math.Pi)
+		s.closeContour()
+	case SquareCap:
+		s.moveTo(p.Add(f32.Point{X: -hw, Y: -hw}))
+		s.lineTo(p.Add(f32.Point{X: hw, Y: -hw}))
+		s.lineTo(p.Add(f32.Point{X: hw, Y: hw}))
+		s.lineTo(p.Add(f32.Point{X: -hw, Y: hw}))
+		s.closeContour()
+	}
+}
+
+// stroke records the outline of the connected segments in segs.
+// The segments are reversed in the process.
+func (s *Stroker) stroke(segs []strokeSeg, closed bool) {
+	hw := s.style.Width / 2
+	first, last := segs[0], segs[len(segs)-1]
+	s.moveTo(first.from.Add(normal(first.startTangent(), hw)))
+	s.side(segs, closed)
+	if closed {
+		s.closeContour()
+	} else {
+		s.cap(last.to, last.endTangent())
+	}
+	// Trace the other side by stroking the reversed segments.
+	reverse(segs)
+	if closed {
+		first = segs[0]
+		s.moveTo(first.from.Add(normal(first.startTangent(), hw)))
+	}
+	s.side(segs, closed)
+	if !closed {
+		s.cap(first.from, first.startTangent().Mul(-1))
+	}
+	s.closeContour()
+}
+
+// side records the outline left of segs.
+func (s *Stroker) side(segs []strokeSeg, closed bool) {
+	hw := s.style.Width / 2
+	for i, seg := range segs {
+		s.offset(seg, hw, 0)
+		if i < len(segs)-1 {
+			s.join(seg.to, seg.endTangent(), segs[i+1].startTangent())
+		} else if closed {
+			s.join(seg.to, seg.endTangent(), segs[0].startTangent())
+		}
+	}
+}
+
+// offset records the curve at distance d left of seg.
+func (s *Stroker) offset(seg strokeSeg, d float32, splits int) {
+	t0, t1 := seg.startTangent(), seg.endTangent()
+	p0 := seg.from.Add(normal(t0, d))
+	p1 := seg.to.Add(normal(t1, d))
+	if seg.straight() {
+		s.lineTo(p1)
+		return
+	}
+	// The control point is the intersection of the
+	// tangents through the offset end points.
+	den := cross(t0, t1)
+	if !parallel(t0, t1) {
+		a := cross(p1.Sub(p0), t1) / den
+		ctrl := p0.Add(t0.Mul(a))
+		// Compare the midpoints of the approximation
+		// and the exact offset curve.
+		mid := seg.at(.5).Add(normal(seg.tangentAt(.5), d))
+		approx := strokeSeg{from: p0, ctrl: ctrl, to: p1}.at(.5)
+		if dist(mid, approx) <= strokeTolerance || splits >= maxStrokeSplits {
+			s.curveTo(ctrl, p1)
+			return
+		}
+	} else if splits >= maxStrokeSplits {
+		s.lineTo(p1)
+		return
+	}
+	s.offset(seg.sub(0, .5), d, splits+1)
+	s.offset(seg.sub(.5, 1), d, splits+1)
+}
+
+// join records the join left of the vertex p between a
+// segment ending with tangent t0 and a segment starting
+// with tangent t1.
+func (s *Stroker) join(p, t0, t1 f32.Point) {
+	hw := s.style.Width / 2
+	n0, n1 := normal(t0, hw), normal(t1, hw)
+	to := p.Add(n1)
+	switch {
+	case parallel(t0, t1) && dot(t0, t1) > 0:
+		// No corner.
+		s.lineTo(to)
+		return
+	case cross(t0, t1) > 0:
+		// The inner side of the corner. Go through the vertex
+		// to ensure the area between the segments is covered.
+		s.lineTo(p)
+		s.lineTo(to)
+		return
+	}
+	switch s.style.Join {
+	case RoundJoin:
+		s.arcTo(p, angle(n0, n1))
+	case MiterJoin:
+		// The miter point is on the bisector of the normals.
+		bis := n0.Add(n1)
+		l2 := dot(bis, bis)
+		if l2 > 0 {
+			ratio := 2 * hw / float32(math.Sqrt(float64(l2)))
+			if ratio <= s.style.Miter {
+				s.lineTo(p.Add(bis.Mul(2 * hw * hw / l2)))
+			}
+		}
+		s.lineTo(to)
+	default:
+		s.lineTo(to)
+	}
+}
+
+// cap records the cap at the end point p of a subpath with
+// end tangent t.
+func (s *Stroker) cap(p, t f32.Point) {
+	hw := s.style.Width / 2
+	n := normal(t, hw)
+	switch s.style.Cap {
+	case RoundCap:
+		s.arcTo(p, -math.Pi)
+	case SquareCap:
+		e := normal(n, hw).Mul(-1)
+		s.lineTo(p.Add(n).Add(e))
+		s.lineTo(p.Sub(n).Add(e))
+		s.lineTo(p.Sub(n))
+	default:
+		s.lineTo(p.Sub(n))
+	}
+}
+
+// arcTo records the circular arc around center from the outline
+// pen, sweeping the given angle in radians.
+func (s *Stroker) arcTo(center f32.Point, sweep float32) {
+	v := s.out.Sub(center)
+	r := float32(math.Hypot(float64(v.X), float64(v.Y)))
+	start := math.Atan2(float64(v.Y), float64(v.X))
+	// Use at most an eighth of a circle per quadratic curve.
+	n := int(math.Ceil(math.Abs(float64(sweep)) / (math.Pi / 4)))
+	if n == 0 {
+		return
+	}
+	step := float64(sweep) / float64(n)
+	ctrlDist := r / float32(math.Cos(step/2))
+	for i := 1; i <= n; i++ {
+		a := start + step*float64(i)
+		mid := a - step/2
+		ctrl := center.Add(f32.Point{X: float32(math.Cos(mid)), Y: float32(math.Sin(mid))}.Mul(ctrlDist))
+		to := center.Add(f32.Point{X: float32(math.Cos(a)), Y: float32(math.Sin(a))}.Mul(r))
+		s.curveTo(ctrl, to)
+	}
+}
+
+func (s *Stroker) moveTo(p f32.Point) {
+	s.b.Move(p.Sub(s.out))
+	s.out = p
+	s.contour = p
+}
+
+func (s *Stroker) lineTo(p f32.Point) {
+	if p == s.out {
+		return
+	}
+	s.b.Line(p.Sub(s.out))
+	s.out = p
+}
+
+// curveTo records a quadratic Bézier in the outline.
+func (s *Stroker) curveTo(ctrl, to f32.Point) {
+	s.b.Quad(ctrl.Sub(s.out), to.Sub(s.out))
+	s.out = to
+}
+
+// closeContour closes the current outline contour.
+func (s *Stroker) closeContour() {
+	s.lineTo(s.contour)
+}
+
+func reverse(segs []strokeSeg) {
+	for i, j := 0, len(segs)-1; i <= j; i, j = i+1, j-1 {
+		segs[i], segs[j] = segs[j].reverse(), segs[i].reverse()
+	}
+}
+
+func (s strokeSeg) reverse() strokeSeg {
+	return strokeSeg{from: s.to, ctrl: s.ctrl, to: s.from}
+}
+
+func (s strokeSeg) startTangent() f32.Point {
+	if t := s.ctrl.Sub(s.from); t != (f32.Point{}) {
+		return t
+	}
+	return s.to.Sub(s.from)
+}
+
+func (s strokeSeg) endTangent() f32.Point {
+	if t := s.to.Sub(s.ctrl); t != (f32.Point{}) {
+		return t
+	}
+	return s.to.Sub(s.from)
+}
+
+// straight reports whether the segment is a line.
+func (s strokeSeg) straight() bool {
+	t0, t1 := s.startTangent(), s.endTangent()
+	return parallel(t0, t1) && dot(t0, t1) > 0
+}
+
+func (s strokeSeg) at(t float32) f32.Point {
+	c0 := s.from.Add(s.ctrl.Sub(s.from).Mul(t))
+	c1 := s.ctrl.Add(s.to.Sub(s.ctrl).Mul(t))
+	return c0.Add(c1.Sub(c0).Mul(t))
+}
+
+func (s strokeSeg) tangentAt(t float32) f32.Point {
+	d := s.ctrl.Sub(s.from).Mul(1 - t).Add(s.to.Sub(s.ctrl).Mul(t))
+	if d == (f32.Point{}) {
+		return s.to.Sub(s.from)
+	}
+	return d
+}
+
+// sub returns the part of s between t0 and t1.
+func (s strokeSeg) sub(t0, t1 float32) strokeSeg {
+	from := s.at(t0)
+	d := s.ctrl.Sub(s.from).Mul(1 - t0).Add(s.to.Sub(s.ctrl).Mul(t0))
+	return strokeSeg{
+		from: from,
+		ctrl: from.Add(d.Mul(t1 - t0)),
+		to:   s.at(t1),
+	}
+}
+
+// flatSegs is the number of lines used to measure
+// the length of a segment.
+const flatSegs = 16
+
+// length approximates the arc length of s.
+func (s strokeSeg) length() float32 {
+	var l float32
+	prev := s.from
+	for i := 1; i <= flatSegs; i++ {
+		p := s.at(float32(i) / flatSegs)
+		l += dist(prev, p)
+		prev = p
+	}
+	return l
+}
+
+// paramAt approximates the parameter t where the arc
+// length of s from 0 to t is l.
+func (s strokeSeg) paramAt(l float32) float32 {
+	prev := s.from
+	for i := 1; i <= flatSegs; i++ {
+		p := s.at(float32(i) / flatSegs)
+		d := dist(prev, p)
+		if l <= d {
+			if d == 0 {
+				return float32(i) / flatSegs
+			}
+			return (float32(i-1) + l/d) / flatSegs
+		}
+		l -= d
+		prev = p
+	}
+	return 1
+}
+
+// normal returns the vector of length l perpendicular to t.
+func normal(t f32.Point, l float32) f32.Point {
+	n := float32(math.Hypot(float64(t.X), float64(t.Y)))
+	if n == 0 {
+		return f32.Point{}
+	}
+	return f32.Point{X: -t.Y, Y: t.X}.Mul(l / n)
+}
+
+// angle returns the signed angle from v0 to v1.
+func angle(v0, v1 f32.Point) float32 {
+	return float32(math.Atan2(float64(cross(v0, v1)), float64(dot(v0, v1))))
+}
+
+// parallel reports whether a and b are parallel, within
+// a small tolerance.
+func parallel(a, b f32.Point) bool {
+	c := cross(a, b)
+	return c*c <= 1e-8*dot(a, a)*dot(b, b)
+}
+
+func cross(a, b f32.Point) float32 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func dot(a, b f32.Point) float32 {
+	return a.X*b.X + a.Y*b.Y
+}
+
+func dist(a, b f32.Point) float32 {
+	d := b.Sub(a)
+	return float32(math.Hypot(float64(d.X), float64(d.Y)))
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/paint"
+	"gioui.org/ui/uitest"
+)
+
+func strokeLine(style paint.StrokeStyle, from, to f32.Point) *image.RGBA {
+	ops := new(ui.Ops)
+	var s paint.Stroker
+	s.Init(ops, style)
+	s.Move(from)
+	s.Line(to.Sub(from))
+	s.End()
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	return uitest.Render(ops, image.Point{X: 20, Y: 20})
+}
+
+func covered(img *image.RGBA, x, y int) bool {
+	return img.RGBAAt(x, y).R < 0x80
+}
+
+func TestStrokeCaps(t *testing.T) {
+	from, to := f32.Point{X: 4, Y: 10}, f32.Point{X: 16, Y: 10}
+	tests := []struct {
+		cap   paint.StrokeCap
+		ended bool
+	}{
+		{paint.ButtCap, false},
+		{paint.SquareCap, true},
+		{paint.RoundCap, true},
+	}
+	for _, test := range tests {
+		img := strokeLine(paint.StrokeStyle{Width: 4, Cap: test.cap}, from, to)
+		if !covered(img, 10, 9) || !covered(img, 10, 11) {
+			t.Errorf("cap %d: line not covered", test.cap)
+		}
+		if covered(img, 10, 13) {
+			t.Errorf("cap %d: stroke too wide", test.cap)
+		}
+		if got := covered(img, 2, 9); got != test.ended {
+			t.Errorf("cap %d: cap coverage %v, expected %v", test.cap, got, test.ended)
+		}
+	}
+}
+
+func TestStrokeDashes(t *testing.T) {
+	style := paint.StrokeStyle{Width: 2, Dashes: []float32{4}}
+	img := strokeLine(style, f32.Point{X: 0, Y: 10}, f32.Point{X: 20, Y: 10})
+	for x := 0; x < 20; x++ {
+		on := (x/4)%2 == 0
+		if got := covered(img, x, 9); got != on {
+			t.Errorf("x=%d: covered %v, expected %v", x, got, on)
+		}
+	}
+}
+
+func TestStrokeClosed(t *testing.T) {
+	ops := new(ui.Ops)
+	var s paint.Stroker
+	s.Init(ops, paint.StrokeStyle{Width: 2})
+	s.Move(f32.Point{X: 4, Y: 4})
+	s.Line(f32.Point{X: 12})
+	s.Line(f32.Point{Y: 12})
+	s.Line(f32.Point{X: -12})
+	s.Close()
+	s.End()
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	paint.PaintOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	img := uitest.Render(ops, image.Point{X: 20, Y: 20})
+	for _, p := range []image.Point{{4, 10}, {15, 10}, {10, 3}, {10, 16}, {3, 3}, {16, 16}} {
+		if !covered(img, p.X, p.Y) {
+			t.Errorf("%v: edge not covered", p)
+		}
+	}
+	for _, p := range []image.Point{{10, 10}, {6, 6}, {1, 1}, {18, 18}} {
+		if covered(img, p.X, p.Y) {
+			t.Errorf("%v: covered", p)
+		}
+	}
+}
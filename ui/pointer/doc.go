@@ -93,6 +93,15 @@ The losing handlers are notified by a Cancel event.
 
 For multiple grabbing handlers, the foremost handler wins.
 
+Hover
+
+Enter and Leave events track the handlers whose areas contain
+the pointer, regardless of the matching set. A handler receives
+an Enter event when a pointer moves into its area, and a Leave
+event when the pointer moves out of its area, is cancelled or,
+for touch pointers, is released. Enter and Leave events are also
+generated when the handler areas move under a stationary pointer.
+
 Priorities
 
 Handlers know their position in a matching set of a pointer through
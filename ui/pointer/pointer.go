@@ -5,12 +5,14 @@ package pointer
 import (
 	"encoding/binary"
 	"image"
+	"strings"
 	"time"
 
 	"gioui.org/ui"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/opconst"
+	"gioui.org/ui/key"
 )
 
 // Event is a pointer event.
@@ -37,6 +39,14 @@ type Event struct {
 	Position f32.Point
 	// Scroll is the scroll amount, if any.
 	Scroll f32.Point
+	// Buttons are the set of pressed mouse buttons for
+	// this event. A mouse pointer is pressed from the first
+	// button press to the last button release; pressing or
+	// releasing other buttons in between generate Move events.
+	Buttons Buttons
+	// Modifiers is the set of active modifiers when the
+	// event occurred.
+	Modifiers key.Modifiers
 }
 
 // RectAreaOp updates the hit area to the intersection
@@ -86,6 +96,9 @@ type Priority uint8
 // Source of an Event.
 type Source uint8
 
+// Buttons is a set of mouse buttons.
+type Buttons uint8
+
 // Must match input.areaKind
 type areaKind uint8
 
@@ -99,6 +112,13 @@ const (
 	Release
 	// Move of a pointer.
 	Move
+	// Enter is generated when a pointer moves into
+	// the hit area of the handler.
+	Enter
+	// Leave is generated when a pointer moves out of
+	// the hit area of the handler, or when the pointer
+	// is released or cancelled.
+	Leave
 )
 
 const (
@@ -108,6 +128,22 @@ const (
 	Touch
 )
 
+const (
+	// ButtonPrimary is the primary button, usually the left
+	// button of a right-handed mouse.
+	ButtonPrimary Buttons = 1 << iota
+	// ButtonSecondary is the secondary button, usually the
+	// right button of a right-handed mouse.
+	ButtonSecondary
+	// ButtonTertiary is the tertiary button, usually the
+	// middle button.
+	ButtonTertiary
+	// ButtonBack is the back navigation button.
+	ButtonBack
+	// ButtonForward is the forward navigation button.
+	ButtonForward
+)
+
 const (
 	// Shared priority is for handlers that
 	// are part of a matching set larger than 1.
@@ -178,11 +214,40 @@ func (t Type) String() string {
 		return "Cancel"
 	case Move:
 		return "Move"
+	case Enter:
+		return "Enter"
+	case Leave:
+		return "Leave"
 	default:
 		panic("unknown Type")
 	}
 }
 
+// Contain reports whether the set b contains
+// all of the buttons.
+func (b Buttons) Contain(buttons Buttons) bool {
+	return b&buttons == buttons
+}
+
+func (b Buttons) String() string {
+	var strs []string
+	for _, btn := range []struct {
+		b    Buttons
+		name string
+	}{
+		{ButtonPrimary, "ButtonPrimary"},
+		{ButtonSecondary, "ButtonSecondary"},
+		{ButtonTertiary, "ButtonTertiary"},
+		{ButtonBack, "ButtonBack"},
+		{ButtonForward, "ButtonForward"},
+	} {
+		if b.Contain(btn.b) {
+			strs = append(strs, btn.name)
+		}
+	}
+	return strings.Join(strs, "|")
+}
+
 func (p Priority) String() string {
 	switch p {
 	case Shared:
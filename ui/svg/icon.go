@@ -0,0 +1,675 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package svg
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/paint"
+)
+
+// Icon is a static SVG image. Only the subset of SVG commonly
+// used by icons is supported: path, rect, circle, ellipse, line,
+// polyline and polygon elements, possibly nested in groups, with
+// solid fills and strokes. Text, gradients, masks and scripts are
+// ignored.
+type Icon struct {
+	// viewBox is the rectangle of the user space
+	// mapped to the icon.
+	viewBox f32.Rectangle
+	// width and height are the intrinsic size
+	// of the icon, in dp.
+	width, height float32
+	shapes        []shape
+}
+
+type shape struct {
+	path  *Path
+	trans f32.Affine2D
+	// fill and stroke are the colors of the shape,
+	// with the opacity applied. Transparent colors
+	// are not drawn.
+	fill, stroke color.RGBA
+	evenOdd      bool
+	strokeStyle  paint.StrokeStyle
+}
+
+// style is the set of inherited presentation attributes.
+type style struct {
+	fill, stroke  color.RGBA
+	fillOpacity   float32
+	strokeOpacity float32
+	evenOdd       bool
+	strokeStyle   paint.StrokeStyle
+	// opacity is the product of the opacity
+	// of the element and its ancestors.
+	opacity float32
+	trans   f32.Affine2D
+}
+
+type decoder struct {
+	icon   *Icon
+	styles []style
+}
+
+// Decode an icon from an SVG document.
+func Decode(r io.Reader) (*Icon, error) {
+	d := &decoder{icon: new(Icon)}
+	if err := d.decode(xml.NewDecoder(r)); err != nil {
+		return nil, fmt.Errorf("svg: %v", err)
+	}
+	return d.icon, nil
+}
+
+func (d *decoder) decode(x *xml.Decoder) error {
+	root := false
+	// skip counts the depth of unsupported
+	// elements being skipped.
+	skip := 0
+	for {
+		t, err := x.Token()
+		if err == io.EOF {
+			if !root {
+				return errors.New("missing svg element")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			if !root {
+				if t.Name.Local != "svg" {
+					return fmt.Errorf("unexpected root element <%s>", t.Name.Local)
+				}
+				root = true
+				if err := d.root(t.Attr); err != nil {
+					return err
+				}
+				continue
+			}
+			s := d.styles[len(d.styles)-1]
+			switch t.Name.Local {
+			case "g", "svg":
+				if err := s.parse(t.Attr); err != nil {
+					return err
+				}
+				d.styles = append(d.styles, s)
+			case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+				if err := s.parse(t.Attr); err != nil {
+					return err
+				}
+				p, err := shapePath(t.Name.Local, t.Attr)
+				if err != nil {
+					return err
+				}
+				d.add(p, s)
+				// Shapes have no rendered children.
+				skip++
+			default:
+				skip++
+			}
+		case xml.EndElement:
+			switch {
+			case skip > 0:
+				skip--
+			case len(d.styles) > 0:
+				d.styles = d.styles[:len(d.styles)-1]
+			}
+		}
+	}
+}
+
+func (d *decoder) root(attrs []xml.Attr) error {
+	ic := d.icon
+	s := style{
+		fill:          color.RGBA{A: 0xff},
+		fillOpacity:   1,
+		strokeOpacity: 1,
+		opacity:       1,
+		strokeStyle:   paint.StrokeStyle{Width: 1, Miter: 4},
+	}
+	if err := s.parse(attrs); err != nil {
+		return err
+	}
+	d.styles = append(d.styles, s)
+	hasViewBox := false
+	for _, a := range attrs {
+		var err error
+		switch a.Name.Local {
+		case "width":
+			ic.width, err = parseLength(a.Value)
+		case "height":
+			ic.height, err = parseLength(a.Value)
+		case "viewBox":
+			var v []float32
+			v, err = parseNumbers(a.Value)
+			if err == nil && (len(v) != 4 || v[2] <= 0 || v[3] <= 0) {
+				err = fmt.Errorf("invalid viewBox %q", a.Value)
+			}
+			if err == nil {
+				hasViewBox = true
+				ic.viewBox = f32.Rectangle{
+					Min: f32.Point{X: v[0], Y: v[1]},
+					Max: f32.Point{X: v[0] + v[2], Y: v[1] + v[3]},
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	switch {
+	case !hasViewBox && (ic.width <= 0 || ic.height <= 0):
+		return errors.New("missing viewBox or size")
+	case !hasViewBox:
+		ic.viewBox.Max = f32.Point{X: ic.width, Y: ic.height}
+	}
+	// Derive a missing dimension from the
+	// aspect ratio of the view box.
+	vw, vh := ic.viewBox.Dx(), ic.viewBox.Dy()
+	switch {
+	case ic.width <= 0 && ic.height <= 0:
+		ic.width, ic.height = vw, vh
+	case ic.width <= 0:
+		ic.width = ic.height * vw / vh
+	case ic.height <= 0:
+		ic.height = ic.width * vh / vw
+	}
+	return nil
+}
+
+func (d *decoder) add(p *Path, s style) {
+	sh := shape{
+		path:        p,
+		trans:       s.trans,
+		fill:        scaleAlpha(s.fill, s.fillOpacity*s.opacity),
+		stroke:      scaleAlpha(s.stroke, s.strokeOpacity*s.opacity),
+		evenOdd:     s.evenOdd,
+		strokeStyle: s.strokeStyle,
+	}
+	if sh.strokeStyle.Width <= 0 {
+		sh.stroke = color.RGBA{}
+	}
+	if sh.fill.A == 0 && sh.stroke.A == 0 {
+		return
+	}
+	d.icon.shapes = append(d.icon.shapes, sh)
+}
+
+// Layout the icon scaled to fit the constraints while preserving its
+// aspect ratio. Without constraints the icon is drawn at its intrinsic
+// size.
+func (ic *Icon) Layout(c ui.Config, ops *ui.Ops, cs layout.Constraints) layout.Dimens {
+	w, h := c.Px(ui.Dp(ic.width)), c.Px(ui.Dp(ic.height))
+	d := image.Point{X: cs.Width.Constrain(w), Y: cs.Height.Constrain(h)}
+	aspect := ic.width / ic.height
+	dw, dh := float32(d.X), float32(d.Y)
+	if aspect < dw/dh {
+		d.X = int(dh*aspect + 0.5)
+	} else {
+		d.Y = int(dw/aspect + 0.5)
+	}
+	dw, dh = float32(d.X), float32(d.Y)
+	// Map the view box to the center of the
+	// icon as by preserveAspectRatio="xMidYMid meet".
+	vb := ic.viewBox
+	scale := dw / vb.Dx()
+	if s := dh / vb.Dy(); s < scale {
+		scale = s
+	}
+	view := f32.Affine2D{}.Offset(vb.Min.Mul(-1)).Scale(f32.Point{}, f32.Point{X: scale, Y: scale}).Offset(f32.Point{
+		X: (dw - vb.Dx()*scale) / 2,
+		Y: (dh - vb.Dy()*scale) / 2,
+	})
+	for _, sh := range ic.shapes {
+		sh.draw(ops, view.Mul(sh.trans))
+	}
+	return layout.Dimens{Size: d, Baseline: d.Y}
+}
+
+func (sh *shape) draw(ops *ui.Ops, t f32.Affine2D) {
+	var stack ui.StackOp
+	if sh.fill.A > 0 {
+		stack.Push(ops)
+		ui.Affine(t).Add(ops)
+		var b paint.PathBuilder
+		b.Init(ops)
+		if sh.evenOdd {
+			b.SetFillRule(paint.EvenOdd)
+		}
+		sh.path.Fill(&b)
+		b.End()
+		paint.ColorOp{Color: sh.fill}.Add(ops)
+		paint.PaintOp{Rect: sh.path.Bounds()}.Add(ops)
+		stack.Pop()
+	}
+	if sh.stroke.A > 0 {
+		stack.Push(ops)
+		ui.Affine(t).Add(ops)
+		var s paint.Stroker
+		s.Init(ops, sh.strokeStyle)
+		sh.path.Stroke(&s)
+		s.End()
+		// Miter joins extend at most half the miter
+		// length outside the path.
+		st := sh.strokeStyle
+		ext := st.Width / 2
+		if st.Join == paint.MiterJoin && st.Miter > 1 {
+			ext *= st.Miter
+		}
+		if st.Cap == paint.SquareCap {
+			ext *= math.Sqrt2
+		}
+		r := sh.path.Bounds()
+		r.Min = r.Min.Sub(f32.Point{X: ext, Y: ext})
+		r.Max = r.Max.Add(f32.Point{X: ext, Y: ext})
+		paint.ColorOp{Color: sh.stroke}.Add(ops)
+		paint.PaintOp{Rect: r}.Add(ops)
+		stack.Pop()
+	}
+}
+
+// parse the presentation attributes and style property
+// of an element into s.
+func (s *style) parse(attrs []xml.Attr) error {
+	var props string
+	for _, a := range attrs {
+		if a.Name.Local == "style" {
+			props = a.Value
+			continue
+		}
+		if err := s.set(a.Name.Local, a.Value); err != nil {
+			return err
+		}
+	}
+	// Style properties override attributes.
+	for _, p := range strings.Split(props, ";") {
+		i := strings.IndexByte(p, ':')
+		if i == -1 {
+			continue
+		}
+		name, val := strings.TrimSpace(p[:i]), strings.TrimSpace(p[i+1:])
+		if name == "transform" {
+			continue
+		}
+		if err := s.set(name, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *style) set(name, val string) error {
+	var err error
+	val = strings.TrimSpace(val)
+	if val == "inherit" {
+		return nil
+	}
+	switch name {
+	case "fill":
+		s.fill, err = parseColor(val)
+	case "stroke":
+		s.stroke, err = parseColor(val)
+	case "fill-opacity":
+		s.fillOpacity, err = parseOpacity(val)
+	case "stroke-opacity":
+		s.strokeOpacity, err = parseOpacity(val)
+	case "opacity":
+		var o float32
+		o, err = parseOpacity(val)
+		s.opacity *= o
+	case "fill-rule":
+		switch val {
+		case "nonzero":
+			s.evenOdd = false
+		case "evenodd":
+			s.evenOdd = true
+		default:
+			err = fmt.Errorf("invalid fill-rule %q", val)
+		}
+	case "stroke-width":
+		s.strokeStyle.Width, err = parseLength(val)
+	case "stroke-miterlimit":
+		s.strokeStyle.Miter, err = parseNumber(val)
+	case "stroke-linecap":
+		switch val {
+		case "butt":
+			s.strokeStyle.Cap = paint.ButtCap
+		case "round":
+			s.strokeStyle.Cap = paint.RoundCap
+		case "square":
+			s.strokeStyle.Cap = paint.SquareCap
+		default:
+			err = fmt.Errorf("invalid stroke-linecap %q", val)
+		}
+	case "stroke-linejoin":
+		switch val {
+		case "miter":
+			s.strokeStyle.Join = paint.MiterJoin
+		case "round":
+			s.strokeStyle.Join = paint.RoundJoin
+		case "bevel":
+			s.strokeStyle.Join = paint.BevelJoin
+		default:
+			err = fmt.Errorf("invalid stroke-linejoin %q", val)
+		}
+	case "stroke-dasharray":
+		s.strokeStyle.Dashes = nil
+		if val != "none" {
+			s.strokeStyle.Dashes, err = parseNumbers(val)
+		}
+	case "stroke-dashoffset":
+		s.strokeStyle.DashOffset, err = parseLength(val)
+	case "transform":
+		var t f32.Affine2D
+		t, err = parseTransform(val)
+		s.trans = s.trans.Mul(t)
+	}
+	return err
+}
+
+// shapePath converts the geometry of a shape element to a Path.
+func shapePath(elem string, attrs []xml.Attr) (*Path, error) {
+	get := func(names ...string) ([]float32, error) {
+		v := make([]float32, len(names))
+		for _, a := range attrs {
+			for i, n := range names {
+				if a.Name.Local == n {
+					var err error
+					if v[i], err = parseLength(a.Value); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		return v, nil
+	}
+	p := new(Path)
+	switch elem {
+	case "path":
+		for _, a := range attrs {
+			if a.Name.Local == "d" {
+				return ParsePath(a.Value)
+			}
+		}
+	case "rect":
+		v, err := get("x", "y", "width", "height", "rx", "ry")
+		if err != nil {
+			return nil, err
+		}
+		x, y, w, h, rx, ry := v[0], v[1], v[2], v[3], v[4], v[5]
+		if w <= 0 || h <= 0 {
+			break
+		}
+		// A missing radius defaults to the other.
+		if rx == 0 {
+			rx = ry
+		}
+		if ry == 0 {
+			ry = rx
+		}
+		rx, ry = min(rx, w/2), min(ry, h/2)
+		p.add(segMove, f32.Point{X: x + rx, Y: y})
+		p.add(segLine, f32.Point{X: x + w - rx, Y: y})
+		p.corner(f32.Point{X: x + w - rx, Y: y + ry}, rx, ry, 3)
+		p.add(segLine, f32.Point{X: x + w, Y: y + h - ry})
+		p.corner(f32.Point{X: x + w - rx, Y: y + h - ry}, rx, ry, 0)
+		p.add(segLine, f32.Point{X: x + rx, Y: y + h})
+		p.corner(f32.Point{X: x + rx, Y: y + h - ry}, rx, ry, 1)
+		p.add(segLine, f32.Point{X: x, Y: y + ry})
+		p.corner(f32.Point{X: x + rx, Y: y + ry}, rx, ry, 2)
+		p.add(segClose, f32.Point{X: x + rx, Y: y})
+	case "circle", "ellipse":
+		var v []float32
+		var err error
+		if elem == "circle" {
+			v, err = get("cx", "cy", "r")
+			if err == nil {
+				v = append(v, v[2])
+			}
+		} else {
+			v, err = get("cx", "cy", "rx", "ry")
+		}
+		if err != nil {
+			return nil, err
+		}
+		c, rx, ry := f32.Point{X: v[0], Y: v[1]}, v[2], v[3]
+		if rx <= 0 || ry <= 0 {
+			break
+		}
+		start := c.Add(f32.Point{X: rx})
+		p.add(segMove, start)
+		for q := 0; q < 4; q++ {
+			p.corner(c, rx, ry, q)
+		}
+		p.add(segClose, start)
+	case "line":
+		v, err := get("x1", "y1", "x2", "y2")
+		if err != nil {
+			return nil, err
+		}
+		p.add(segMove, f32.Point{X: v[0], Y: v[1]})
+		p.add(segLine, f32.Point{X: v[2], Y: v[3]})
+	case "polyline", "polygon":
+		var v []float32
+		for _, a := range attrs {
+			if a.Name.Local == "points" {
+				var err error
+				if v, err = parseNumbers(a.Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(v) < 2 {
+			break
+		}
+		start := f32.Point{X: v[0], Y: v[1]}
+		p.add(segMove, start)
+		for i := 2; i+1 < len(v); i += 2 {
+			p.add(segLine, f32.Point{X: v[i], Y: v[i+1]})
+		}
+		if elem == "polygon" {
+			p.add(segClose, start)
+		}
+	}
+	return p, nil
+}
+
+// corner adds a cubic approximation of a quarter of the ellipse
+// around c, starting at the given quadrant in clockwise order,
+// beginning at angle 0.
+func (p *Path) corner(c f32.Point, rx, ry float32, quadrant int) {
+	// k is the distance of the control points
+	// of a unit quarter circle.
+	const k = 0.5522847498
+	dirs := [...]f32.Point{{X: 1}, {Y: 1}, {X: -1}, {Y: -1}}
+	u, v := dirs[quadrant%4], dirs[(quadrant+1)%4]
+	u = f32.Point{X: u.X * rx, Y: u.Y * ry}
+	v = f32.Point{X: v.X * rx, Y: v.Y * ry}
+	p.add(segCube,
+		c.Add(u).Add(v.Mul(k)),
+		c.Add(v).Add(u.Mul(k)),
+		c.Add(v),
+	)
+}
+
+// parseTransform parses the value of an SVG transform
+// attribute.
+func parseTransform(s string) (f32.Affine2D, error) {
+	var t f32.Affine2D
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		open := strings.IndexByte(rest, '(')
+		end := strings.IndexByte(rest, ')')
+		if open == -1 || end < open {
+			return t, fmt.Errorf("invalid transform %q", s)
+		}
+		name := strings.TrimSpace(rest[:open])
+		args, err := parseNumbers(rest[open+1 : end])
+		if err != nil {
+			return t, err
+		}
+		rest = strings.TrimLeft(rest[end+1:], " \t\r\n,")
+		n := len(args)
+		var m f32.Affine2D
+		switch {
+		case name == "matrix" && n == 6:
+			m = f32.NewAffine2D(args[0], args[2], args[4], args[1], args[3], args[5])
+		case name == "translate" && (n == 1 || n == 2):
+			args = append(args, 0)
+			m = m.Offset(f32.Point{X: args[0], Y: args[1]})
+		case name == "scale" && (n == 1 || n == 2):
+			args = append(args, args[0])
+			m = m.Scale(f32.Point{}, f32.Point{X: args[0], Y: args[1]})
+		case name == "rotate" && (n == 1 || n == 3):
+			var c f32.Point
+			if n == 3 {
+				c = f32.Point{X: args[1], Y: args[2]}
+			}
+			m = m.Rotate(c, args[0]*math.Pi/180)
+		case name == "skewX" && n == 1:
+			m = m.Shear(f32.Point{}, args[0]*math.Pi/180, 0)
+		case name == "skewY" && n == 1:
+			m = m.Shear(f32.Point{}, 0, args[0]*math.Pi/180)
+		default:
+			return t, fmt.Errorf("invalid transform %q", s)
+		}
+		// Transforms apply right to left.
+		t = t.Mul(m)
+	}
+	return t, nil
+}
+
+// parseNumbers parses a list of numbers separated
+// by whitespace or commas.
+func parseNumbers(s string) ([]float32, error) {
+	var v []float32
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	}) {
+		x, err := parseNumber(f)
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, x)
+	}
+	return v, nil
+}
+
+func parseNumber(s string) (float32, error) {
+	v, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return float32(v), nil
+}
+
+// parseLength parses a length in user units. Absolute
+// units other than px are not supported.
+func parseLength(s string) (float32, error) {
+	return parseNumber(strings.TrimSuffix(strings.TrimSpace(s), "px"))
+}
+
+func parseOpacity(s string) (float32, error) {
+	v, err := parseNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	return float32(math.Max(0, math.Min(float64(v), 1))), nil
+}
+
+// parseColor parses an SVG paint. The color "none" is
+// transparent.
+func parseColor(s string) (color.RGBA, error) {
+	s = strings.ToLower(s)
+	switch {
+	case s == "none":
+		return color.RGBA{}, nil
+	case s == "currentcolor":
+		return color.RGBA{A: 0xff}, nil
+	case strings.HasPrefix(s, "#"):
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 6 || err != nil {
+			break
+		}
+		return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		fields := strings.Split(s[4:len(s)-1], ",")
+		if len(fields) != 3 {
+			break
+		}
+		var c [3]uint8
+		for i, f := range fields {
+			f = strings.TrimSpace(f)
+			scale := 1.0
+			if strings.HasSuffix(f, "%") {
+				f, scale = f[:len(f)-1], 2.55
+			}
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+			}
+			c[i] = uint8(math.Max(0, math.Min(v*scale+.5, 255)))
+		}
+		return color.RGBA{R: c[0], G: c[1], B: c[2], A: 0xff}, nil
+	default:
+		if c, ok := namedColors[s]; ok {
+			return color.RGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}, nil
+		}
+	}
+	return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+}
+
+// scaleAlpha scales the premultiplied color c by alpha.
+func scaleAlpha(c color.RGBA, alpha float32) color.RGBA {
+	s := func(v uint8) uint8 {
+		return uint8(float32(v)*alpha + .5)
+	}
+	return color.RGBA{R: s(c.R), G: s(c.G), B: s(c.B), A: s(c.A)}
+}
+
+// namedColors is the subset of the CSS named colors
+// common in icons.
+var namedColors = map[string]uint32{
+	"black":   0x000000,
+	"white":   0xffffff,
+	"red":     0xff0000,
+	"green":   0x008000,
+	"lime":    0x00ff00,
+	"blue":    0x0000ff,
+	"yellow":  0xffff00,
+	"cyan":    0x00ffff,
+	"aqua":    0x00ffff,
+	"magenta": 0xff00ff,
+	"fuchsia": 0xff00ff,
+	"gray":    0x808080,
+	"grey":    0x808080,
+	"silver":  0xc0c0c0,
+	"maroon":  0x800000,
+	"olive":   0x808000,
+	"navy":    0x000080,
+	"purple":  0x800080,
+	"teal":    0x008080,
+	"orange":  0xffa500,
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package svg_test
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/svg"
+	"gioui.org/ui/uitest"
+)
+
+const testIcon = `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="20" height="10" viewBox="0 0 40 20">
+	<g transform="translate(20 0)" fill="red">
+		<rect width="20" height="20" style="fill: #00f"/>
+	</g>
+	<circle cx="10" cy="10" r="5"/>
+	<path d="M0 0 L40 0" stroke="lime" stroke-width="2"/>
+</svg>`
+
+func TestIcon(t *testing.T) {
+	ic, err := svg.Decode(strings.NewReader(testIcon))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := new(ui.Ops)
+	cs := layout.Constraints{
+		Width:  layout.Constraint{Max: 100},
+		Height: layout.Constraint{Max: 100},
+	}
+	dims := ic.Layout(&uitest.Config{PxPerDp: 4}, ops, cs)
+	if want := (image.Point{X: 80, Y: 40}); dims.Size != want {
+		t.Errorf("got size %v, expected %v", dims.Size, want)
+	}
+	img := uitest.Render(ops, image.Point{X: 80, Y: 80})
+	tests := []struct {
+		p       image.Point
+		r, g, b uint8
+	}{
+		{image.Point{X: 20, Y: 20}, 0, 0, 0},
+		{image.Point{X: 60, Y: 20}, 0, 0, 0xff},
+		{image.Point{X: 5, Y: 30}, 0xff, 0xff, 0xff},
+		{image.Point{X: 5, Y: 0}, 0, 0xff, 0},
+	}
+	for _, test := range tests {
+		c := img.RGBAAt(test.p.X, test.p.Y)
+		if c.R != test.r || c.G != test.g || c.B != test.b {
+			t.Errorf("%v: got %v, expected %d,%d,%d", test.p, c, test.r, test.g, test.b)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		`<html/>`,
+		`<svg/>`,
+		`<svg viewBox="0 0 10"/>`,
+		`<svg viewBox="0 0 10 10"><path d="M0 0 Q"/></svg>`,
+		`<svg viewBox="0 0 10 10"><rect fill="#12"/></svg>`,
+	} {
+		if _, err := svg.Decode(strings.NewReader(s)); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
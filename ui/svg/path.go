@@ -0,0 +1,514 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"gioui.org/ui/f32"
+	"gioui.org/ui/paint"
+)
+
+// Path is parsed SVG path data.
+type Path struct {
+	segs []segment
+	// bounds contains the path and its
+	// control points.
+	bounds    f32.Rectangle
+	hasBounds bool
+}
+
+type segment struct {
+	op  segOp
+	pts [3]f32.Point
+}
+
+type segOp uint8
+
+const (
+	segMove segOp = iota
+	segLine
+	segQuad
+	segCube
+	segClose
+)
+
+// ParsePath parses the path data of the d attribute of an
+// SVG path element.
+func ParsePath(d string) (*Path, error) {
+	p := &pathParser{s: d}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("svg: invalid path data %q: %v", d, err)
+	}
+	return &p.path, nil
+}
+
+// Bounds returns a rectangle that contains the
+// path and its control points.
+func (p *Path) Bounds() f32.Rectangle {
+	return p.bounds
+}
+
+// Fill records the path in b. Every subpath is closed as
+// required for filling.
+func (p *Path) Fill(b *paint.PathBuilder) {
+	var pen, start f32.Point
+	for _, s := range p.segs {
+		switch s.op {
+		case segMove:
+			if pen != start {
+				b.Line(start.Sub(pen))
+			}
+			b.Move(s.pts[0].Sub(pen))
+			pen, start = s.pts[0], s.pts[0]
+			continue
+		case segLine:
+			b.Line(s.pts[0].Sub(pen))
+		case segQuad:
+			b.Quad(s.pts[0].Sub(pen), s.pts[1].Sub(pen))
+		case segCube:
+			b.Cube(s.pts[0].Sub(pen), s.pts[1].Sub(pen), s.pts[2].Sub(pen))
+		case segClose:
+			if pen != start {
+				b.Line(start.Sub(pen))
+			}
+			pen = start
+			continue
+		}
+		pen = s.end()
+	}
+	if pen != start {
+		b.Line(start.Sub(pen))
+	}
+}
+
+// Stroke records the path in s.
+func (p *Path) Stroke(s *paint.Stroker) {
+	var pen f32.Point
+	for _, seg := range p.segs {
+		switch seg.op {
+		case segMove:
+			s.Move(seg.pts[0].Sub(pen))
+		case segLine:
+			s.Line(seg.pts[0].Sub(pen))
+		case segQuad:
+			s.Quad(seg.pts[0].Sub(pen), seg.pts[1].Sub(pen))
+		case segCube:
+			s.Cube(seg.pts[0].Sub(pen), seg.pts[1].Sub(pen), seg.pts[2].Sub(pen))
+		case segClose:
+			s.Close()
+		}
+		pen = seg.end()
+	}
+}
+
+// end returns the pen position after the segment. The end of
+// a segClose segment is set by the parser.
+func (s segment) end() f32.Point {
+	switch s.op {
+	case segQuad:
+		return s.pts[1]
+	case segCube:
+		return s.pts[2]
+	default:
+		return s.pts[0]
+	}
+}
+
+type pathParser struct {
+	s    string
+	pos  int
+	path Path
+	// pen is the current point and start is the
+	// start of the current subpath.
+	pen, start f32.Point
+	// ctrl is the last control point of the previous
+	// segment, for reflection by S and T commands.
+	ctrl    f32.Point
+	lastCmd byte
+	started bool
+}
+
+func (p *pathParser) parse() error {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return nil
+	}
+	var cmd byte
+	for {
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			return nil
+		}
+		if c := p.s[p.pos]; isCommand(c) {
+			cmd = c
+			p.pos++
+		} else if cmd == 0 {
+			return errors.New("missing command")
+		} else if cmd == 'Z' || cmd == 'z' {
+			return fmt.Errorf("unexpected %q after close", c)
+		}
+		if !p.started && cmd != 'M' && cmd != 'm' {
+			return errors.New("path must start with a move")
+		}
+		if err := p.command(cmd); err != nil {
+			return err
+		}
+		p.lastCmd = cmd
+		// Repeated move coordinates are implicit lines.
+		switch cmd {
+		case 'M':
+			cmd = 'L'
+		case 'm':
+			cmd = 'l'
+		}
+	}
+}
+
+func (p *pathParser) command(cmd byte) error {
+	rel := cmd >= 'a'
+	var base f32.Point
+	if rel {
+		base = p.pen
+	}
+	switch cmd {
+	case 'M', 'm':
+		to, err := p.point()
+		if err != nil {
+			return err
+		}
+		to = to.Add(base)
+		p.started = true
+		p.add(segMove, to)
+		p.start = to
+	case 'L', 'l':
+		to, err := p.point()
+		if err != nil {
+			return err
+		}
+		p.add(segLine, to.Add(base))
+	case 'H', 'h':
+		x, err := p.number()
+		if err != nil {
+			return err
+		}
+		to := p.pen
+		to.X = x + base.X
+		p.add(segLine, to)
+	case 'V', 'v':
+		y, err := p.number()
+		if err != nil {
+			return err
+		}
+		to := p.pen
+		to.Y = y + base.Y
+		p.add(segLine, to)
+	case 'C', 'c':
+		pts, err := p.points(3)
+		if err != nil {
+			return err
+		}
+		p.add(segCube, pts[0].Add(base), pts[1].Add(base), pts[2].Add(base))
+	case 'S', 's':
+		pts, err := p.points(2)
+		if err != nil {
+			return err
+		}
+		p.add(segCube, p.reflect("CcSs"), pts[0].Add(base), pts[1].Add(base))
+	case 'Q', 'q':
+		pts, err := p.points(2)
+		if err != nil {
+			return err
+		}
+		p.add(segQuad, pts[0].Add(base), pts[1].Add(base))
+	case 'T', 't':
+		to, err := p.point()
+		if err != nil {
+			return err
+		}
+		p.add(segQuad, p.reflect("QqTt"), to.Add(base))
+	case 'A', 'a':
+		return p.arc(base)
+	case 'Z', 'z':
+		p.path.add(segClose, p.start)
+		p.pen = p.start
+		p.ctrl = p.start
+	}
+	return nil
+}
+
+// reflect returns the reflection of the previous control point
+// if the previous command is one of cmds. Otherwise, it returns
+// the current point.
+func (p *pathParser) reflect(cmds string) f32.Point {
+	for i := 0; i < len(cmds); i++ {
+		if p.lastCmd == cmds[i] {
+			return p.pen.Mul(2).Sub(p.ctrl)
+		}
+	}
+	return p.pen
+}
+
+// add a segment of the given type and points.
+func (p *pathParser) add(op segOp, pts ...f32.Point) {
+	p.path.add(op, pts...)
+	p.pen = pts[len(pts)-1]
+	if len(pts) > 1 {
+		p.ctrl = pts[len(pts)-2]
+	} else {
+		p.ctrl = p.pen
+	}
+}
+
+// add a segment of the given type and points.
+func (p *Path) add(op segOp, pts ...f32.Point) {
+	s := segment{op: op}
+	copy(s.pts[:], pts)
+	p.segs = append(p.segs, s)
+	if op == segClose {
+		return
+	}
+	for _, pt := range pts {
+		p.expand(pt)
+	}
+}
+
+func (p *Path) expand(pt f32.Point) {
+	b := &p.bounds
+	if !p.hasBounds {
+		p.hasBounds = true
+		*b = f32.Rectangle{Min: pt, Max: pt}
+		return
+	}
+	b.Min.X = min(b.Min.X, pt.X)
+	b.Min.Y = min(b.Min.Y, pt.Y)
+	b.Max.X = max(b.Max.X, pt.X)
+	b.Max.Y = max(b.Max.Y, pt.Y)
+}
+
+// arc parses an elliptical arc and converts it to cubic
+// Béziers, following the SVG implementation notes for
+// converting from endpoint to center parameterization.
+func (p *pathParser) arc(base f32.Point) error {
+	radii, err := p.point()
+	if err != nil {
+		return err
+	}
+	rot, err := p.number()
+	if err != nil {
+		return err
+	}
+	large, err := p.flag()
+	if err != nil {
+		return err
+	}
+	sweep, err := p.flag()
+	if err != nil {
+		return err
+	}
+	to, err := p.point()
+	if err != nil {
+		return err
+	}
+	to = to.Add(base)
+	from := p.pen
+	rx, ry := math.Abs(float64(radii.X)), math.Abs(float64(radii.Y))
+	if from == to {
+		return nil
+	}
+	if rx == 0 || ry == 0 {
+		p.add(segLine, to)
+		return nil
+	}
+	sinPhi, cosPhi := math.Sincos(float64(rot) * math.Pi / 180)
+	// Step 1: compute the transformed start point.
+	dx2, dy2 := float64(from.X-to.X)/2, float64(from.Y-to.Y)/2
+	x1 := cosPhi*dx2 + sinPhi*dy2
+	y1 := -sinPhi*dx2 + cosPhi*dy2
+	// Scale up out of range radii.
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		s := math.Sqrt(l)
+		rx, ry = rx*s, ry*s
+	}
+	// Step 2: compute the transformed center.
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(num/den, 0))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+	// Step 3: compute the center.
+	cx := cosPhi*cx1 - sinPhi*cy1 + float64(from.X+to.X)/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + float64(from.Y+to.Y)/2
+	// Step 4: compute the start and sweep angles.
+	theta := vecAngle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := vecAngle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	// Approximate the arc with cubic Béziers of at most
+	// a quarter circle each.
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+	// point returns the point on the ellipse at angle a, moved
+	// t times along the tangent of the unit circle.
+	point := func(a, t float64) f32.Point {
+		sin, cos := math.Sincos(a)
+		x := rx * (cos - t*sin)
+		y := ry * (sin + t*cos)
+		return f32.Point{
+			X: float32(cx + cosPhi*x - sinPhi*y),
+			Y: float32(cy + sinPhi*x + cosPhi*y),
+		}
+	}
+	a := theta
+	for i := 0; i < n; i++ {
+		b := a + step
+		end := to
+		if i < n-1 {
+			end = point(b, 0)
+		}
+		p.add(segCube, point(a, k), point(b, -k), end)
+		a = b
+	}
+	return nil
+}
+
+// vecAngle returns the signed angle between two vectors.
+func vecAngle(ux, uy, vx, vy float64) float64 {
+	return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+}
+
+func (p *pathParser) points(n int) ([3]f32.Point, error) {
+	var pts [3]f32.Point
+	for i := 0; i < n; i++ {
+		pt, err := p.point()
+		if err != nil {
+			return pts, err
+		}
+		pts[i] = pt
+	}
+	return pts, nil
+}
+
+func (p *pathParser) point() (f32.Point, error) {
+	x, err := p.number()
+	if err != nil {
+		return f32.Point{}, err
+	}
+	y, err := p.number()
+	if err != nil {
+		return f32.Point{}, err
+	}
+	return f32.Point{X: x, Y: y}, nil
+}
+
+// flag parses an arc flag. Flags need no separators.
+func (p *pathParser) flag() (bool, error) {
+	p.skipSeparator()
+	if p.pos == len(p.s) {
+		return false, errors.New("missing flag")
+	}
+	c := p.s[p.pos]
+	if c != '0' && c != '1' {
+		return false, fmt.Errorf("invalid flag %q", c)
+	}
+	p.pos++
+	return c == '1', nil
+}
+
+func (p *pathParser) number() (float32, error) {
+	p.skipSeparator()
+	start := p.pos
+	s := p.s
+	i := p.pos
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := false
+	for i < len(s) && isDigit(s[i]) {
+		i++
+		digits = true
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			i++
+			digits = true
+		}
+	}
+	if !digits {
+		return 0, fmt.Errorf("expected number at offset %d", start)
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	v, err := strconv.ParseFloat(s[start:i], 32)
+	if err != nil {
+		return 0, err
+	}
+	p.pos = i
+	return float32(v), nil
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.s) && isSpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// skipSeparator skips white space and at most one comma.
+func (p *pathParser) skipSeparator() {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == ',' {
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+func isCommand(c byte) bool {
+	switch c {
+	case 'M', 'm', 'L', 'l', 'H', 'h', 'V', 'v', 'C', 'c', 'S', 's', 'Q', 'q', 'T', 't', 'A', 'a', 'Z', 'z':
+		return true
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package svg
+
+import (
+	"testing"
+
+	"gioui.org/ui/f32"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		d    string
+		ops  []segOp
+		end  f32.Point
+		bnds f32.Rectangle
+	}{
+		{
+			d:    "M10 10 20 10 v10 h-10z",
+			ops:  []segOp{segMove, segLine, segLine, segLine, segClose},
+			end:  f32.Point{X: 10, Y: 10},
+			bnds: f32.Rectangle{Min: f32.Point{X: 10, Y: 10}, Max: f32.Point{X: 20, Y: 20}},
+		},
+		{
+			d:    "m1,2c1,0 2,0 2,1s1,1 1,1",
+			ops:  []segOp{segMove, segCube, segCube},
+			end:  f32.Point{X: 4, Y: 4},
+			bnds: f32.Rectangle{Min: f32.Point{X: 1, Y: 2}, Max: f32.Point{X: 4, Y: 4}},
+		},
+		{
+			d:    "M0 0Q5-5 10 0T20 0",
+			ops:  []segOp{segMove, segQuad, segQuad},
+			end:  f32.Point{X: 20, Y: 0},
+			bnds: f32.Rectangle{Min: f32.Point{X: 0, Y: -5}, Max: f32.Point{X: 20, Y: 5}},
+		},
+		{
+			// A half circle with unseparated flags.
+			d:    "M0 0A5 5 0 0110 0",
+			ops:  []segOp{segMove, segCube, segCube},
+			end:  f32.Point{X: 10, Y: 0},
+			bnds: f32.Rectangle{Min: f32.Point{X: 0, Y: -5}, Max: f32.Point{X: 10, Y: 0}},
+		},
+		{
+			d:    "M.5.5-1e1.5",
+			ops:  []segOp{segMove, segLine},
+			end:  f32.Point{X: -10, Y: .5},
+			bnds: f32.Rectangle{Min: f32.Point{X: -10, Y: .5}, Max: f32.Point{X: .5, Y: .5}},
+		},
+	}
+	for _, test := range tests {
+		p, err := ParsePath(test.d)
+		if err != nil {
+			t.Errorf("%q: %v", test.d, err)
+			continue
+		}
+		if len(p.segs) != len(test.ops) {
+			t.Errorf("%q: got %d segments, expected %d", test.d, len(p.segs), len(test.ops))
+			continue
+		}
+		for i, s := range p.segs {
+			if s.op != test.ops[i] {
+				t.Errorf("%q: segment %d is %d, expected %d", test.d, i, s.op, test.ops[i])
+			}
+		}
+		if end := p.segs[len(p.segs)-1].end(); !near(end, test.end) {
+			t.Errorf("%q: ends at %v, expected %v", test.d, end, test.end)
+		}
+		if b := p.Bounds(); !near(b.Min, test.bnds.Min) || !near(b.Max, test.bnds.Max) {
+			t.Errorf("%q: bounds %v, expected %v", test.d, b, test.bnds)
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, d := range []string{"L1 1", "M1", "M1 1 L", "M1 1 X", "M0 0 A1 1 0 2 0 1 1", "M0 0 Z 1"} {
+		if _, err := ParsePath(d); err == nil {
+			t.Errorf("%q: expected error", d)
+		}
+	}
+}
+
+func near(a, b f32.Point) bool {
+	d := a.Sub(b)
+	return d.X*d.X+d.Y*d.Y < 1e-6
+}
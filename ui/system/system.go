@@ -5,6 +5,9 @@
 package system
 
 import (
+	"fmt"
+	"time"
+
 	"gioui.org/ui"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/opconst"
@@ -19,8 +22,39 @@ type ProfileOp struct {
 // ProfileEvent contain profile data from a single
 // rendered frame.
 type ProfileEvent struct {
-	// String with timings. Very likely to change.
-	Timings string
+	// Frame is the time since the previous frame.
+	Frame time.Duration
+	// Events is the time spent routing input events
+	// since the previous frame.
+	Events time.Duration
+	// Layout is the time from the start of the frame
+	// to the submission of its operations.
+	Layout time.Duration
+	// Collect is the time spent processing the
+	// operations for rendering.
+	Collect time.Duration
+	// Blit is the time spent blitting opaque images.
+	Blit time.Duration
+	// Stencil is the time spent stenciling paths.
+	Stencil time.Duration
+	// Cover is the time spent drawing the remaining
+	// operations.
+	Cover time.Duration
+	// GPU is the GPU time of the most recently measured
+	// frame, or zero if the GPU doesn't support timers.
+	GPU time.Duration
+	// DrawCalls is the number of draw calls.
+	DrawCalls int
+	// Paths is the number of paths drawn.
+	Paths int
+	// UploadBytes is the number of bytes uploaded to the
+	// GPU.
+	UploadBytes int
+	// TextureHitRate and PathHitRate are the fractions
+	// of the lookups in the texture and path caches that
+	// found a resource from an earlier frame. They are
+	// zero for frames without lookups.
+	TextureHitRate, PathHitRate float32
 }
 
 func (p ProfileOp) Add(o *ui.Ops) {
@@ -29,4 +63,12 @@ func (p ProfileOp) Add(o *ui.Ops) {
 	o.Write(data, p.Key)
 }
 
+// String returns a short summary of the profile.
+func (p ProfileEvent) String() string {
+	q := 100 * time.Microsecond
+	cpu := p.Layout + p.Collect + p.Blit + p.Stencil + p.Cover
+	return fmt.Sprintf("tot:%7s cpu:%7s gpu:%7s draws:%d paths:%d up:%dk",
+		p.Frame.Round(q), cpu.Round(q), p.GPU.Round(q), p.DrawCalls, p.Paths, p.UploadBytes/1024)
+}
+
 func (p ProfileEvent) ImplementsEvent() {}
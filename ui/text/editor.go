@@ -52,6 +52,11 @@ type Editor struct {
 	padTop, padBottom int
 	padLeft, padRight int
 	requestFocus      bool
+	// preeditStart and preeditLen is the byte range in rr
+	// of the text composed by an input method. The preedit
+	// text is displayed but is not part of the content.
+	preeditStart int
+	preeditLen   int
 
 	it lineIterator
 
@@ -112,6 +117,7 @@ func (e *Editor) Next(cfg ui.Config, queue input.Queue) (EditorEvent, bool) {
 		case evt.Type == gesture.TypePress && evt.Source == pointer.Mouse,
 			evt.Type == gesture.TypeClick && evt.Source == pointer.Touch:
 			e.blinkStart = cfg.Now()
+			e.commitPreedit()
 			e.moveCoord(image.Point{
 				X: int(math.Round(float64(evt.Position.X))),
 				Y: int(math.Round(float64(evt.Position.Y))),
@@ -125,15 +131,23 @@ func (e *Editor) Next(cfg ui.Config, queue input.Queue) (EditorEvent, bool) {
 	if (sdist > 0 && soff >= smax) || (sdist < 0 && soff <= smin) {
 		e.scroller.Stop()
 	}
+	if e.rr.Changed() {
+		// A click committed the preedit text.
+		return ChangeEvent{}, true
+	}
 	for ke, ok := queue.Next(e); ok; ke, ok = queue.Next(e) {
 		e.blinkStart = cfg.Now()
 		switch ke := ke.(type) {
 		case key.FocusEvent:
 			e.focused = ke.Focus
-		case key.Event:
 			if !e.focused {
+				e.commitPreedit()
+			}
+		case key.Event:
+			if !e.focused || ke.State != key.Press {
 				break
 			}
+			e.commitPreedit()
 			if e.Submit && (ke.Name == key.NameReturn || ke.Name == key.NameEnter) {
 				if !ke.Modifiers.Contain(key.ModShift) {
 					return SubmitEvent{}, true
@@ -146,7 +160,14 @@ func (e *Editor) Next(cfg ui.Config, queue input.Queue) (EditorEvent, bool) {
 		case key.EditEvent:
 			e.scrollToCaret(cfg)
 			e.scroller.Stop()
+			e.removePreedit()
+			e.deleteSurrounding(ke.DeleteBefore, ke.DeleteAfter)
 			e.append(ke.Text)
+		case key.PreeditEvent:
+			e.setPreedit(ke)
+			e.scrollToCaret(cfg)
+			// The preedit text is not a change to the content.
+			e.rr.Changed()
 		}
 		if e.rr.Changed() {
 			return ChangeEvent{}, true
@@ -227,7 +248,29 @@ func (e *Editor) Layout(cfg ui.Config, queue input.Queue, ops *ui.Ops, cs layout
 		paint.PaintOp{Rect: toRectF(clip).Sub(lineOff)}.Add(ops)
 		stack.Pop()
 	}
+	if e.preeditLen > 0 {
+		e.drawPreedit(cfg, ops, off, clip)
+	}
 	if e.focused {
+		carWidth := e.caretWidth(cfg)
+		carX -= carWidth / 2
+		carAsc, carDesc := -lines[carLine].Bounds.Min.Y, lines[carLine].Bounds.Max.Y
+		carRect := image.Rectangle{
+			Min: image.Point{X: carX.Ceil(), Y: carY - carAsc.Ceil()},
+			Max: image.Point{X: carX.Ceil() + carWidth.Ceil(), Y: carY + carDesc.Ceil()},
+		}
+		carRect = carRect.Add(off)
+		cursor := e.rr.caret
+		if e.preeditLen > 0 {
+			cursor = e.preeditStart
+		}
+		key.TextInputOp{
+			Key:    e,
+			Caret:  carRect,
+			Text:   e.Text(),
+			Cursor: cursor,
+			Anchor: cursor,
+		}.Add(ops)
 		now := cfg.Now()
 		dt := now.Sub(e.blinkStart)
 		blinking := dt < maxBlinkDuration
@@ -235,17 +278,6 @@ func (e *Editor) Layout(cfg ui.Config, queue input.Queue, ops *ui.Ops, cs layout
 		nextBlink := now.Add(timePerBlink/2 - dt%(timePerBlink/2))
 		on := !blinking || dt%timePerBlink < timePerBlink/2
 		if on {
-			carWidth := e.caretWidth(cfg)
-			carX -= carWidth / 2
-			carAsc, carDesc := -lines[carLine].Bounds.Min.Y, lines[carLine].Bounds.Max.Y
-			carRect := image.Rectangle{
-				Min: image.Point{X: carX.Ceil(), Y: carY - carAsc.Ceil()},
-				Max: image.Point{X: carX.Ceil() + carWidth.Ceil(), Y: carY + carDesc.Ceil()},
-			}
-			carRect = carRect.Add(image.Point{
-				X: -e.scrollOff.X + e.padLeft,
-				Y: -e.scrollOff.Y + e.padTop,
-			})
 			carRect = clip.Intersect(carRect)
 			if !carRect.Empty() {
 				paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
@@ -273,18 +305,128 @@ func (e *Editor) Layout(cfg ui.Config, queue input.Queue, ops *ui.Ops, cs layout
 	return layout.Dimens{Size: e.viewSize, Baseline: baseline}
 }
 
-// Text returns the contents of the editor.
+// Text returns the contents of the editor. Text being
+// composed by an input method is not included.
 func (e *Editor) Text() string {
-	return e.rr.String()
+	s := e.rr.String()
+	if e.preeditLen > 0 {
+		s = s[:e.preeditStart] + s[e.preeditStart+e.preeditLen:]
+	}
+	return s
 }
 
 // SetText replaces the contents of the editor.
 func (e *Editor) SetText(s string) {
 	e.rr = editBuffer{}
 	e.carXOff = 0
+	e.preeditStart, e.preeditLen = 0, 0
 	e.prepend(s)
 }
 
+// drawPreedit underlines the preedit text.
+func (e *Editor) drawPreedit(cfg ui.Config, ops *ui.Ops, off image.Point, clip image.Rectangle) {
+	thickness := cfg.Px(ui.Dp(1))
+	if thickness < 1 {
+		thickness = 1
+	}
+	start, end := e.preeditStart, e.preeditStart+e.preeditLen
+	var (
+		idx      int
+		y        int
+		prevDesc fixed.Int26_6
+	)
+	paint.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	e.Material.Add(ops)
+	for _, l := range e.lines {
+		y += (prevDesc + l.Ascent).Ceil()
+		prevDesc = l.Descent
+		if idx >= end {
+			break
+		}
+		x := align(e.Alignment, l.Width, e.viewWidth())
+		minX, maxX := fixed.Int26_6(-1), fixed.Int26_6(-1)
+		str := l.Text.String
+		for _, adv := range l.Text.Advances {
+			_, s := utf8.DecodeRuneInString(str)
+			if idx >= start && idx < end {
+				if minX == -1 {
+					minX = x
+				}
+				maxX = x + adv
+			}
+			x += adv
+			idx += s
+			str = str[s:]
+		}
+		idx += len(str)
+		if minX == -1 {
+			continue
+		}
+		r := image.Rectangle{
+			Min: image.Point{X: minX.Floor(), Y: y + thickness},
+			Max: image.Point{X: maxX.Ceil(), Y: y + 2*thickness},
+		}
+		r = clip.Intersect(r.Add(off))
+		if !r.Empty() {
+			paint.PaintOp{Rect: toRectF(r)}.Add(ops)
+		}
+	}
+}
+
+// setPreedit replaces the preedit text.
+func (e *Editor) setPreedit(p key.PreeditEvent) {
+	e.removePreedit()
+	if p.Text == "" {
+		return
+	}
+	start := e.rr.caret
+	e.prepend(p.Text)
+	e.preeditStart, e.preeditLen = start, len(p.Text)
+	cursor := len(p.Text)
+	if p.Begin >= 0 && p.End >= 0 && p.End <= len(p.Text) {
+		cursor = p.End
+	}
+	e.rr.caret = start + cursor
+}
+
+// removePreedit removes the preedit text and moves the caret
+// to where it was.
+func (e *Editor) removePreedit() {
+	if e.preeditLen == 0 {
+		return
+	}
+	start := e.preeditStart
+	e.rr.caret = start + e.preeditLen
+	for e.rr.caret > start {
+		e.deleteRune()
+	}
+	e.preeditStart, e.preeditLen = 0, 0
+}
+
+// commitPreedit makes the preedit text part of the content.
+func (e *Editor) commitPreedit() {
+	if e.preeditLen == 0 {
+		return
+	}
+	e.preeditStart, e.preeditLen = 0, 0
+	e.rr.changed = true
+}
+
+// deleteSurrounding deletes before bytes before the caret and
+// after bytes after the caret, rounded to whole runes.
+func (e *Editor) deleteSurrounding(before, after int) {
+	for before > 0 && e.rr.caret > 0 {
+		_, s := e.rr.runeBefore(e.rr.caret)
+		e.deleteRune()
+		before -= s
+	}
+	for after > 0 && e.rr.caret < e.rr.len() {
+		_, s := e.rr.runeAt(e.rr.caret)
+		e.deleteRuneForward()
+		after -= s
+	}
+}
+
 func (e *Editor) layout() {
 	e.adjustScroll()
 	if e.valid {
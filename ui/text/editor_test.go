@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"image"
+	"testing"
+	"unicode/utf8"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/key"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/pointer"
+	"gioui.org/ui/uitest"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// fakeFace lays out text on a single line where every rune
+// is 10 pixels wide.
+type fakeFace struct{}
+
+func (fakeFace) Layout(s string, opts LayoutOptions) *Layout {
+	advs := make([]fixed.Int26_6, utf8.RuneCountInString(s))
+	for i := range advs {
+		advs[i] = fixed.I(10)
+	}
+	w := fixed.I(10 * len(advs))
+	return &Layout{Lines: []Line{{
+		Text:    String{String: s, Advances: advs},
+		Width:   w,
+		Ascent:  fixed.I(8),
+		Descent: fixed.I(2),
+		Bounds: fixed.Rectangle26_6{
+			Min: fixed.Point26_6{Y: -fixed.I(8)},
+			Max: fixed.Point26_6{X: w, Y: fixed.I(2)},
+		},
+	}}}
+}
+
+func (fakeFace) Path(s String) ui.MacroOp {
+	return ui.MacroOp{}
+}
+
+func TestEditorPreedit(t *testing.T) {
+	e := &Editor{Face: fakeFace{}, SingleLine: true}
+	cfg, q := new(uitest.Config), new(uitest.Queue)
+	q.Add(e, key.FocusEvent{Focus: true}, key.EditEvent{Text: "ab"})
+	if n := changes(e, cfg, q); n != 1 {
+		t.Fatalf("got %d changes from the edit, expected 1", n)
+	}
+	tests := []struct {
+		name  string
+		event key.PreeditEvent
+		buf   string
+		caret int
+	}{
+		{name: "compose", event: key.PreeditEvent{Text: "にほ", Begin: -1, End: -1}, buf: "abにほ", caret: 8},
+		{name: "replace", event: key.PreeditEvent{Text: "日本", Begin: 0, End: 3}, buf: "ab日本", caret: 5},
+		{name: "clear", event: key.PreeditEvent{}, buf: "ab", caret: 2},
+	}
+	for _, test := range tests {
+		q.Add(e, test.event)
+		if n := changes(e, cfg, q); n != 0 {
+			t.Errorf("%s: got %d changes, expected none", test.name, n)
+		}
+		if got := e.rr.String(); got != test.buf {
+			t.Errorf("%s: got buffer %q, expected %q", test.name, got, test.buf)
+		}
+		if e.rr.caret != test.caret {
+			t.Errorf("%s: got caret %d, expected %d", test.name, e.rr.caret, test.caret)
+		}
+		// The preedit text is not part of the content.
+		if got := e.Text(); got != "ab" {
+			t.Errorf("%s: got text %q, expected %q", test.name, got, "ab")
+		}
+	}
+
+	// An EditEvent replaces the preedit text with the committed text.
+	q.Add(e, key.PreeditEvent{Text: "にほ", Begin: -1, End: -1}, key.EditEvent{Text: "日本"})
+	if n := changes(e, cfg, q); n != 1 {
+		t.Errorf("got %d changes from the commit, expected 1", n)
+	}
+	if got := e.Text(); got != "ab日本" {
+		t.Errorf("got committed text %q, expected %q", got, "ab日本")
+	}
+	if e.preeditLen != 0 {
+		t.Errorf("got preedit length %d after the commit, expected 0", e.preeditLen)
+	}
+
+	// Losing the focus commits the preedit text.
+	q.Add(e, key.PreeditEvent{Text: "x", Begin: -1, End: -1}, key.FocusEvent{Focus: false})
+	if n := changes(e, cfg, q); n != 1 {
+		t.Errorf("got %d changes from the focus loss, expected 1", n)
+	}
+	if got := e.Text(); got != "ab日本x" {
+		t.Errorf("got text %q after the focus loss, expected %q", got, "ab日本x")
+	}
+}
+
+func TestEditorPreeditClick(t *testing.T) {
+	e := &Editor{Face: fakeFace{}, SingleLine: true}
+	cfg, q := new(uitest.Config), new(uitest.Queue)
+	cs := layout.RigidConstraints(image.Point{X: 200, Y: 20})
+	q.Add(e, key.FocusEvent{Focus: true}, key.EditEvent{Text: "ab"}, key.PreeditEvent{Text: "日本", Begin: -1, End: -1})
+	e.Layout(cfg, q, new(ui.Ops), cs)
+	if got := e.Text(); got != "ab" {
+		t.Fatalf("got text %q, expected %q", got, "ab")
+	}
+	// Press between 'a' and 'b', after the 2dp padding.
+	q.Add(&e.clicker, pointer.Event{
+		Type:     pointer.Press,
+		Source:   pointer.Mouse,
+		Buttons:  pointer.ButtonPrimary,
+		Hit:      true,
+		Position: f32.Point{X: 12, Y: 5},
+	})
+	evt, ok := e.Next(cfg, q)
+	if _, isChange := evt.(ChangeEvent); !ok || !isChange {
+		t.Fatalf("got event %v, %v from the click, expected a ChangeEvent", evt, ok)
+	}
+	if got := e.Text(); got != "ab日本" {
+		t.Errorf("got text %q after the click, expected %q", got, "ab日本")
+	}
+	if e.preeditLen != 0 {
+		t.Errorf("got preedit length %d after the click, expected 0", e.preeditLen)
+	}
+	if e.rr.caret != 1 {
+		t.Errorf("got caret %d after the click, expected 1", e.rr.caret)
+	}
+}
+
+func TestEditorDeleteSurrounding(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after int
+		preedit       string
+		exp           string
+	}{
+		{name: "none", exp: "aé日b本"},
+		// Partial runes are deleted whole.
+		{name: "before", before: 1, exp: "aéb本"},
+		{name: "before two runes", before: 4, exp: "ab本"},
+		{name: "after", after: 1, exp: "aé日本"},
+		{name: "after two runes", after: 2, exp: "aé日"},
+		{name: "both", before: 3, after: 1, exp: "aé本"},
+		{name: "beyond", before: 100, after: 100, exp: ""},
+		// The preedit text is removed before the deletion.
+		{name: "preedit", before: 3, after: 1, preedit: "にほ", exp: "aé本"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &Editor{Face: fakeFace{}}
+			cfg, q := new(uitest.Config), new(uitest.Queue)
+			e.SetText("aé日b本")
+			// Place the caret after 日.
+			e.rr.caret = len("aé日")
+			q.Add(e, key.FocusEvent{Focus: true})
+			if test.preedit != "" {
+				q.Add(e, key.PreeditEvent{Text: test.preedit, Begin: -1, End: -1})
+			}
+			q.Add(e, key.EditEvent{DeleteBefore: test.before, DeleteAfter: test.after})
+			changes(e, cfg, q)
+			if got := e.Text(); got != test.exp {
+				t.Errorf("got text %q, expected %q", got, test.exp)
+			}
+			if e.preeditLen != 0 {
+				t.Errorf("got preedit length %d, expected 0", e.preeditLen)
+			}
+		})
+	}
+}
+
+// changes drains the events of e and returns the number
+// of ChangeEvents.
+func changes(e *Editor, cfg ui.Config, q *uitest.Queue) int {
+	n := 0
+	for evt, ok := e.Next(cfg, q); ok; evt, ok = e.Next(cfg, q) {
+		if _, ok := evt.(ChangeEvent); ok {
+			n++
+		}
+	}
+	return n
+}
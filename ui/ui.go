@@ -26,10 +26,10 @@ type InvalidateOp struct {
 	At time.Time
 }
 
-// TransformOp applies a transform to the current transform.
+// TransformOp applies a transform to the current transform. The
+// zero value of TransformOp is the identity transform.
 type TransformOp struct {
-	// TODO: general transformations.
-	offset f32.Point
+	t f32.Affine2D
 }
 
 func (r InvalidateOp) Add(o *Ops) {
@@ -46,33 +46,47 @@ func (r InvalidateOp) Add(o *Ops) {
 	o.Write(data)
 }
 
+// Affine returns a TransformOp for the affine transformation a.
+func Affine(a f32.Affine2D) TransformOp {
+	return TransformOp{t: a}
+}
+
 // Offset the transformation.
 func (t TransformOp) Offset(o f32.Point) TransformOp {
-	return t.Multiply(TransformOp{o})
+	return t.Multiply(TransformOp{t: f32.Affine2D{}.Offset(o)})
 }
 
 // Invert the transformation.
 func (t TransformOp) Invert() TransformOp {
-	return TransformOp{offset: t.offset.Mul(-1)}
+	return TransformOp{t: t.t.Invert()}
 }
 
 // Transform a point.
 func (t TransformOp) Transform(p f32.Point) f32.Point {
-	return p.Add(t.offset)
+	return t.t.Transform(p)
 }
 
-// Multiply by a transformation.
+// Multiply by a transformation. The resulting transformation
+// applies t2 followed by t.
 func (t TransformOp) Multiply(t2 TransformOp) TransformOp {
-	return TransformOp{
-		offset: t.offset.Add(t2.offset),
-	}
+	return TransformOp{t: t.t.Mul(t2.t)}
+}
+
+// Affine2D returns the affine transformation of t.
+func (t TransformOp) Affine2D() f32.Affine2D {
+	return t.t
 }
 
 func (t TransformOp) Add(o *Ops) {
 	data := make([]byte, opconst.TypeTransformLen)
 	data[0] = byte(opconst.TypeTransform)
 	bo := binary.LittleEndian
-	bo.PutUint32(data[1:], math.Float32bits(t.offset.X))
-	bo.PutUint32(data[5:], math.Float32bits(t.offset.Y))
+	sx, hx, ox, hy, sy, oy := t.t.Elems()
+	bo.PutUint32(data[1:], math.Float32bits(sx))
+	bo.PutUint32(data[5:], math.Float32bits(hx))
+	bo.PutUint32(data[9:], math.Float32bits(ox))
+	bo.PutUint32(data[13:], math.Float32bits(hy))
+	bo.PutUint32(data[17:], math.Float32bits(sy))
+	bo.PutUint32(data[21:], math.Float32bits(oy))
 	o.Write(data)
 }
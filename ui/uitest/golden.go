@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package uitest
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Golden compares images with golden images stored
+// as PNG files.
+type Golden struct {
+	// Dir is the directory containing the golden
+	// images. Empty means "testdata".
+	Dir string
+	// Tolerance is the largest difference allowed
+	// between corresponding color components.
+	Tolerance uint8
+	// Update, if set, writes the checked images as the
+	// new golden images instead of comparing them.
+	Update bool
+}
+
+// TB is the subset of testing.TB used by Golden.
+type TB interface {
+	Helper()
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatal(args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// UpdateEnv is the environment variable that, if set to a
+// non-empty value, enables Update for every Golden.
+const UpdateEnv = "UITEST_UPDATE"
+
+// Check compares img with the golden image name.png. If the
+// images differ, Check writes name.got.png with the actual image
+// and name.diff.png marking the differing pixels, and fails t.
+//
+// If Update is set or the UITEST_UPDATE environment variable
+// is not empty, Check writes img as the new golden image
+// instead.
+func (g Golden) Check(t TB, name string, img image.Image) {
+	t.Helper()
+	dir := g.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	base := filepath.Join(dir, name)
+	if g.Update || os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writePNG(base+".png", img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := readPNG(base + ".png")
+	if err != nil {
+		t.Fatalf("%v (run with %s=1 to create golden images)", err, UpdateEnv)
+	}
+	diff, n := Diff(want, img, g.Tolerance)
+	if n == 0 {
+		return
+	}
+	if err := writePNG(base+".got.png", img); err != nil {
+		t.Error(err)
+	}
+	if err := writePNG(base+".diff.png", diff); err != nil {
+		t.Error(err)
+	}
+	t.Errorf("%s: %d pixels differ from the golden image; see %s.diff.png", name, n, base)
+}
+
+// Diff compares two images and returns the number of differing
+// pixels along with an image that marks them. A pixel differs if
+// any of its color components differ by more than tolerance, or if
+// it is only present in one of the images.
+//
+// The diff image is a dimmed copy of b with the differing pixels
+// in red.
+func Diff(a, b image.Image, tolerance uint8) (*image.RGBA, int) {
+	ab, bb := a.Bounds(), b.Bounds()
+	bounds := ab.Union(bb)
+	diff := image.NewRGBA(bounds)
+	n := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Point{X: x, Y: y}
+			if !p.In(ab) || !p.In(bb) {
+				diff.SetRGBA(x, y, color.RGBA{R: 0xff, A: 0xff})
+				n++
+				continue
+			}
+			ca := color.RGBAModel.Convert(a.At(x, y)).(color.RGBA)
+			cb := color.RGBAModel.Convert(b.At(x, y)).(color.RGBA)
+			if !colorsMatch(ca, cb, tolerance) {
+				diff.SetRGBA(x, y, color.RGBA{R: 0xff, A: 0xff})
+				n++
+				continue
+			}
+			// Dim matching pixels to make differences stand out.
+			diff.SetRGBA(x, y, color.RGBA{
+				R: cb.R/4 + 0xbf,
+				G: cb.G/4 + 0xbf,
+				B: cb.B/4 + 0xbf,
+				A: 0xff,
+			})
+		}
+	}
+	return diff, n
+}
+
+func colorsMatch(a, b color.RGBA, tolerance uint8) bool {
+	return absDiff(a.R, b.R) <= tolerance &&
+		absDiff(a.G, b.G) <= tolerance &&
+		absDiff(a.B, b.B) <= tolerance &&
+		absDiff(a.A, b.A) <= tolerance
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func readPNG(name string) (image.Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(name string, img image.Image) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package uitest provides utilities for testing user interfaces.
+
+Config is a ui.Config with a controllable clock and density and
+Queue is an input.Queue that delivers scripted events:
+
+	cfg := &uitest.Config{PxPerDp: 2}
+	q := new(uitest.Queue)
+	q.Add(&button, pointer.Event{Type: pointer.Press, ...})
+	ops := new(ui.Ops)
+	button.Layout(cfg, q, ops)
+
+Operation lists are rendered in software with Render and compared
+to checked in golden images with Golden:
+
+	img := uitest.Render(ops, image.Point{X: 100, Y: 100})
+	uitest.Golden{}.Check(t, "button", img)
+
+Run tests with the UITEST_UPDATE environment variable set, or set
+Golden.Update, to create or update golden images:
+
+	UITEST_UPDATE=1 go test
+*/
+package uitest
+
+import (
+	"image"
+	"math"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/input"
+	"gioui.org/ui/internal/raster"
+)
+
+// Config implements ui.Config with a fixed density and
+// a manually advanced clock.
+type Config struct {
+	// PxPerDp is the number of pixels per dp. Zero
+	// means 1.
+	PxPerDp float32
+	// PxPerSp is the number of pixels per sp. Zero
+	// means the value of PxPerDp.
+	PxPerSp float32
+	// Time is the time returned by Now.
+	Time time.Time
+}
+
+// Queue implements input.Queue by delivering events
+// added with Add.
+type Queue struct {
+	events map[input.Key][]input.Event
+}
+
+// Now returns c.Time.
+func (c *Config) Now() time.Time {
+	return c.Time
+}
+
+// Advance the clock by d.
+func (c *Config) Advance(d time.Duration) {
+	c.Time = c.Time.Add(d)
+}
+
+// Px converts v to pixels, rounded to the nearest
+// integer.
+func (c *Config) Px(v ui.Value) int {
+	var r float32
+	switch v.U {
+	case ui.UnitPx:
+		r = v.V
+	case ui.UnitDp:
+		r = c.pxPerDp() * v.V
+	case ui.UnitSp:
+		s := c.PxPerSp
+		if s == 0 {
+			s = c.pxPerDp()
+		}
+		r = s * v.V
+	default:
+		panic("unknown unit")
+	}
+	return int(math.Round(float64(r)))
+}
+
+func (c *Config) pxPerDp() float32 {
+	if c.PxPerDp == 0 {
+		return 1
+	}
+	return c.PxPerDp
+}
+
+// Add events for the handler k. The events are
+// delivered in order by Next.
+func (q *Queue) Add(k input.Key, events ...input.Event) {
+	if q.events == nil {
+		q.events = make(map[input.Key][]input.Event)
+	}
+	q.events[k] = append(q.events[k], events...)
+}
+
+// Next returns the next event for k, if any.
+func (q *Queue) Next(k input.Key) (input.Event, bool) {
+	events := q.events[k]
+	if len(events) == 0 {
+		return nil, false
+	}
+	e := events[0]
+	q.events[k] = events[1:]
+	return e, true
+}
+
+// Pending reports the number of undelivered events
+// for k.
+func (q *Queue) Pending(k input.Key) int {
+	return len(q.events[k])
+}
+
+// Render renders an operation list to a new image of the
+// given size. Render uses the same software renderer as
+// headless app.Windows.
+func Render(ops *ui.Ops, size image.Point) *image.RGBA {
+	return raster.Render(ops, size)
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package uitest
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/key"
+)
+
+func TestConfig(t *testing.T) {
+	c := &Config{PxPerDp: 2}
+	if got := c.Px(ui.Dp(10)); got != 20 {
+		t.Errorf("dp: got %d, expected 20", got)
+	}
+	if got := c.Px(ui.Sp(10)); got != 20 {
+		t.Errorf("sp: got %d, expected 20", got)
+	}
+	start := c.Now()
+	c.Advance(time.Second)
+	if d := c.Now().Sub(start); d != time.Second {
+		t.Errorf("clock advanced %v, expected 1s", d)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := new(Queue)
+	h1, h2 := new(int), new(int)
+	q.Add(h1, key.Event{Name: 'A'}, key.Event{Name: 'B'})
+	if _, ok := q.Next(h2); ok {
+		t.Error("unexpected event for other handler")
+	}
+	for _, exp := range []rune{'A', 'B'} {
+		e, ok := q.Next(h1)
+		if !ok || e.(key.Event).Name != exp {
+			t.Errorf("got %v, expected %c", e, exp)
+		}
+	}
+	if q.Pending(h1) != 0 {
+		t.Error("events left in queue")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	b := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	b.Set(1, 1, color.RGBA{R: 3})
+	if _, n := Diff(a, b, 3); n != 0 {
+		t.Errorf("%d pixels differ within tolerance", n)
+	}
+	diff, n := Diff(a, b, 2)
+	if n != 1 {
+		t.Errorf("got %d differing pixels, expected 1", n)
+	}
+	if c := diff.RGBAAt(1, 1); c != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("differing pixel not marked, got %v", c)
+	}
+}
+
+// fakeTB records the failures of a Golden check. Like
+// testing.T, fatal failures stop the goroutine.
+type fakeTB struct {
+	failed bool
+}
+
+func (t *fakeTB) Helper()                                   {}
+func (t *fakeTB) Error(args ...interface{})                 { t.failed = true }
+func (t *fakeTB) Errorf(format string, args ...interface{}) { t.failed = true }
+func (t *fakeTB) Fatal(args ...interface{})                 { t.failed = true; runtime.Goexit() }
+func (t *fakeTB) Fatalf(format string, args ...interface{}) { t.failed = true; runtime.Goexit() }
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	Golden{Dir: dir, Update: true}.Check(t, "img", img)
+	if _, err := os.Stat(filepath.Join(dir, "img.png")); err != nil {
+		t.Fatalf("golden image not written: %v", err)
+	}
+	g := Golden{Dir: dir}
+	g.Check(t, "img", img)
+	img.Set(1, 1, color.RGBA{G: 0xff, A: 0xff})
+	if !checkFails(g, "img", img) {
+		t.Error("differing image passed the check")
+	}
+	for _, f := range []string{"img.got.png", "img.diff.png"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("%s not written: %v", f, err)
+		}
+	}
+	if !checkFails(g, "missing", img) {
+		t.Error("missing golden image passed the check")
+	}
+}
+
+// checkFails runs g.Check in a separate goroutine and reports
+// whether it failed.
+func checkFails(g Golden, name string, img image.Image) bool {
+	ft := new(fakeTB)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Check(ft, name, img)
+	}()
+	<-done
+	return ft.failed
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/paint"
+	"gioui.org/ui/system"
+)
+
+// FrameTimings is a widget that overlays a rolling graph of
+// the CPU and GPU times of the most recent frames. The middle
+// of the graph marks the frame budget, and frames that took
+// more than one and a half budgets are marked as dropped.
+//
+// FrameTimings receives system.ProfileEvents, so the same
+// FrameTimings must be laid out in every frame.
+type FrameTimings struct {
+	// Alignment is the corner of the graph.
+	Alignment layout.Direction
+	// Budget is the frame budget. Zero means 1/60 second.
+	Budget time.Duration
+
+	frames [timingFrames]frameTiming
+	// next is the index of the next frame in frames.
+	next int
+}
+
+type frameTiming struct {
+	cpu, gpu time.Duration
+	dropped  bool
+}
+
+const (
+	// timingFrames is the number of frames in the graph.
+	timingFrames = 120
+	// timingBarWidth and timingHeight are the width of
+	// each of the bars of a frame and the height of the
+	// graph, in dp.
+	timingBarWidth = 1
+	timingHeight   = 48
+)
+
+var (
+	timingBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xc0}
+	timingBudget     = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	timingCPU        = color.RGBA{R: 0x40, G: 0xa0, B: 0xff, A: 0xff}
+	timingGPU        = color.RGBA{R: 0x60, G: 0xe0, B: 0x60, A: 0xff}
+	timingDropped    = color.RGBA{R: 0xff, G: 0x30, B: 0x30, A: 0xff}
+)
+
+// Layout records the profiles in q and draws the graph in the
+// Alignment corner of the constraints maximum size.
+func (f *FrameTimings) Layout(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Constraints) layout.Dimens {
+	for e, ok := q.Next(f); ok; e, ok = q.Next(f) {
+		if e, ok := e.(system.ProfileEvent); ok {
+			f.add(e)
+		}
+	}
+	system.ProfileOp{Key: f}.Add(ops)
+	full := image.Point{X: cs.Width.Max, Y: cs.Height.Max}
+	al := layout.Align{Alignment: f.Alignment}
+	cs = al.Begin(ops, layout.RigidConstraints(full))
+	in := layout.UniformInset(ui.Dp(8))
+	cs = in.Begin(c, ops, cs)
+	dims := f.draw(c, ops, cs)
+	dims = in.End(dims)
+	return al.End(dims)
+}
+
+func (f *FrameTimings) add(e system.ProfileEvent) {
+	f.frames[f.next] = frameTiming{
+		cpu:     e.Layout + e.Collect + e.Blit + e.Stencil + e.Cover,
+		gpu:     e.GPU,
+		dropped: e.Frame > f.budget()*3/2,
+	}
+	f.next = (f.next + 1) % len(f.frames)
+}
+
+func (f *FrameTimings) budget() time.Duration {
+	if f.Budget == 0 {
+		return time.Second / 60
+	}
+	return f.Budget
+}
+
+func (f *FrameTimings) draw(c ui.Config, ops *ui.Ops, cs layout.Constraints) layout.Dimens {
+	bw := float32(c.Px(ui.Dp(timingBarWidth)))
+	sz := cs.Constrain(image.Point{
+		X: int(bw) * 2 * timingFrames,
+		Y: c.Px(ui.Dp(timingHeight)),
+	})
+	w, h := float32(sz.X), float32(sz.Y)
+	fillRect(ops, timingBackground, f32.Rectangle{Max: f32.Point{X: w, Y: h}})
+	// The graph spans two frame budgets.
+	scale := h / float32(2*f.budget())
+	bar := func(col color.RGBA, x float32, d time.Duration) {
+		top := h - float32(d)*scale
+		if top < 0 {
+			top = 0
+		}
+		fillRect(ops, col, f32.Rectangle{
+			Min: f32.Point{X: x, Y: top},
+			Max: f32.Point{X: x + bw, Y: h},
+		})
+	}
+	marker := bw * 2
+	for i := range f.frames {
+		// Draw the oldest frame first.
+		t := f.frames[(f.next+i)%len(f.frames)]
+		x := float32(i) * bw * 2
+		if x+bw*2 > w {
+			break
+		}
+		bar(timingCPU, x, t.cpu)
+		bar(timingGPU, x+bw, t.gpu)
+		if t.dropped {
+			fillRect(ops, timingDropped, f32.Rectangle{
+				Min: f32.Point{X: x},
+				Max: f32.Point{X: x + bw*2, Y: marker},
+			})
+		}
+	}
+	fillRect(ops, timingBudget, f32.Rectangle{
+		Min: f32.Point{Y: h / 2},
+		Max: f32.Point{X: w, Y: h/2 + 1},
+	})
+	return layout.Dimens{Size: sz, Baseline: sz.Y}
+}
+
+func fillRect(ops *ui.Ops, col color.RGBA, r f32.Rectangle) {
+	paint.ColorOp{Color: col}.Add(ops)
+	paint.PaintOp{Rect: r}.Add(ops)
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/system"
+	"gioui.org/ui/uitest"
+	"gioui.org/ui/widget"
+)
+
+func TestFrameTimings(t *testing.T) {
+	var ft widget.FrameTimings
+	q := new(uitest.Queue)
+	budget := time.Second / 60
+	// A smooth frame followed by a dropped frame.
+	q.Add(&ft,
+		system.ProfileEvent{Frame: budget, Layout: budget / 2, GPU: budget / 4},
+		system.ProfileEvent{Frame: 3 * budget, Layout: 2 * budget},
+	)
+	cfg := &uitest.Config{}
+	ops := new(ui.Ops)
+	size := image.Point{X: 300, Y: 100}
+	dims := ft.Layout(cfg, q, ops, layout.RigidConstraints(size))
+	if dims.Size != size {
+		t.Errorf("got size %v, expected %v", dims.Size, size)
+	}
+	if n := q.Pending(&ft); n != 0 {
+		t.Errorf("%d profile events not received", n)
+	}
+	img := uitest.Render(ops, size)
+	// The graph is inset by 8 pixels, and the newest frames are
+	// drawn to the right in slots of 2 pixels.
+	dropped := img.RGBAAt(8+238, 8)
+	smooth := img.RGBAAt(8+236, 8)
+	if !isRed(dropped) {
+		t.Errorf("dropped frame has no marker, got %v", dropped)
+	}
+	if isRed(smooth) {
+		t.Errorf("smooth frame marked as dropped")
+	}
+	if c := img.RGBAAt(4, 4); c != img.RGBAAt(size.X-1, size.Y-1) {
+		t.Errorf("graph covers the inset, got %v", c)
+	}
+}
+
+func isRed(c color.RGBA) bool {
+	return c.R > 0xc0 && c.G < 0x60 && c.B < 0x60
+}
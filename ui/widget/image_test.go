@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/uitest"
+	"gioui.org/ui/widget"
+)
+
+func TestImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				src.Set(x, y, color.RGBA{R: 0x80, G: 0x20, B: 0xc0, A: 0xff})
+			}
+		}
+	}
+	cfg := &uitest.Config{PxPerDp: 2}
+	ops := new(ui.Ops)
+	cs := layout.RigidConstraints(image.Point{X: 48, Y: 32})
+	img := widget.Image{Src: src, Rect: src.Bounds(), Scale: 8}
+	dims := img.Layout(cfg, ops, cs)
+	if exp := (image.Point{X: 32, Y: 32}); dims.Size != exp {
+		t.Errorf("got size %v, expected %v", dims.Size, exp)
+	}
+	uitest.Golden{Tolerance: 1}.Check(t, "image", uitest.Render(ops, image.Point{X: 48, Y: 32}))
+}